@@ -1,9 +1,17 @@
 package ast
 
+import "github.com/fabiante/monkeylang/token"
+
 type Node interface {
 	// TokenLiteral is used only for debugging / testing
 	TokenLiteral() string
 	String() string
+
+	// Pos returns the position of the first character of the node.
+	Pos() token.Position
+	// End returns the position of the character following the node.
+	// Parentheses around an expression are not part of its span.
+	End() token.Position
 }
 
 // A Statement is something that does not produce a value.
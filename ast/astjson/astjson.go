@@ -0,0 +1,647 @@
+// Package astjson encodes ast nodes as JSON and decodes them back into ast nodes.
+//
+// Every node is encoded as an object with a "kind" discriminator, which is the
+// name of its ast type, and the token of the node including its position:
+//
+//	{
+//	  "kind": "PrefixExpression",
+//	  "token": {"type": "Minus", "literal": "-", "pos": {"offset": 0, "line": 1, "column": 1}},
+//	  "operator": "-",
+//	  "right": {"kind": "IntegerLiteral", "token": {...}, "value": 5}
+//	}
+//
+// The remaining fields are named like the fields of the ast type. The "value"
+// of literals and identifiers is the JSON representation of their Go value.
+// The same applies to the "name" of function literals and named types, which
+// is a string.
+//
+// Closing brackets and the semicolons terminating statements are encoded as
+// tokens in the "rparen", "rbracket", "rbrace" and "semicolon" fields, the
+// export keyword of let statements in the "export" field.
+//
+// Type annotations are encoded as nodes in the "type" field of identifiers
+// and the "returnType" field of function literals.
+//
+// MarshalTokens encodes a list of tokens in the same representation as the
+// tokens of nodes.
+package astjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// Kinds of nodes used as discriminator in the "kind" field.
+const (
+	KindProgram             = "Program"
+	KindLetStatement        = "LetStatement"
+	KindReturnStatement     = "ReturnStatement"
+	KindThrowStatement      = "ThrowStatement"
+	KindImportStatement     = "ImportStatement"
+	KindExpressionStatement = "ExpressionStatement"
+	KindBlockStatement      = "BlockStatement"
+	KindIdentifier          = "Identifier"
+	KindIntegerLiteral      = "IntegerLiteral"
+	KindBooleanLiteral      = "BooleanLiteral"
+	KindStringLiteral       = "StringLiteral"
+	KindPrefixExpression    = "PrefixExpression"
+	KindInfixExpression     = "InfixExpression"
+	KindIfExpression        = "IfExpression"
+	KindTryExpression       = "TryExpression"
+	KindFunctionLiteral     = "FunctionLiteral"
+	KindCallExpression      = "CallExpression"
+	KindArrayLiteral        = "ArrayLiteral"
+	KindIndexExpression     = "IndexExpression"
+	KindMemberExpression    = "MemberExpression"
+	KindHashLiteral         = "HashLiteral"
+	KindNamedType           = "NamedType"
+	KindArrayType           = "ArrayType"
+	KindHashType            = "HashType"
+	KindFunctionType        = "FunctionType"
+)
+
+// node is the JSON representation of any ast.Node.
+//
+// Fields which are not used by the kind of node are omitted.
+type node struct {
+	Kind  string     `json:"kind"`
+	Token *jsonToken `json:"token,omitempty"`
+
+	// Name is either the identifier node of a let or import statement or
+	// the name string of a function literal or named type.
+	Name     json.RawMessage `json:"name,omitempty"`
+	Operator string          `json:"operator,omitempty"`
+
+	// Value is either the value of a literal or identifier, or the value
+	// node of a let statement, throw statement or hash type.
+	Value json.RawMessage `json:"value,omitempty"`
+
+	ReturnValue *node `json:"returnValue,omitempty"`
+	Expression  *node `json:"expression,omitempty"`
+	Left        *node `json:"left,omitempty"`
+	Right       *node `json:"right,omitempty"`
+
+	Condition   *node `json:"condition,omitempty"`
+	Consequence *node `json:"consequence,omitempty"`
+	Alternative *node `json:"alternative,omitempty"`
+
+	Block     *node `json:"block,omitempty"`
+	Parameter *node `json:"parameter,omitempty"`
+	Catch     *node `json:"catch,omitempty"`
+	Finally   *node `json:"finally,omitempty"`
+
+	Parameters []*node `json:"parameters,omitempty"`
+	ReturnType *node   `json:"returnType,omitempty"`
+	Body       *node   `json:"body,omitempty"`
+
+	Type    *node `json:"type,omitempty"`
+	Element *node `json:"element,omitempty"`
+	Key     *node `json:"key,omitempty"`
+
+	Function  *node   `json:"function,omitempty"`
+	Arguments []*node `json:"arguments,omitempty"`
+
+	Path     *node `json:"path,omitempty"`
+	Object   *node `json:"object,omitempty"`
+	Property *node `json:"property,omitempty"`
+
+	Elements []*node    `json:"elements,omitempty"`
+	Index    *node      `json:"index,omitempty"`
+	Pairs    []jsonPair `json:"pairs,omitempty"`
+
+	Statements []*node      `json:"statements,omitempty"`
+	Comments   []*jsonToken `json:"comments,omitempty"`
+
+	RParen    *jsonToken `json:"rparen,omitempty"`
+	RBracket  *jsonToken `json:"rbracket,omitempty"`
+	RBrace    *jsonToken `json:"rbrace,omitempty"`
+	Semicolon *jsonToken `json:"semicolon,omitempty"`
+	Export    *jsonToken `json:"export,omitempty"`
+}
+
+type jsonPair struct {
+	Key   *node `json:"key"`
+	Value *node `json:"value"`
+}
+
+type jsonToken struct {
+	Type    string       `json:"type"`
+	Literal string       `json:"literal"`
+	Pos     jsonPosition `json:"pos"`
+}
+
+type jsonPosition struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Marshal returns the JSON encoding of the given node.
+func Marshal(n ast.Node) ([]byte, error) {
+	e := &encoder{}
+	encoded := e.node(n)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return json.Marshal(encoded)
+}
+
+// MarshalTokens returns the JSON encoding of the given tokens as an array.
+func MarshalTokens(tokens []token.Token) ([]byte, error) {
+	encoded := make([]*jsonToken, len(tokens))
+	for i, t := range tokens {
+		encoded[i] = encodeToken(t)
+	}
+	return json.Marshal(encoded)
+}
+
+// Unmarshal decodes a node encoded by Marshal.
+func Unmarshal(data []byte) (ast.Node, error) {
+	var n node
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+
+	d := &decoder{}
+	decoded := d.node(&n)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return decoded, nil
+}
+
+// encoder converts ast nodes into their JSON representation. The first error
+// is kept in err, after which all further encoding is skipped.
+type encoder struct {
+	err error
+}
+
+func (e *encoder) node(n ast.Node) *node {
+	if e.err != nil || isNil(n) {
+		return nil
+	}
+
+	switch n := n.(type) {
+	case *ast.Program:
+		out := &node{Kind: KindProgram}
+		out.Statements = e.statements(n.Statements)
+		for _, comment := range n.Comments {
+			out.Comments = append(out.Comments, encodeToken(comment))
+		}
+		return out
+	case *ast.LetStatement:
+		out := &node{Kind: KindLetStatement, Token: encodeToken(n.Token), Semicolon: encodeOptionalToken(n.Semicolon), Export: encodeOptionalToken(n.Export)}
+		out.Name = e.raw(e.node(n.Name))
+		out.Value = e.raw(e.node(n.Value))
+		return out
+	case *ast.ImportStatement:
+		out := &node{Kind: KindImportStatement, Token: encodeToken(n.Token), Semicolon: encodeOptionalToken(n.Semicolon)}
+		out.Path = e.node(n.Path)
+		out.Name = e.raw(e.node(n.Name))
+		return out
+	case *ast.ReturnStatement:
+		out := &node{Kind: KindReturnStatement, Token: encodeToken(n.Token), Semicolon: encodeOptionalToken(n.Semicolon)}
+		out.ReturnValue = e.node(n.ReturnValue)
+		return out
+	case *ast.ThrowStatement:
+		out := &node{Kind: KindThrowStatement, Token: encodeToken(n.Token), Semicolon: encodeOptionalToken(n.Semicolon)}
+		out.Value = e.raw(e.node(n.Value))
+		return out
+	case *ast.ExpressionStatement:
+		out := &node{Kind: KindExpressionStatement, Token: encodeToken(n.Token), Semicolon: encodeOptionalToken(n.Semicolon)}
+		out.Expression = e.node(n.Expression)
+		return out
+	case *ast.BlockStatement:
+		out := &node{Kind: KindBlockStatement, Token: encodeToken(n.Token), RBrace: encodeToken(n.RBrace)}
+		out.Statements = e.statements(n.Statements)
+		return out
+	case *ast.Identifier:
+		out := e.value(KindIdentifier, n.Token, n.Value)
+		out.Type = e.node(n.Type)
+		return out
+	case *ast.IntegerLiteral:
+		return e.value(KindIntegerLiteral, n.Token, n.Value)
+	case *ast.BooleanLiteral:
+		return e.value(KindBooleanLiteral, n.Token, n.Value)
+	case *ast.StringLiteral:
+		return e.value(KindStringLiteral, n.Token, n.Value)
+	case *ast.PrefixExpression:
+		out := &node{Kind: KindPrefixExpression, Token: encodeToken(n.Token), Operator: n.Operator}
+		out.Right = e.node(n.Right)
+		return out
+	case *ast.InfixExpression:
+		out := &node{Kind: KindInfixExpression, Token: encodeToken(n.Token), Operator: n.Operator}
+		out.Left = e.node(n.Left)
+		out.Right = e.node(n.Right)
+		return out
+	case *ast.IfExpression:
+		out := &node{Kind: KindIfExpression, Token: encodeToken(n.Token)}
+		out.Condition = e.node(n.Condition)
+		out.Consequence = e.node(n.Consequence)
+		out.Alternative = e.node(n.Alternative)
+		return out
+	case *ast.TryExpression:
+		out := &node{Kind: KindTryExpression, Token: encodeToken(n.Token)}
+		out.Block = e.node(n.Block)
+		out.Parameter = e.node(n.Parameter)
+		out.Catch = e.node(n.Catch)
+		out.Finally = e.node(n.Finally)
+		return out
+	case *ast.FunctionLiteral:
+		out := &node{Kind: KindFunctionLiteral, Token: encodeToken(n.Token)}
+		if n.Name != "" {
+			out.Name = e.raw(n.Name)
+		}
+		for _, param := range n.Parameters {
+			out.Parameters = append(out.Parameters, e.node(param))
+		}
+		out.ReturnType = e.node(n.ReturnType)
+		out.Body = e.node(n.Body)
+		return out
+	case *ast.CallExpression:
+		out := &node{Kind: KindCallExpression, Token: encodeToken(n.Token), RParen: encodeToken(n.RParen)}
+		out.Function = e.node(n.Function)
+		out.Arguments = e.expressions(n.Arguments)
+		return out
+	case *ast.ArrayLiteral:
+		out := &node{Kind: KindArrayLiteral, Token: encodeToken(n.Token), RBracket: encodeToken(n.RBracket)}
+		out.Elements = e.expressions(n.Elements)
+		return out
+	case *ast.IndexExpression:
+		out := &node{Kind: KindIndexExpression, Token: encodeToken(n.Token), RBracket: encodeToken(n.RBracket)}
+		out.Left = e.node(n.Left)
+		out.Index = e.node(n.Index)
+		return out
+	case *ast.MemberExpression:
+		out := &node{Kind: KindMemberExpression, Token: encodeToken(n.Token)}
+		out.Object = e.node(n.Object)
+		out.Property = e.node(n.Property)
+		return out
+	case *ast.HashLiteral:
+		out := &node{Kind: KindHashLiteral, Token: encodeToken(n.Token), RBrace: encodeToken(n.RBrace)}
+		for _, pair := range n.Pairs {
+			out.Pairs = append(out.Pairs, jsonPair{Key: e.node(pair.Key), Value: e.node(pair.Value)})
+		}
+		return out
+	case *ast.NamedType:
+		return &node{Kind: KindNamedType, Token: encodeToken(n.Token), Name: e.raw(n.Name)}
+	case *ast.ArrayType:
+		return &node{Kind: KindArrayType, Token: encodeToken(n.Token), RBracket: encodeToken(n.RBracket), Element: e.node(n.Element)}
+	case *ast.HashType:
+		out := &node{Kind: KindHashType, Token: encodeToken(n.Token), RBrace: encodeToken(n.RBrace)}
+		out.Key = e.node(n.Key)
+		out.Value = e.raw(e.node(n.Value))
+		return out
+	case *ast.FunctionType:
+		out := &node{Kind: KindFunctionType, Token: encodeToken(n.Token), Parameters: make([]*node, 0, len(n.Parameters))}
+		for _, param := range n.Parameters {
+			out.Parameters = append(out.Parameters, e.node(param))
+		}
+		out.ReturnType = e.node(n.ReturnType)
+		return out
+	default:
+		e.err = fmt.Errorf("astjson: unsupported node type %T", n)
+		return nil
+	}
+}
+
+func (e *encoder) statements(list []ast.Statement) []*node {
+	out := make([]*node, 0, len(list))
+	for _, stmt := range list {
+		out = append(out, e.node(stmt))
+	}
+	return out
+}
+
+func (e *encoder) expressions(list []ast.Expression) []*node {
+	out := make([]*node, 0, len(list))
+	for _, exp := range list {
+		out = append(out, e.node(exp))
+	}
+	return out
+}
+
+func (e *encoder) value(kind string, t token.Token, value any) *node {
+	return &node{Kind: kind, Token: encodeToken(t), Value: e.raw(value)}
+}
+
+// raw returns the JSON encoding of v. A nil *node is encoded as nil, which
+// omits the field.
+func (e *encoder) raw(v any) json.RawMessage {
+	if n, ok := v.(*node); ok && n == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil && e.err == nil {
+		e.err = err
+	}
+	return data
+}
+
+// isNil reports whether n is nil or one of the nil pointers which are stored
+// in fields with a concrete node type.
+func isNil(n ast.Node) bool {
+	switch n := n.(type) {
+	case nil:
+		return true
+	case *ast.Identifier:
+		return n == nil
+	case *ast.StringLiteral:
+		return n == nil
+	case *ast.BlockStatement:
+		return n == nil
+	default:
+		return false
+	}
+}
+
+// encodeOptionalToken encodes t unless it is the zero token, which is omitted.
+func encodeOptionalToken(t token.Token) *jsonToken {
+	if t == (token.Token{}) {
+		return nil
+	}
+	return encodeToken(t)
+}
+
+func encodeToken(t token.Token) *jsonToken {
+	return &jsonToken{
+		Type:    t.Type.String(),
+		Literal: t.Literal,
+		Pos:     jsonPosition(t.Pos),
+	}
+}
+
+// decoder converts the JSON representation of nodes into ast nodes. The first
+// error is kept in err, after which all further decoding is skipped.
+type decoder struct {
+	err error
+}
+
+func (d *decoder) node(n *node) ast.Node {
+	if d.err != nil || n == nil {
+		return nil
+	}
+
+	tok := d.token(n.Token)
+
+	switch n.Kind {
+	case KindProgram:
+		out := ast.NewProgram()
+		out.Statements = append(out.Statements, d.statements(n.Statements)...)
+		for _, comment := range n.Comments {
+			out.Comments = append(out.Comments, d.token(comment))
+		}
+		return out
+	case KindLetStatement:
+		out := &ast.LetStatement{Export: d.token(n.Export), Token: tok, Semicolon: d.token(n.Semicolon)}
+		if len(n.Name) > 0 {
+			out.Name = d.identifier(d.rawNode(n.Name))
+		}
+		if len(n.Value) > 0 {
+			out.Value = d.expression(d.rawNode(n.Value))
+		}
+		return out
+	case KindImportStatement:
+		out := &ast.ImportStatement{Token: tok, Path: d.stringLiteral(n.Path), Semicolon: d.token(n.Semicolon)}
+		if len(n.Name) > 0 {
+			out.Name = d.identifier(d.rawNode(n.Name))
+		}
+		return out
+	case KindReturnStatement:
+		return &ast.ReturnStatement{Token: tok, ReturnValue: d.expression(n.ReturnValue), Semicolon: d.token(n.Semicolon)}
+	case KindThrowStatement:
+		out := &ast.ThrowStatement{Token: tok, Semicolon: d.token(n.Semicolon)}
+		if len(n.Value) > 0 {
+			out.Value = d.expression(d.rawNode(n.Value))
+		}
+		return out
+	case KindExpressionStatement:
+		return &ast.ExpressionStatement{Token: tok, Expression: d.expression(n.Expression), Semicolon: d.token(n.Semicolon)}
+	case KindBlockStatement:
+		return &ast.BlockStatement{Token: tok, RBrace: d.token(n.RBrace), Statements: d.statements(n.Statements)}
+	case KindIdentifier:
+		out := &ast.Identifier{Token: tok, Type: d.typeExpression(n.Type)}
+		d.value(n, n.Value, &out.Value)
+		return out
+	case KindIntegerLiteral:
+		out := &ast.IntegerLiteral{Token: tok}
+		d.value(n, n.Value, &out.Value)
+		return out
+	case KindBooleanLiteral:
+		out := &ast.BooleanLiteral{Token: tok}
+		d.value(n, n.Value, &out.Value)
+		return out
+	case KindStringLiteral:
+		out := &ast.StringLiteral{Token: tok}
+		d.value(n, n.Value, &out.Value)
+		return out
+	case KindPrefixExpression:
+		return &ast.PrefixExpression{Token: tok, Operator: n.Operator, Right: d.expression(n.Right)}
+	case KindInfixExpression:
+		return &ast.InfixExpression{
+			Token:    tok,
+			Operator: n.Operator,
+			Left:     d.expression(n.Left),
+			Right:    d.expression(n.Right),
+		}
+	case KindIfExpression:
+		return &ast.IfExpression{
+			Token:       tok,
+			Condition:   d.expression(n.Condition),
+			Consequence: d.block(n.Consequence),
+			Alternative: d.block(n.Alternative),
+		}
+	case KindTryExpression:
+		return &ast.TryExpression{
+			Token:     tok,
+			Block:     d.block(n.Block),
+			Parameter: d.identifier(n.Parameter),
+			Catch:     d.block(n.Catch),
+			Finally:   d.block(n.Finally),
+		}
+	case KindFunctionLiteral:
+		out := &ast.FunctionLiteral{Token: tok, Parameters: make([]*ast.Identifier, 0, len(n.Parameters))}
+		if len(n.Name) > 0 {
+			d.value(n, n.Name, &out.Name)
+		}
+		for _, param := range n.Parameters {
+			out.Parameters = append(out.Parameters, d.identifier(param))
+		}
+		out.ReturnType = d.typeExpression(n.ReturnType)
+		out.Body = d.block(n.Body)
+		return out
+	case KindCallExpression:
+		return &ast.CallExpression{
+			Token:     tok,
+			Function:  d.expression(n.Function),
+			Arguments: d.expressions(n.Arguments),
+			RParen:    d.token(n.RParen),
+		}
+	case KindArrayLiteral:
+		return &ast.ArrayLiteral{Token: tok, Elements: d.expressions(n.Elements), RBracket: d.token(n.RBracket)}
+	case KindIndexExpression:
+		return &ast.IndexExpression{Token: tok, Left: d.expression(n.Left), Index: d.expression(n.Index), RBracket: d.token(n.RBracket)}
+	case KindMemberExpression:
+		return &ast.MemberExpression{Token: tok, Object: d.expression(n.Object), Property: d.identifier(n.Property)}
+	case KindHashLiteral:
+		out := &ast.HashLiteral{Token: tok, Pairs: make([]ast.HashPair, 0, len(n.Pairs)), RBrace: d.token(n.RBrace)}
+		for _, pair := range n.Pairs {
+			out.Pairs = append(out.Pairs, ast.HashPair{Key: d.expression(pair.Key), Value: d.expression(pair.Value)})
+		}
+		return out
+	case KindNamedType:
+		out := &ast.NamedType{Token: tok}
+		d.value(n, n.Name, &out.Name)
+		return out
+	case KindArrayType:
+		return &ast.ArrayType{Token: tok, Element: d.typeExpression(n.Element), RBracket: d.token(n.RBracket)}
+	case KindHashType:
+		out := &ast.HashType{Token: tok, Key: d.typeExpression(n.Key), RBrace: d.token(n.RBrace)}
+		if len(n.Value) > 0 {
+			out.Value = d.typeExpression(d.rawNode(n.Value))
+		}
+		return out
+	case KindFunctionType:
+		out := &ast.FunctionType{Token: tok, Parameters: make([]ast.TypeExpression, 0, len(n.Parameters))}
+		for _, param := range n.Parameters {
+			out.Parameters = append(out.Parameters, d.typeExpression(param))
+		}
+		out.ReturnType = d.typeExpression(n.ReturnType)
+		return out
+	default:
+		d.fail(fmt.Errorf("astjson: unknown node kind %q", n.Kind))
+		return nil
+	}
+}
+
+func (d *decoder) statements(list []*node) []ast.Statement {
+	out := make([]ast.Statement, 0, len(list))
+	for _, n := range list {
+		decoded := d.node(n)
+		if decoded == nil {
+			continue
+		}
+		stmt, ok := decoded.(ast.Statement)
+		if !ok {
+			d.fail(fmt.Errorf("astjson: expected statement, got %s", n.Kind))
+			continue
+		}
+		out = append(out, stmt)
+	}
+	return out
+}
+
+func (d *decoder) expressions(list []*node) []ast.Expression {
+	out := make([]ast.Expression, 0, len(list))
+	for _, n := range list {
+		out = append(out, d.expression(n))
+	}
+	return out
+}
+
+func (d *decoder) expression(n *node) ast.Expression {
+	decoded := d.node(n)
+	if decoded == nil {
+		return nil
+	}
+	exp, ok := decoded.(ast.Expression)
+	if !ok {
+		d.fail(fmt.Errorf("astjson: expected expression, got %s", n.Kind))
+		return nil
+	}
+	return exp
+}
+
+func (d *decoder) identifier(n *node) *ast.Identifier {
+	decoded := d.node(n)
+	if decoded == nil {
+		return nil
+	}
+	ident, ok := decoded.(*ast.Identifier)
+	if !ok {
+		d.fail(fmt.Errorf("astjson: expected %s, got %s", KindIdentifier, n.Kind))
+		return nil
+	}
+	return ident
+}
+
+func (d *decoder) stringLiteral(n *node) *ast.StringLiteral {
+	decoded := d.node(n)
+	if decoded == nil {
+		return nil
+	}
+	str, ok := decoded.(*ast.StringLiteral)
+	if !ok {
+		d.fail(fmt.Errorf("astjson: expected %s, got %s", KindStringLiteral, n.Kind))
+		return nil
+	}
+	return str
+}
+
+func (d *decoder) typeExpression(n *node) ast.TypeExpression {
+	decoded := d.node(n)
+	if decoded == nil {
+		return nil
+	}
+	typ, ok := decoded.(ast.TypeExpression)
+	if !ok {
+		d.fail(fmt.Errorf("astjson: expected type, got %s", n.Kind))
+		return nil
+	}
+	return typ
+}
+
+func (d *decoder) block(n *node) *ast.BlockStatement {
+	decoded := d.node(n)
+	if decoded == nil {
+		return nil
+	}
+	block, ok := decoded.(*ast.BlockStatement)
+	if !ok {
+		d.fail(fmt.Errorf("astjson: expected %s, got %s", KindBlockStatement, n.Kind))
+		return nil
+	}
+	return block
+}
+
+// rawNode decodes a node which is embedded as raw JSON.
+func (d *decoder) rawNode(data json.RawMessage) *node {
+	var n node
+	if err := json.Unmarshal(data, &n); err != nil {
+		d.fail(err)
+		return nil
+	}
+	return &n
+}
+
+func (d *decoder) value(n *node, data json.RawMessage, target any) {
+	if err := json.Unmarshal(data, target); err != nil {
+		d.fail(fmt.Errorf("astjson: invalid value of %s: %w", n.Kind, err))
+	}
+}
+
+func (d *decoder) token(t *jsonToken) token.Token {
+	if t == nil {
+		return token.Token{}
+	}
+
+	typ, ok := token.LookupType(t.Type)
+	if !ok {
+		d.fail(fmt.Errorf("astjson: unknown token type %q", t.Type))
+	}
+
+	return token.Token{
+		Type:    typ,
+		Literal: t.Literal,
+		Pos:     token.Position(t.Pos),
+	}
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
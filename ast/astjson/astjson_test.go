@@ -0,0 +1,92 @@
+package astjson
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/internal/testutil"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	program := testutil.MustParse(t, "-5")
+
+	data, err := Marshal(program.Statements[0].(*ast.ExpressionStatement).Expression)
+	require.NoError(t, err)
+
+	expected := `{
+		"kind": "PrefixExpression",
+		"token": {"type": "Minus", "literal": "-", "pos": {"offset": 0, "line": 1, "column": 1}},
+		"operator": "-",
+		"right": {
+			"kind": "IntegerLiteral",
+			"token": {"type": "Int", "literal": "5", "pos": {"offset": 1, "line": 1, "column": 2}},
+			"value": 5
+		}
+	}`
+	assert.JSONEq(t, expected, string(data))
+}
+
+func TestMarshalTokens(t *testing.T) {
+	tokens, errs := lexer.Tokenize("-5")
+	require.Empty(t, errs)
+
+	data, err := MarshalTokens(tokens)
+	require.NoError(t, err)
+
+	expected := `[
+		{"type": "Minus", "literal": "-", "pos": {"offset": 0, "line": 1, "column": 1}},
+		{"type": "Int", "literal": "5", "pos": {"offset": 1, "line": 1, "column": 2}}
+	]`
+	assert.JSONEq(t, expected, string(data))
+}
+
+func TestUnmarshal(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		inputs := []string{
+			"let x = 5;",
+			"return a + b * -c;",
+			"// comment\ntrue != !false; x;",
+			`let f = fn(a, b) { if (a < b) { return [a, b][0]; } else { {"a": a}["a"] } }; f(1, 2);`,
+			"if (x) { } else { }",
+			"let f: fn([int], {string: bool}) -> int = fn(a: [int], b) -> int { 1 };",
+			`try { throw "x"; } catch (e) { e["message"] } finally { 1 }; try { 1 } finally { 2 }`,
+			`import "lib/math.mk" as math; export let add = math.add; math.f(1).x`,
+			"",
+		}
+
+		for _, input := range inputs {
+			t.Run(input, func(t *testing.T) {
+				program := testutil.MustParse(t, input)
+
+				data, err := Marshal(program)
+				require.NoError(t, err)
+
+				decoded, err := Unmarshal(data)
+				require.NoError(t, err)
+				assert.Equal(t, program, decoded)
+			})
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		inputs := []string{
+			`{"kind": "Foo"}`,
+			`{"kind": "Identifier", "token": {"type": "Foo"}, "value": "x"}`,
+			`{"kind": "IntegerLiteral", "value": "x"}`,
+			`{"kind": "LetStatement", "name": {"kind": "IntegerLiteral", "value": 1}}`,
+			`{"kind": "ExpressionStatement", "expression": {"kind": "Program"}}`,
+			`{"kind": "IfExpression", "consequence": {"kind": "Identifier", "value": "x"}}`,
+			`{"kind": "FunctionLiteral", "name": 5}`,
+			`[]`,
+		}
+
+		for _, input := range inputs {
+			t.Run(input, func(t *testing.T) {
+				_, err := Unmarshal([]byte(input))
+				assert.Error(t, err)
+			})
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+	"strings"
+)
+
+type ArrayLiteral struct {
+	// Token is the opening bracket.
+	Token    token.Token
+	Elements []Expression
+	// RBracket is the closing bracket.
+	RBracket token.Token
+}
+
+func (a *ArrayLiteral) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (a *ArrayLiteral) Pos() token.Position {
+	return a.Token.Pos
+}
+
+func (a *ArrayLiteral) End() token.Position {
+	return a.RBracket.End()
+}
+
+func (a *ArrayLiteral) String() string {
+	elements := make([]string, 0, len(a.Elements))
+	for _, e := range a.Elements {
+		elements = append(elements, e.String())
+	}
+
+	var out bytes.Buffer
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+func (a *ArrayLiteral) expressionNode() {}
@@ -0,0 +1,38 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// BlockStatement is a list of statements enclosed in braces, like the body
+// of a function.
+type BlockStatement struct {
+	// Token is the opening brace.
+	Token token.Token
+	// RBrace is the closing brace.
+	RBrace     token.Token
+	Statements []Statement
+}
+
+func (b *BlockStatement) String() string {
+	var out bytes.Buffer
+	for _, stmt := range b.Statements {
+		out.WriteString(stmt.String())
+	}
+	return out.String()
+}
+
+func (b *BlockStatement) TokenLiteral() string {
+	return b.Token.Literal
+}
+
+func (b *BlockStatement) Pos() token.Position {
+	return b.Token.Pos
+}
+
+func (b *BlockStatement) End() token.Position {
+	return b.RBrace.End()
+}
+
+func (b *BlockStatement) statementNode() {}
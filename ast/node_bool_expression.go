@@ -11,6 +11,14 @@ func (b *BooleanLiteral) TokenLiteral() string {
 	return b.Token.Literal
 }
 
+func (b *BooleanLiteral) Pos() token.Position {
+	return b.Token.Pos
+}
+
+func (b *BooleanLiteral) End() token.Position {
+	return b.Token.End()
+}
+
 func (b *BooleanLiteral) String() string {
 	return b.Token.Literal
 }
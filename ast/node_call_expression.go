@@ -0,0 +1,48 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+	"strings"
+)
+
+type CallExpression struct {
+	// Token is the opening parenthesis.
+	Token token.Token
+	// Function is either an Identifier or a FunctionLiteral.
+	Function  Expression
+	Arguments []Expression
+	// RParen is the closing parenthesis.
+	RParen token.Token
+}
+
+func (c *CallExpression) TokenLiteral() string {
+	return c.Token.Literal
+}
+
+func (c *CallExpression) Pos() token.Position {
+	if c.Function == nil {
+		return c.Token.Pos
+	}
+	return c.Function.Pos()
+}
+
+func (c *CallExpression) End() token.Position {
+	return c.RParen.End()
+}
+
+func (c *CallExpression) String() string {
+	args := make([]string, 0, len(c.Arguments))
+	for _, a := range c.Arguments {
+		args = append(args, a.String())
+	}
+
+	var out bytes.Buffer
+	out.WriteString(c.Function.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(args, ", "))
+	out.WriteString(")")
+	return out.String()
+}
+
+func (c *CallExpression) expressionNode() {}
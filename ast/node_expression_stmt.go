@@ -5,6 +5,9 @@ import "github.com/fabiante/monkeylang/token"
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
+	// Semicolon is the semicolon terminating the statement. It is the zero
+	// token if the statement is not terminated by a semicolon.
+	Semicolon token.Token
 }
 
 func (e *ExpressionStatement) String() string {
@@ -18,4 +21,19 @@ func (e *ExpressionStatement) TokenLiteral() string {
 	return e.Token.Literal
 }
 
+func (e *ExpressionStatement) Pos() token.Position {
+	return e.Token.Pos
+}
+
+func (e *ExpressionStatement) End() token.Position {
+	switch {
+	case e.Semicolon.Type == token.Semicolon:
+		return e.Semicolon.End()
+	case e.Expression != nil:
+		return e.Expression.End()
+	default:
+		return e.Token.End()
+	}
+}
+
 func (e *ExpressionStatement) statementNode() {}
@@ -0,0 +1,57 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+	"strings"
+)
+
+type FunctionLiteral struct {
+	Token      token.Token
+	Parameters []*Identifier
+	// ReturnType is the annotated type of the result, or nil.
+	ReturnType TypeExpression
+	Body       *BlockStatement
+
+	// Name is the name of the binding if the function is the value of a let
+	// statement. Otherwise, it is empty.
+	Name string
+}
+
+func (f *FunctionLiteral) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f *FunctionLiteral) Pos() token.Position {
+	return f.Token.Pos
+}
+
+func (f *FunctionLiteral) End() token.Position {
+	if f.Body == nil {
+		return f.Token.End()
+	}
+	return f.Body.End()
+}
+
+func (f *FunctionLiteral) String() string {
+	params := make([]string, 0, len(f.Parameters))
+	for _, p := range f.Parameters {
+		params = append(params, p.declaration())
+	}
+
+	var out bytes.Buffer
+	out.WriteString(f.TokenLiteral())
+	if f.Name != "" {
+		out.WriteString("<" + f.Name + ">")
+	}
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	if f.ReturnType != nil {
+		out.WriteString("-> " + f.ReturnType.String() + " ")
+	}
+	out.WriteString(f.Body.String())
+	return out.String()
+}
+
+func (f *FunctionLiteral) expressionNode() {}
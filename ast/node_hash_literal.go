@@ -0,0 +1,48 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+	"strings"
+)
+
+type HashLiteral struct {
+	// Token is the opening brace.
+	Token token.Token
+	// Pairs contains the key-value pairs in source order.
+	Pairs []HashPair
+	// RBrace is the closing brace.
+	RBrace token.Token
+}
+
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (h *HashLiteral) TokenLiteral() string {
+	return h.Token.Literal
+}
+
+func (h *HashLiteral) Pos() token.Position {
+	return h.Token.Pos
+}
+
+func (h *HashLiteral) End() token.Position {
+	return h.RBrace.End()
+}
+
+func (h *HashLiteral) String() string {
+	pairs := make([]string, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
+	}
+
+	var out bytes.Buffer
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+func (h *HashLiteral) expressionNode() {}
@@ -11,14 +11,54 @@ import "github.com/fabiante/monkeylang/token"
 type Identifier struct {
 	Token token.Token
 	Value string
+
+	// Type is the annotated type of the name declared by a let statement
+	// or function parameter. It is nil if the name is not annotated and for
+	// identifiers which refer to a binding.
+	Type TypeExpression
+
+	// Binding locates the value of the identifier. It is set by package
+	// resolver and nil if the identifier has not been resolved.
+	Binding *Binding
 }
 
 func (i *Identifier) String() string {
 	return i.Value
 }
 
+// declaration returns the identifier with its type annotation, if any.
+func (i *Identifier) declaration() string {
+	if i.Type == nil {
+		return i.Value
+	}
+	return i.Value + ": " + i.Type.String()
+}
+
 func (i *Identifier) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *Identifier) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i *Identifier) End() token.Position {
+	return i.Token.End()
+}
+
 func (i *Identifier) expressionNode() {}
+
+// Binding locates the value an identifier refers to.
+type Binding struct {
+	// Depth is the number of function scopes between the identifier and
+	// the scope which contains the binding. It is 0 for bindings of the
+	// function the identifier is used in.
+	Depth int
+	// Slot is the index of the binding within its scope. Bindings of a
+	// scope are numbered in the order of their declaration, starting with
+	// the parameters of a function.
+	Slot int
+	// Builtin is true if the identifier refers to a builtin function. Slot
+	// is its index in object.Builtins then.
+	Builtin bool
+}
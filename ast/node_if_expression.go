@@ -0,0 +1,50 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// IfExpression is a conditional. It produces the value of the evaluated branch.
+//
+// Alternative is nil if there is no else branch.
+type IfExpression struct {
+	Token       token.Token
+	Condition   Expression
+	Consequence *BlockStatement
+	Alternative *BlockStatement
+}
+
+func (i *IfExpression) TokenLiteral() string {
+	return i.Token.Literal
+}
+
+func (i *IfExpression) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i *IfExpression) End() token.Position {
+	switch {
+	case i.Alternative != nil:
+		return i.Alternative.End()
+	case i.Consequence != nil:
+		return i.Consequence.End()
+	default:
+		return i.Token.End()
+	}
+}
+
+func (i *IfExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("if")
+	out.WriteString(i.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(i.Consequence.String())
+	if i.Alternative != nil {
+		out.WriteString("else ")
+		out.WriteString(i.Alternative.String())
+	}
+	return out.String()
+}
+
+func (i *IfExpression) expressionNode() {}
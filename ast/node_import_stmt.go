@@ -0,0 +1,59 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// ImportStatement binds Name to the module in the file at Path:
+// import "lib/math.mk" as math;
+type ImportStatement struct {
+	Token token.Token
+	Path  *StringLiteral
+	Name  *Identifier
+	// Semicolon is the semicolon terminating the statement. It is the zero
+	// token if the statement is not terminated by a semicolon.
+	Semicolon token.Token
+
+	// File is the path of the imported file and Program its program. Both
+	// are set by package module and are not part of the syntax tree.
+	File    string
+	Program *Program
+}
+
+func (i *ImportStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(i.TokenLiteral() + " ")
+	if i.Path != nil {
+		out.WriteString(`"` + i.Path.Value + `"`)
+	}
+	out.WriteString(" as ")
+	if i.Name != nil {
+		out.WriteString(i.Name.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+func (i *ImportStatement) TokenLiteral() string {
+	return i.Token.Literal
+}
+
+func (i *ImportStatement) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i *ImportStatement) End() token.Position {
+	switch {
+	case i.Semicolon.Type == token.Semicolon:
+		return i.Semicolon.End()
+	case i.Name != nil:
+		return i.Name.End()
+	case i.Path != nil:
+		return i.Path.End()
+	default:
+		return i.Token.End()
+	}
+}
+
+func (i *ImportStatement) statementNode() {}
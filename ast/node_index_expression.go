@@ -0,0 +1,43 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// IndexExpression accesses an element of an array or hash: left[index]
+type IndexExpression struct {
+	// Token is the opening bracket.
+	Token token.Token
+	Left  Expression
+	Index Expression
+	// RBracket is the closing bracket.
+	RBracket token.Token
+}
+
+func (i *IndexExpression) TokenLiteral() string {
+	return i.Token.Literal
+}
+
+func (i *IndexExpression) Pos() token.Position {
+	if i.Left == nil {
+		return i.Token.Pos
+	}
+	return i.Left.Pos()
+}
+
+func (i *IndexExpression) End() token.Position {
+	return i.RBracket.End()
+}
+
+func (i *IndexExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(i.Left.String())
+	out.WriteString("[")
+	out.WriteString(i.Index.String())
+	out.WriteString("])")
+	return out.String()
+}
+
+func (i *IndexExpression) expressionNode() {}
@@ -6,6 +6,7 @@ import (
 )
 
 type InfixExpression struct {
+	// Token is the operator.
 	Token    token.Token
 	Operator string
 	Left     Expression
@@ -16,6 +17,20 @@ func (p *InfixExpression) TokenLiteral() string {
 	return p.Token.Literal
 }
 
+func (p *InfixExpression) Pos() token.Position {
+	if p.Left == nil {
+		return p.Token.Pos
+	}
+	return p.Left.Pos()
+}
+
+func (p *InfixExpression) End() token.Position {
+	if p.Right == nil {
+		return p.Token.End()
+	}
+	return p.Right.End()
+}
+
 func (p *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
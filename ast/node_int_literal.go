@@ -11,6 +11,14 @@ func (i *IntegerLiteral) TokenLiteral() string {
 	return i.Token.Literal
 }
 
+func (i *IntegerLiteral) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i *IntegerLiteral) End() token.Position {
+	return i.Token.End()
+}
+
 func (i *IntegerLiteral) String() string {
 	return i.Token.Literal
 }
@@ -6,15 +6,24 @@ import (
 )
 
 type LetStatement struct {
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	// Export is the export keyword preceding the statement. It is the zero
+	// token if the binding is not exported.
+	Export token.Token
+	Token  token.Token
+	Name   *Identifier
+	Value  Expression
+	// Semicolon is the semicolon terminating the statement. It is the zero
+	// token if the statement is not terminated by a semicolon.
+	Semicolon token.Token
 }
 
 func (l *LetStatement) String() string {
 	var out bytes.Buffer
+	if l.Exported() {
+		out.WriteString(l.Export.Literal + " ")
+	}
 	out.WriteString(l.TokenLiteral() + " ")
-	out.WriteString(l.Name.String())
+	out.WriteString(l.Name.declaration())
 	out.WriteString(" = ")
 	if l.Value != nil {
 		out.WriteString(l.Value.String())
@@ -27,4 +36,29 @@ func (l *LetStatement) TokenLiteral() string {
 	return l.Token.Literal
 }
 
+// Exported reports whether the binding is exported by its module.
+func (l *LetStatement) Exported() bool {
+	return l.Export.Type == token.Export
+}
+
+func (l *LetStatement) Pos() token.Position {
+	if l.Exported() {
+		return l.Export.Pos
+	}
+	return l.Token.Pos
+}
+
+func (l *LetStatement) End() token.Position {
+	switch {
+	case l.Semicolon.Type == token.Semicolon:
+		return l.Semicolon.End()
+	case l.Value != nil:
+		return l.Value.End()
+	case l.Name != nil:
+		return l.Name.End()
+	default:
+		return l.Token.End()
+	}
+}
+
 func (l *LetStatement) statementNode() {}
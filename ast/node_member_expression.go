@@ -0,0 +1,48 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// MemberExpression accesses an export of a module: object.property
+//
+// Property is not a reference to a binding and is never resolved.
+type MemberExpression struct {
+	// Token is the dot.
+	Token    token.Token
+	Object   Expression
+	Property *Identifier
+}
+
+func (m *MemberExpression) TokenLiteral() string {
+	return m.Token.Literal
+}
+
+func (m *MemberExpression) Pos() token.Position {
+	if m.Object == nil {
+		return m.Token.Pos
+	}
+	return m.Object.Pos()
+}
+
+func (m *MemberExpression) End() token.Position {
+	if m.Property == nil {
+		return m.Token.End()
+	}
+	return m.Property.End()
+}
+
+func (m *MemberExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(m.Object.String())
+	out.WriteString(".")
+	if m.Property != nil {
+		out.WriteString(m.Property.String())
+	}
+	out.WriteString(")")
+	return out.String()
+}
+
+func (m *MemberExpression) expressionNode() {}
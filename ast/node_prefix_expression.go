@@ -15,6 +15,17 @@ func (p *PrefixExpression) TokenLiteral() string {
 	return p.Token.Literal
 }
 
+func (p *PrefixExpression) Pos() token.Position {
+	return p.Token.Pos
+}
+
+func (p *PrefixExpression) End() token.Position {
+	if p.Right == nil {
+		return p.Token.End()
+	}
+	return p.Right.End()
+}
+
 func (p *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
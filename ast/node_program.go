@@ -1,9 +1,15 @@
 package ast
 
-import "bytes"
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+)
 
 type Program struct {
 	Statements []Statement
+
+	// Comments contains all comments of the program in source order.
+	Comments []token.Token
 }
 
 func NewProgram() *Program {
@@ -31,3 +37,21 @@ func (p *Program) TokenLiteral() string {
 		return ""
 	}
 }
+
+// Pos returns the position of the first statement, or the zero position if
+// the program is empty.
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) == 0 {
+		return token.Position{}
+	}
+	return p.Statements[0].Pos()
+}
+
+// End returns the end of the last statement, or the zero position if the
+// program is empty.
+func (p *Program) End() token.Position {
+	if len(p.Statements) == 0 {
+		return token.Position{}
+	}
+	return p.Statements[len(p.Statements)-1].End()
+}
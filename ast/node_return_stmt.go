@@ -8,6 +8,9 @@ import (
 type ReturnStatement struct {
 	Token       token.Token
 	ReturnValue Expression
+	// Semicolon is the semicolon terminating the statement. It is the zero
+	// token if the statement is not terminated by a semicolon.
+	Semicolon token.Token
 }
 
 func (r *ReturnStatement) String() string {
@@ -24,4 +27,19 @@ func (r *ReturnStatement) TokenLiteral() string {
 	return r.Token.Literal
 }
 
+func (r *ReturnStatement) Pos() token.Position {
+	return r.Token.Pos
+}
+
+func (r *ReturnStatement) End() token.Position {
+	switch {
+	case r.Semicolon.Type == token.Semicolon:
+		return r.Semicolon.End()
+	case r.ReturnValue != nil:
+		return r.ReturnValue.End()
+	default:
+		return r.Token.End()
+	}
+}
+
 func (r *ReturnStatement) statementNode() {}
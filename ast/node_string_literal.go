@@ -0,0 +1,26 @@
+package ast
+
+import "github.com/fabiante/monkeylang/token"
+
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (s *StringLiteral) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+func (s *StringLiteral) Pos() token.Position {
+	return s.Token.Pos
+}
+
+func (s *StringLiteral) End() token.Position {
+	return s.Token.End()
+}
+
+func (s *StringLiteral) String() string {
+	return s.Token.Literal
+}
+
+func (s *StringLiteral) expressionNode() {}
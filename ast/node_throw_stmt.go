@@ -0,0 +1,47 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// ThrowStatement raises Value as an error. Values which are not errors are
+// converted to an error whose message is their representation.
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+	// Semicolon is the semicolon terminating the statement. It is the zero
+	// token if the statement is not terminated by a semicolon.
+	Semicolon token.Token
+}
+
+func (t *ThrowStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(t.TokenLiteral() + " ")
+	if t.Value != nil {
+		out.WriteString(t.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+func (t *ThrowStatement) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (t *ThrowStatement) Pos() token.Position {
+	return t.Token.Pos
+}
+
+func (t *ThrowStatement) End() token.Position {
+	switch {
+	case t.Semicolon.Type == token.Semicolon:
+		return t.Semicolon.End()
+	case t.Value != nil:
+		return t.Value.End()
+	default:
+		return t.Token.End()
+	}
+}
+
+func (t *ThrowStatement) statementNode() {}
@@ -0,0 +1,60 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// TryExpression evaluates Block and, if it throws an error, binds the error to
+// Parameter and evaluates Catch instead. It produces the value of the block
+// which completed. Finally is evaluated afterwards in any case.
+//
+// Parameter and Catch are nil if there is no catch clause and Finally is nil
+// if there is no finally clause. At least one of them is present.
+type TryExpression struct {
+	Token     token.Token
+	Block     *BlockStatement
+	Parameter *Identifier
+	Catch     *BlockStatement
+	Finally   *BlockStatement
+}
+
+func (t *TryExpression) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+func (t *TryExpression) Pos() token.Position {
+	return t.Token.Pos
+}
+
+func (t *TryExpression) End() token.Position {
+	switch {
+	case t.Finally != nil:
+		return t.Finally.End()
+	case t.Catch != nil:
+		return t.Catch.End()
+	case t.Block != nil:
+		return t.Block.End()
+	default:
+		return t.Token.End()
+	}
+}
+
+func (t *TryExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(t.Block.String())
+	if t.Catch != nil {
+		out.WriteString("catch(")
+		out.WriteString(t.Parameter.String())
+		out.WriteString(") ")
+		out.WriteString(t.Catch.String())
+	}
+	if t.Finally != nil {
+		out.WriteString("finally ")
+		out.WriteString(t.Finally.String())
+	}
+	return out.String()
+}
+
+func (t *TryExpression) expressionNode() {}
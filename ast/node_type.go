@@ -0,0 +1,132 @@
+package ast
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/token"
+	"strings"
+)
+
+// A TypeExpression is the type annotation of a let statement, a function
+// parameter or the result of a function. Annotations have no effect on how
+// a program is run. They are only used by package typecheck.
+type TypeExpression interface {
+	Node
+	typeNode()
+}
+
+// NamedType is a type referred to by its name, like int.
+type NamedType struct {
+	Token token.Token
+	Name  string
+}
+
+func (n *NamedType) TokenLiteral() string {
+	return n.Token.Literal
+}
+
+func (n *NamedType) Pos() token.Position {
+	return n.Token.Pos
+}
+
+func (n *NamedType) End() token.Position {
+	return n.Token.End()
+}
+
+func (n *NamedType) String() string {
+	return n.Name
+}
+
+func (n *NamedType) typeNode() {}
+
+// ArrayType is the type of arrays, like [int].
+type ArrayType struct {
+	// Token is the opening bracket.
+	Token   token.Token
+	Element TypeExpression
+	// RBracket is the closing bracket.
+	RBracket token.Token
+}
+
+func (a *ArrayType) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+func (a *ArrayType) Pos() token.Position {
+	return a.Token.Pos
+}
+
+func (a *ArrayType) End() token.Position {
+	return a.RBracket.End()
+}
+
+func (a *ArrayType) String() string {
+	return "[" + a.Element.String() + "]"
+}
+
+func (a *ArrayType) typeNode() {}
+
+// HashType is the type of hashes, like {string: int}.
+type HashType struct {
+	// Token is the opening brace.
+	Token token.Token
+	Key   TypeExpression
+	Value TypeExpression
+	// RBrace is the closing brace.
+	RBrace token.Token
+}
+
+func (h *HashType) TokenLiteral() string {
+	return h.Token.Literal
+}
+
+func (h *HashType) Pos() token.Position {
+	return h.Token.Pos
+}
+
+func (h *HashType) End() token.Position {
+	return h.RBrace.End()
+}
+
+func (h *HashType) String() string {
+	return "{" + h.Key.String() + ": " + h.Value.String() + "}"
+}
+
+func (h *HashType) typeNode() {}
+
+// FunctionType is the type of functions, like fn(int, int) -> bool.
+type FunctionType struct {
+	Token      token.Token
+	Parameters []TypeExpression
+	ReturnType TypeExpression
+}
+
+func (f *FunctionType) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+func (f *FunctionType) Pos() token.Position {
+	return f.Token.Pos
+}
+
+func (f *FunctionType) End() token.Position {
+	if f.ReturnType == nil {
+		return f.Token.End()
+	}
+	return f.ReturnType.End()
+}
+
+func (f *FunctionType) String() string {
+	params := make([]string, 0, len(f.Parameters))
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+
+	var out bytes.Buffer
+	out.WriteString("fn(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") -> ")
+	out.WriteString(f.ReturnType.String())
+	return out.String()
+}
+
+func (f *FunctionType) typeNode() {}
@@ -0,0 +1,157 @@
+package ast
+
+import "fmt"
+
+// Rewrite traverses an AST in depth-first order and allows to replace nodes.
+//
+// The children of a node are rewritten before the node itself. Each node is then
+// replaced by the result of f, which may be the node itself. Rewrite returns
+// the rewritten node.
+//
+// The replacement of a node must be usable in place of the node: Statements
+// must be replaced by statements, expressions by expressions and identifiers
+// which name a binding, like the name of a let statement, or a member by
+// identifiers. The path of an import statement can only be replaced by a
+// string literal. Otherwise, Rewrite panics.
+//
+// Type annotations are neither rewritten nor passed to f.
+func Rewrite(node Node, f func(Node) Node) Node {
+	switch n := node.(type) {
+	case *Program:
+		for i, stmt := range n.Statements {
+			n.Statements[i] = rewriteStatement(stmt, f)
+		}
+	case *LetStatement:
+		if n.Name != nil {
+			n.Name = rewriteIdentifier(n.Name, f)
+		}
+		n.Value = rewriteExpression(n.Value, f)
+	case *ImportStatement:
+		if n.Path != nil {
+			n.Path = rewriteString(n.Path, f)
+		}
+		if n.Name != nil {
+			n.Name = rewriteIdentifier(n.Name, f)
+		}
+	case *ReturnStatement:
+		n.ReturnValue = rewriteExpression(n.ReturnValue, f)
+	case *ThrowStatement:
+		n.Value = rewriteExpression(n.Value, f)
+	case *ExpressionStatement:
+		n.Expression = rewriteExpression(n.Expression, f)
+	case *PrefixExpression:
+		n.Right = rewriteExpression(n.Right, f)
+	case *InfixExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Right = rewriteExpression(n.Right, f)
+	case *BlockStatement:
+		for i, stmt := range n.Statements {
+			n.Statements[i] = rewriteStatement(stmt, f)
+		}
+	case *IfExpression:
+		n.Condition = rewriteExpression(n.Condition, f)
+		n.Consequence = rewriteBlock(n.Consequence, f)
+		n.Alternative = rewriteBlock(n.Alternative, f)
+	case *TryExpression:
+		n.Block = rewriteBlock(n.Block, f)
+		if n.Parameter != nil {
+			n.Parameter = rewriteIdentifier(n.Parameter, f)
+		}
+		n.Catch = rewriteBlock(n.Catch, f)
+		n.Finally = rewriteBlock(n.Finally, f)
+	case *FunctionLiteral:
+		for i, param := range n.Parameters {
+			n.Parameters[i] = rewriteIdentifier(param, f)
+		}
+		n.Body = rewriteBlock(n.Body, f)
+	case *CallExpression:
+		n.Function = rewriteExpression(n.Function, f)
+		for i, arg := range n.Arguments {
+			n.Arguments[i] = rewriteExpression(arg, f)
+		}
+	case *ArrayLiteral:
+		for i, element := range n.Elements {
+			n.Elements[i] = rewriteExpression(element, f)
+		}
+	case *MemberExpression:
+		n.Object = rewriteExpression(n.Object, f)
+		if n.Property != nil {
+			n.Property = rewriteIdentifier(n.Property, f)
+		}
+	case *IndexExpression:
+		n.Left = rewriteExpression(n.Left, f)
+		n.Index = rewriteExpression(n.Index, f)
+	case *HashLiteral:
+		for i, pair := range n.Pairs {
+			n.Pairs[i] = HashPair{
+				Key:   rewriteExpression(pair.Key, f),
+				Value: rewriteExpression(pair.Value, f),
+			}
+		}
+	case *Identifier, *IntegerLiteral, *BooleanLiteral, *StringLiteral:
+		// nothing to do
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", n))
+	}
+
+	return f(node)
+}
+
+func rewriteStatement(stmt Statement, f func(Node) Node) Statement {
+	if stmt == nil {
+		return nil
+	}
+
+	node := Rewrite(stmt, f)
+	replacement, ok := node.(Statement)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace statement %T with %T", stmt, node))
+	}
+	return replacement
+}
+
+// rewriteBlock rewrites the statements of a block. Blocks themselves can only
+// be replaced by blocks, since they are the body of functions and branches.
+func rewriteBlock(block *BlockStatement, f func(Node) Node) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+
+	node := Rewrite(block, f)
+	replacement, ok := node.(*BlockStatement)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace block with %T", node))
+	}
+	return replacement
+}
+
+func rewriteExpression(exp Expression, f func(Node) Node) Expression {
+	if exp == nil {
+		return nil
+	}
+
+	node := Rewrite(exp, f)
+	replacement, ok := node.(Expression)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace expression %T with %T", exp, node))
+	}
+	return replacement
+}
+
+func rewriteIdentifier(ident *Identifier, f func(Node) Node) *Identifier {
+	node := Rewrite(ident, f)
+	replacement, ok := node.(*Identifier)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace identifier with %T", node))
+	}
+	return replacement
+}
+
+func rewriteString(str *StringLiteral, f func(Node) Node) *StringLiteral {
+	node := Rewrite(str, f)
+	replacement, ok := node.(*StringLiteral)
+	if !ok {
+		panic(fmt.Sprintf("ast.Rewrite: cannot replace import path with %T", node))
+	}
+	return replacement
+}
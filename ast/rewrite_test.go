@@ -0,0 +1,71 @@
+package ast
+
+import (
+	"github.com/fabiante/monkeylang/token"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	t.Run("replaces nodes", func(t *testing.T) {
+		prog := testProgram()
+
+		// rename all identifiers and replace negations of booleans by their result
+		result := Rewrite(prog, func(node Node) Node {
+			switch n := node.(type) {
+			case *Identifier:
+				return testIdentifier(n.Value + "2")
+			case *PrefixExpression:
+				if b, ok := n.Right.(*BooleanLiteral); ok && n.Operator == "!" {
+					return &BooleanLiteral{
+						Token: token.Token{Type: token.False, Literal: "false"},
+						Value: !b.Value,
+					}
+				}
+			}
+			return node
+		})
+
+		assert.Same(t, prog, result)
+		assert.Equal(t, "let x2 = ((-a2) + 5);return false;x2", prog.String())
+	})
+
+	t.Run("rewrites children first", func(t *testing.T) {
+		var visited []string
+		Rewrite(testProgram().Statements[0], func(node Node) Node {
+			visited = append(visited, node.String())
+			return node
+		})
+
+		assert.Equal(t, []string{"x", "a", "(-a)", "5", "((-a) + 5)", "let x = ((-a) + 5);"}, visited)
+	})
+
+	t.Run("replaces nodes in all node types", func(t *testing.T) {
+		fn := testFunction()
+
+		var blocks int
+		Rewrite(fn, func(node Node) Node {
+			switch n := node.(type) {
+			case *Identifier:
+				return testIdentifier(n.Value + "2")
+			case *BlockStatement:
+				blocks++
+			}
+			return node
+		})
+
+		assert.Equal(t, 3, blocks)
+		assert.Equal(t, `fn(a2) ifa2 f2((a2[0]), {b2:[c2]})else d`, fn.String())
+	})
+
+	t.Run("panics on incompatible replacement", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Rewrite(testProgram(), func(node Node) Node {
+				if _, ok := node.(*IntegerLiteral); ok {
+					return &ReturnStatement{}
+				}
+				return node
+			})
+		})
+	})
+}
@@ -0,0 +1,212 @@
+package ast
+
+import (
+	"fmt"
+	"github.com/fabiante/monkeylang/token"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// testProgram builds the AST of:
+//
+//	let x = -a + 5;
+//	return !true;
+//	x;
+func testProgram() *Program {
+	prog := NewProgram()
+	prog.Statements = append(prog.Statements,
+		&LetStatement{
+			Token: token.Token{Type: token.Let, Literal: "let"},
+			Name:  testIdentifier("x"),
+			Value: &InfixExpression{
+				Token:    token.Token{Type: token.Plus, Literal: "+"},
+				Operator: "+",
+				Left: &PrefixExpression{
+					Token:    token.Token{Type: token.Minus, Literal: "-"},
+					Operator: "-",
+					Right:    testIdentifier("a"),
+				},
+				Right: &IntegerLiteral{
+					Token: token.Token{Type: token.Int, Literal: "5"},
+					Value: 5,
+				},
+			},
+		},
+		&ReturnStatement{
+			Token: token.Token{Type: token.Return, Literal: "return"},
+			ReturnValue: &PrefixExpression{
+				Token:    token.Token{Type: token.Bang, Literal: "!"},
+				Operator: "!",
+				Right: &BooleanLiteral{
+					Token: token.Token{Type: token.True, Literal: "true"},
+					Value: true,
+				},
+			},
+		},
+		&ExpressionStatement{
+			Token:      token.Token{Type: token.Identifier, Literal: "x"},
+			Expression: testIdentifier("x"),
+		},
+	)
+	return prog
+}
+
+func testIdentifier(name string) *Identifier {
+	return &Identifier{
+		Token: token.Token{Type: token.Identifier, Literal: name},
+		Value: name,
+	}
+}
+
+type recordingVisitor struct {
+	visited *[]string
+}
+
+func (r recordingVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		*r.visited = append(*r.visited, "end")
+	} else {
+		*r.visited = append(*r.visited, fmt.Sprintf("%T", node))
+	}
+	return r
+}
+
+func TestWalk(t *testing.T) {
+	var visited []string
+	Walk(recordingVisitor{visited: &visited}, testProgram())
+
+	expected := []string{
+		"*ast.Program",
+		"*ast.LetStatement",
+		"*ast.Identifier", "end",
+		"*ast.InfixExpression",
+		"*ast.PrefixExpression",
+		"*ast.Identifier", "end",
+		"end",
+		"*ast.IntegerLiteral", "end",
+		"end",
+		"end",
+		"*ast.ReturnStatement",
+		"*ast.PrefixExpression",
+		"*ast.BooleanLiteral", "end",
+		"end",
+		"end",
+		"*ast.ExpressionStatement",
+		"*ast.Identifier", "end",
+		"end",
+		"end",
+	}
+
+	assert.Equal(t, expected, visited)
+}
+
+// testFunction builds the AST of:
+//
+//	fn(a) { if (a) { f(a[0], {b: [c]}) } else { "d" } }
+func testFunction() *FunctionLiteral {
+	return &FunctionLiteral{
+		Token:      token.Token{Type: token.Func, Literal: "fn"},
+		Parameters: []*Identifier{testIdentifier("a")},
+		Body: &BlockStatement{Statements: []Statement{
+			&ExpressionStatement{Expression: &IfExpression{
+				Condition: testIdentifier("a"),
+				Consequence: &BlockStatement{Statements: []Statement{
+					&ExpressionStatement{Expression: &CallExpression{
+						Function: testIdentifier("f"),
+						Arguments: []Expression{
+							&IndexExpression{
+								Left:  testIdentifier("a"),
+								Index: &IntegerLiteral{Token: token.Token{Type: token.Int, Literal: "0"}},
+							},
+							&HashLiteral{Pairs: []HashPair{{
+								Key:   testIdentifier("b"),
+								Value: &ArrayLiteral{Elements: []Expression{testIdentifier("c")}},
+							}}},
+						},
+					}},
+				}},
+				Alternative: &BlockStatement{Statements: []Statement{
+					&ExpressionStatement{Expression: &StringLiteral{Token: token.Token{Type: token.String, Literal: "d"}, Value: "d"}},
+				}},
+			}},
+		}},
+	}
+}
+
+func TestInspect(t *testing.T) {
+	t.Run("visits all nodes", func(t *testing.T) {
+		var identifiers []string
+		Inspect(testProgram(), func(node Node) bool {
+			if ident, ok := node.(*Identifier); ok {
+				identifiers = append(identifiers, ident.Value)
+			}
+			return true
+		})
+
+		assert.Equal(t, []string{"x", "a", "x"}, identifiers)
+	})
+
+	t.Run("visits all node types", func(t *testing.T) {
+		var visited []string
+		Inspect(testFunction(), func(node Node) bool {
+			switch n := node.(type) {
+			case *Identifier:
+				visited = append(visited, n.Value)
+			case *StringLiteral:
+				visited = append(visited, n.Value)
+			case *IntegerLiteral:
+				visited = append(visited, n.Token.Literal)
+			}
+			return true
+		})
+
+		assert.Equal(t, []string{"a", "a", "f", "a", "0", "b", "c", "d"}, visited)
+	})
+
+	t.Run("visits type annotations", func(t *testing.T) {
+		// fn(a: [int]) -> fn({string: bool}) -> int {}
+		named := func(name string) *NamedType {
+			return &NamedType{Token: token.Token{Type: token.Identifier, Literal: name}, Name: name}
+		}
+		param := testIdentifier("a")
+		param.Type = &ArrayType{Element: named("int")}
+		fn := &FunctionLiteral{
+			Parameters: []*Identifier{param},
+			ReturnType: &FunctionType{
+				Parameters: []TypeExpression{&HashType{Key: named("string"), Value: named("bool")}},
+				ReturnType: named("int"),
+			},
+			Body: &BlockStatement{},
+		}
+
+		var visited []string
+		Inspect(fn, func(node Node) bool {
+			if node != nil {
+				visited = append(visited, fmt.Sprintf("%T", node))
+			}
+			return true
+		})
+
+		expected := []string{
+			"*ast.FunctionLiteral",
+			"*ast.Identifier", "*ast.ArrayType", "*ast.NamedType",
+			"*ast.FunctionType", "*ast.HashType", "*ast.NamedType", "*ast.NamedType", "*ast.NamedType",
+			"*ast.BlockStatement",
+		}
+		assert.Equal(t, expected, visited)
+	})
+
+	t.Run("skips children if false is returned", func(t *testing.T) {
+		var count int
+		Inspect(testProgram(), func(node Node) bool {
+			if node != nil {
+				count++
+			}
+			_, isPrefix := node.(*PrefixExpression)
+			return !isPrefix
+		})
+
+		// all nodes except the children of both prefix expressions
+		assert.Equal(t, 10, count)
+	})
+}
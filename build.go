@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/fabiante/monkeylang/mkc"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// buildCmd compiles the given file and writes the program in the .mkc format,
+// which can be executed with monkey run. Without -o, the output file is named
+// after the source file.
+func buildCmd(args []string) int {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	output := flags.String("o", "", "write the compiled program to `file`")
+	optimize := flags.Bool("O", false, "optimize the program before it is compiled")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 || (flags.Arg(0) == "-" && *output == "") {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: monkey build [-O] [-o out.mkc] <file | ->")
+		return exitUsage
+	}
+
+	input, err := readSource(flags.Arg(0))
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	bytecode, ok := compile(sourceName(flags.Arg(0)), input, *optimize)
+	if !ok {
+		return exitError
+	}
+
+	var out bytes.Buffer
+	if err := mkc.Write(&out, bytecode); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	name := *output
+	if name == "" {
+		name = strings.TrimSuffix(flags.Arg(0), filepath.Ext(flags.Arg(0))) + ".mkc"
+	}
+
+	if err := os.WriteFile(name, out.Bytes(), 0o644); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	return exitOK
+}
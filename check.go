@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/typecheck"
+	"os"
+)
+
+// checkCmd infers the types of the program in the given file and reports
+// type errors without running it. With --types, the inferred types of the
+// top-level let statements are printed.
+func checkCmd(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	types := flags.Bool("types", false, "print the types of the top-level bindings")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: monkey check [--types] <file | ->")
+		return exitUsage
+	}
+
+	input, err := readSource(flags.Arg(0))
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	name := sourceName(flags.Arg(0))
+
+	program, ok := parse(name, input, false, true)
+	if !ok {
+		return exitError
+	}
+
+	info, ok := checkTypes(name, program)
+
+	if *types {
+		for _, stmt := range program.Statements {
+			if let, ok := stmt.(*ast.LetStatement); ok {
+				_, _ = fmt.Printf("%s: %s\n", let.Name.Value, info.TypeOf(let.Name))
+			}
+		}
+	}
+
+	if !ok {
+		return exitError
+	}
+	return exitOK
+}
+
+// checkTypes infers the types of program. Type errors are printed to stderr.
+func checkTypes(name string, program *ast.Program) (*typecheck.Info, bool) {
+	info, errs := typecheck.Check(program)
+	for _, err := range errs {
+		_, _ = fmt.Fprintf(os.Stderr, "%s:%s\n", name, err)
+	}
+	return info, len(errs) == 0
+}
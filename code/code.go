@@ -0,0 +1,276 @@
+// Package code defines the bytecode instructions executed by the virtual machine.
+//
+// An instruction consists of a one byte Opcode followed by its operands.
+// Operands are encoded in big endian with the widths given by the Definition
+// of the opcode.
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Instructions is a flat sequence of encoded instructions.
+type Instructions []byte
+
+// String disassembles the instructions, one instruction per line prefixed by
+// its offset:
+//
+//	0000 OpConstant 0
+//	0003 OpConstant 1
+//	0006 OpAdd
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			_, _ = fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+
+		_, _ = fmt.Fprintf(&out, "%04d %s\n", i, FormatInstruction(def, operands))
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
+
+// FormatInstruction returns the name of an instruction followed by its operands.
+func FormatInstruction(def *Definition, operands []int) string {
+	operandCount := len(def.OperandWidths)
+
+	if len(operands) != operandCount {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d", len(operands), operandCount)
+	}
+
+	switch operandCount {
+	case 0:
+		return def.Name
+	case 1:
+		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	}
+
+	return fmt.Sprintf("ERROR: unhandled operandCount for %s", def.Name)
+}
+
+type Opcode byte
+
+const (
+	// OpConstant pushes the constant with the given index in the constant pool.
+	OpConstant Opcode = iota
+	// OpPop pops the topmost element of the stack. It is emitted after
+	// expression statements.
+	OpPop
+
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+
+	OpTrue
+	OpFalse
+	OpNull
+
+	OpEqual
+	OpNotEqual
+	OpGreaterThan
+	OpLessThan
+
+	OpMinus
+	OpBang
+
+	// OpJumpNotTruthy pops the condition and jumps to the given absolute
+	// offset if it is not truthy.
+	OpJumpNotTruthy
+	// OpJump jumps to the given absolute offset.
+	OpJump
+
+	OpGetGlobal
+	OpSetGlobal
+	OpGetLocal
+	OpSetLocal
+	OpGetBuiltin
+	OpGetFree
+	// OpCurrentClosure pushes the closure which is currently executed. This
+	// allows functions to call themselves.
+	OpCurrentClosure
+
+	// OpArray builds an array of the given number of elements from the stack.
+	OpArray
+	// OpHash builds a hash of the given number of elements from the stack.
+	// The number of elements is twice the number of key-value pairs.
+	OpHash
+	OpIndex
+
+	// OpCall calls the function below the given number of arguments on the stack.
+	OpCall
+	// OpTailCall is an OpCall whose result is returned from the current
+	// function. Calls of closures reuse the frame of the current function.
+	OpTailCall
+	// OpReturnValue returns the topmost element of the stack from the current function.
+	OpReturnValue
+	// OpReturn returns null from the current function.
+	OpReturn
+
+	// OpClosure wraps the compiled function with the given constant index in
+	// a closure. The second operand is the number of free variables, which
+	// are taken from the stack.
+	OpClosure
+
+	// OpTry installs a handler at the given absolute offset for errors thrown
+	// until the matching OpEndTry. The handler starts with the thrown error
+	// on the stack, which is restored to its height at the time of OpTry.
+	OpTry
+	// OpEndTry removes the handler installed last.
+	OpEndTry
+	// OpThrow pops an object and throws it as error.
+	OpThrow
+
+	// OpImport pushes the module whose top level is the compiled function
+	// with the given constant index. The function is called the first time
+	// the module is imported and returns the module with OpModule.
+	OpImport
+	// OpModule builds the module of the current function from the given
+	// number of exports on the stack. Each export is a name followed by its
+	// value.
+	OpModule
+	// OpMember replaces the module on the stack by its export whose name is
+	// the string constant with the given index.
+	OpMember
+)
+
+// Definition describes an Opcode for debugging and decoding.
+type Definition struct {
+	Name string
+	// OperandWidths contains the number of bytes of each operand.
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:       {"OpConstant", []int{2}},
+	OpPop:            {"OpPop", []int{}},
+	OpAdd:            {"OpAdd", []int{}},
+	OpSub:            {"OpSub", []int{}},
+	OpMul:            {"OpMul", []int{}},
+	OpDiv:            {"OpDiv", []int{}},
+	OpTrue:           {"OpTrue", []int{}},
+	OpFalse:          {"OpFalse", []int{}},
+	OpNull:           {"OpNull", []int{}},
+	OpEqual:          {"OpEqual", []int{}},
+	OpNotEqual:       {"OpNotEqual", []int{}},
+	OpGreaterThan:    {"OpGreaterThan", []int{}},
+	OpLessThan:       {"OpLessThan", []int{}},
+	OpMinus:          {"OpMinus", []int{}},
+	OpBang:           {"OpBang", []int{}},
+	OpJumpNotTruthy:  {"OpJumpNotTruthy", []int{2}},
+	OpJump:           {"OpJump", []int{2}},
+	OpGetGlobal:      {"OpGetGlobal", []int{2}},
+	OpSetGlobal:      {"OpSetGlobal", []int{2}},
+	OpGetLocal:       {"OpGetLocal", []int{1}},
+	OpSetLocal:       {"OpSetLocal", []int{1}},
+	OpGetBuiltin:     {"OpGetBuiltin", []int{1}},
+	OpGetFree:        {"OpGetFree", []int{1}},
+	OpCurrentClosure: {"OpCurrentClosure", []int{}},
+	OpArray:          {"OpArray", []int{2}},
+	OpHash:           {"OpHash", []int{2}},
+	OpIndex:          {"OpIndex", []int{}},
+	OpCall:           {"OpCall", []int{1}},
+	OpTailCall:       {"OpTailCall", []int{1}},
+	OpReturnValue:    {"OpReturnValue", []int{}},
+	OpReturn:         {"OpReturn", []int{}},
+	OpClosure:        {"OpClosure", []int{2, 1}},
+	OpTry:            {"OpTry", []int{2}},
+	OpEndTry:         {"OpEndTry", []int{}},
+	OpThrow:          {"OpThrow", []int{}},
+	OpImport:         {"OpImport", []int{2}},
+	OpModule:         {"OpModule", []int{2}},
+	OpMember:         {"OpMember", []int{2}},
+}
+
+// Lookup returns the definition of the given opcode.
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return def, nil
+}
+
+// MaxOperand returns the largest value of an operand of the given width in
+// bytes.
+func MaxOperand(width int) int {
+	return 1<<(8*width) - 1
+}
+
+// Make encodes an instruction. It returns an empty slice if the opcode is
+// undefined and panics if an operand does not fit into its width, see
+// MaxOperand.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	instructionLen := 1
+	for _, w := range def.OperandWidths {
+		instructionLen += w
+	}
+
+	instruction := make([]byte, instructionLen)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		if o < 0 || o > MaxOperand(width) {
+			panic(fmt.Sprintf("code: operand %d of %s does not fit into %d bytes", o, def.Name, width))
+		}
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += width
+	}
+
+	return instruction
+}
+
+// ReadOperands decodes the operands of an instruction, which start at the
+// beginning of ins. It returns the operands and the number of bytes read.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+
+	for i, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+
+		offset += width
+	}
+
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint8(ins Instructions) uint8 {
+	return ins[0]
+}
@@ -0,0 +1,125 @@
+package code
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpClosure, []int{65534, 255}, []byte{byte(OpClosure), 255, 254, 255}},
+	}
+
+	for _, test := range tests {
+		t.Run(definitions[test.op].Name, func(t *testing.T) {
+			assert.Equal(t, test.expected, Make(test.op, test.operands...))
+		})
+	}
+
+	t.Run("operands out of range", func(t *testing.T) {
+		assert.Panics(t, func() { Make(OpConstant, 65536) })
+		assert.Panics(t, func() { Make(OpGetLocal, 256) })
+		assert.Panics(t, func() { Make(OpClosure, 0, 256) })
+		assert.Panics(t, func() { Make(OpCall, -1) })
+	})
+}
+
+func TestInstructions_String(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpAdd),
+		Make(OpGetLocal, 1),
+		Make(OpConstant, 2),
+		Make(OpConstant, 65535),
+		Make(OpClosure, 65535, 255),
+	}
+
+	expected := `0000 OpAdd
+0001 OpGetLocal 1
+0003 OpConstant 2
+0006 OpConstant 65535
+0009 OpClosure 65535 255
+`
+
+	var concatted Instructions
+	for _, ins := range instructions {
+		concatted = append(concatted, ins...)
+	}
+
+	assert.Equal(t, expected, concatted.String())
+}
+
+func TestReadOperands(t *testing.T) {
+	tests := []struct {
+		op        Opcode
+		operands  []int
+		bytesRead int
+	}{
+		{OpConstant, []int{65535}, 2},
+		{OpGetLocal, []int{255}, 1},
+		{OpClosure, []int{65535, 255}, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(definitions[test.op].Name, func(t *testing.T) {
+			instruction := Make(test.op, test.operands...)
+
+			def, err := Lookup(byte(test.op))
+			require.NoError(t, err)
+
+			operandsRead, n := ReadOperands(def, instruction[1:])
+			assert.Equal(t, test.bytesRead, n)
+			assert.Equal(t, test.operands, operandsRead)
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	_, err := Lookup(255)
+	assert.Error(t, err)
+}
+
+func TestLineTable_Line(t *testing.T) {
+	table := LineTable{{Offset: 0, Line: 1}, {Offset: 3, Line: 2}, {Offset: 7, Line: 4}}
+
+	tests := []struct {
+		offset   int
+		expected int
+	}{
+		{0, 1},
+		{2, 1},
+		{3, 2},
+		{6, 2},
+		{7, 4},
+		{100, 4},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, table.Line(test.offset), "offset %d", test.offset)
+	}
+
+	assert.Equal(t, 0, LineTable(nil).Line(0))
+}
+
+func TestLineTable_Position(t *testing.T) {
+	table := LineTable{{Offset: 0, Line: 1, Column: 5}, {Offset: 3, Line: 1, Column: 1}}
+
+	line, column := table.Position(4)
+	assert.Equal(t, 1, line)
+	assert.Equal(t, 1, column)
+
+	line, column = table.Position(2)
+	assert.Equal(t, 1, line)
+	assert.Equal(t, 5, column)
+
+	line, column = LineTable(nil).Position(0)
+	assert.Zero(t, line)
+	assert.Zero(t, column)
+}
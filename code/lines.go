@@ -0,0 +1,32 @@
+package code
+
+// LineEntry states that the instructions starting at Offset were compiled from
+// the node at the given source line and column.
+type LineEntry struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// LineTable maps instruction offsets to source positions. The entries are
+// sorted by offset and each entry applies up to the offset of the next one.
+type LineTable []LineEntry
+
+// Line returns the source line of the instruction at the given offset or 0
+// if it is unknown.
+func (t LineTable) Line(offset int) int {
+	line, _ := t.Position(offset)
+	return line
+}
+
+// Position returns the source line and column of the instruction at the
+// given offset or zeros if they are unknown.
+func (t LineTable) Position(offset int) (line, column int) {
+	for _, entry := range t {
+		if entry.Offset > offset {
+			break
+		}
+		line, column = entry.Line, entry.Column
+	}
+	return line, column
+}
@@ -0,0 +1,887 @@
+// Package compiler compiles an *ast.Program into bytecode for the virtual machine.
+package compiler
+
+import (
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/code"
+	"github.com/fabiante/monkeylang/object"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// Bytecode is the result of a compilation.
+type Bytecode struct {
+	Instructions code.Instructions
+	Constants    []object.Object
+	// Lines maps the instructions to their positions in the source code.
+	Lines code.LineTable
+	// File is the name of the source file which has been compiled.
+	File string
+}
+
+// ModuleFunction is the name of the compiled functions which execute the top
+// level of modules.
+const ModuleFunction = "<module>"
+
+type Compiler struct {
+	constants []object.Object
+
+	symbolTable *SymbolTable
+
+	// scopes contains one scope per function which is being compiled. The
+	// first scope is the top level of the program.
+	scopes     []compilationScope
+	scopeIndex int
+
+	// pos is the source position of the node which is being compiled.
+	pos token.Position
+	// file is the name of the source file which is being compiled.
+	file string
+
+	// modules maps the files of the compiled modules to the constant index of
+	// the functions which execute their top level.
+	modules map[string]int
+
+	// err is the first error found by emit, which is reported for operands
+	// that do not fit into their instruction. It is returned by Compile.
+	err error
+}
+
+// compilationScope holds the instructions of a function while it is compiled.
+type compilationScope struct {
+	instructions code.Instructions
+	lines        code.LineTable
+
+	// lastInstruction and previousInstruction allow to remove or replace the
+	// last instruction, for example the OpPop of an expression statement
+	// whose value is used as result of a block.
+	lastInstruction     emittedInstruction
+	previousInstruction emittedInstruction
+
+	// handlers contains the error handlers of the try expressions which
+	// enclose the code being compiled, starting with the outermost one.
+	handlers []handler
+}
+
+// handler is an error handler installed by OpTry.
+type handler struct {
+	// finally is the finally clause which must be executed when the handler
+	// is left by a return statement. It is nil for handlers of catch clauses.
+	finally *ast.BlockStatement
+}
+
+type emittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+func NewCompiler() *Compiler {
+	symbolTable := NewSymbolTable()
+	for i, def := range object.Builtins {
+		symbolTable.DefineBuiltin(i, def.Name)
+	}
+
+	return &Compiler{
+		constants:   make([]object.Object, 0),
+		symbolTable: symbolTable,
+		modules:     make(map[string]int),
+		scopes: []compilationScope{
+			{instructions: code.Instructions{}},
+		},
+	}
+}
+
+// NewCompilerWithState creates a compiler which continues with the symbols and
+// constants of a previous compilation. This is used by the REPL, where each
+// line is compiled separately.
+func NewCompilerWithState(symbolTable *SymbolTable, constants []object.Object) *Compiler {
+	c := NewCompiler()
+	c.symbolTable = symbolTable
+	c.constants = constants
+	return c
+}
+
+// Compile compiles node. It fails if the program exceeds the limits of the
+// bytecode, for example if a function has more locals or a call more arguments
+// than their operands can hold.
+func (c *Compiler) Compile(node ast.Node) error {
+	if err := c.compile(node); err != nil {
+		return err
+	}
+	return c.err
+}
+
+func (c *Compiler) compile(node ast.Node) error {
+	defer c.enterPos(node)()
+
+	switch node := node.(type) {
+	case *ast.Program:
+		// Functions can call the functions declared after them, as they
+		// are called only after the program has declared both.
+		for _, stmt := range node.Statements {
+			if let, ok := stmt.(*ast.LetStatement); ok {
+				if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+					c.symbolTable.DefineLater(let.Name.Value)
+				}
+			}
+		}
+		for _, stmt := range node.Statements {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+	case *ast.BlockStatement:
+		for _, stmt := range node.Statements {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+	case *ast.LetStatement:
+		// The value is compiled first, so that it cannot refer to the binding
+		// itself. Functions refer to themselves by their name instead.
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.define(node.Name)
+	case *ast.ReturnStatement:
+		return c.compileReturn(node)
+	case *ast.ThrowStatement:
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(code.OpThrow)
+	case *ast.ImportStatement:
+		return c.compileImport(node)
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			return fmt.Errorf("undefined variable %s", node.Value)
+		}
+		c.loadSymbol(symbol)
+	case *ast.IntegerLiteral:
+		integer := &object.Integer{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(integer))
+	case *ast.StringLiteral:
+		str := &object.String{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(str))
+	case *ast.BooleanLiteral:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+	case *ast.PrefixExpression:
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+	case *ast.InfixExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "+":
+			c.emit(code.OpAdd)
+		case "-":
+			c.emit(code.OpSub)
+		case "*":
+			c.emit(code.OpMul)
+		case "/":
+			c.emit(code.OpDiv)
+		case ">":
+			c.emit(code.OpGreaterThan)
+		case "<":
+			c.emit(code.OpLessThan)
+		case "==":
+			c.emit(code.OpEqual)
+		case "!=":
+			c.emit(code.OpNotEqual)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+	case *ast.IfExpression:
+		return c.compileIf(node, false)
+	case *ast.TryExpression:
+		return c.compileTry(node)
+	case *ast.FunctionLiteral:
+		c.enterScope()
+
+		if node.Name != "" {
+			c.symbolTable.DefineFunctionName(node.Name)
+		}
+
+		for _, param := range node.Parameters {
+			c.symbolTable.Define(param.Value)
+		}
+
+		if err := c.compileFunctionBody(node.Body); err != nil {
+			return err
+		}
+
+		freeSymbols := c.symbolTable.FreeSymbols
+		numLocals := c.symbolTable.numDefinitions
+		lines := c.scopes[c.scopeIndex].lines
+		instructions := c.leaveScope()
+
+		// The free variables are pushed before the closure is created.
+		for _, s := range freeSymbols {
+			c.loadSymbol(s)
+		}
+
+		compiledFn := &object.CompiledFunction{
+			Instructions:  instructions,
+			NumLocals:     numLocals,
+			NumParameters: len(node.Parameters),
+			Name:          node.Name,
+			Lines:         lines,
+			File:          c.file,
+			Source:        object.FunctionSource(node.Parameters, node.Body),
+		}
+
+		c.emit(code.OpClosure, c.addConstant(compiledFn), len(freeSymbols))
+	case *ast.CallExpression:
+		return c.compileCall(node, code.OpCall)
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			if err := c.Compile(element); err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpArray, len(node.Elements))
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			if err := c.Compile(pair.Key); err != nil {
+				return err
+			}
+			if err := c.Compile(pair.Value); err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpHash, len(node.Pairs)*2)
+	case *ast.IndexExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Index); err != nil {
+			return err
+		}
+
+		c.emit(code.OpIndex)
+	case *ast.MemberExpression:
+		if err := c.Compile(node.Object); err != nil {
+			return err
+		}
+
+		c.emit(code.OpMember, c.addConstant(&object.String{Value: node.Property.Value}))
+	case nil:
+		return fmt.Errorf("missing node")
+	default:
+		return fmt.Errorf("unsupported node type %T", node)
+	}
+
+	return nil
+}
+
+func (c *Compiler) compileIf(node *ast.IfExpression, tail bool) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	// The jump offsets are patched once the branches are compiled.
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileBlockValue(node.Consequence, tail); err != nil {
+		return err
+	}
+
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+	if node.Alternative == nil {
+		c.emit(code.OpNull)
+	} else {
+		if err := c.compileBlockValue(node.Alternative, tail); err != nil {
+			return err
+		}
+	}
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	return nil
+}
+
+// compileReturn compiles a return statement. The handlers of enclosing try
+// expressions are removed before returning, and their finally clauses are
+// executed in between.
+func (c *Compiler) compileReturn(node *ast.ReturnStatement) error {
+	handlers := c.scopes[c.scopeIndex].handlers
+
+	// A return statement at the top level ends the program. It is not a
+	// tail position, as there is no frame which could be reused. Neither is
+	// a return statement within a try expression, whose handlers must stay
+	// installed during the call.
+	if c.scopeIndex > 0 && len(handlers) == 0 {
+		if err := c.compileTail(node.ReturnValue); err != nil {
+			return err
+		}
+	} else {
+		if err := c.Compile(node.ReturnValue); err != nil {
+			return err
+		}
+	}
+
+	for i := len(handlers) - 1; i >= 0; i-- {
+		c.emit(code.OpEndTry)
+		if handlers[i].finally != nil {
+			// Errors thrown by the finally clause are handled by the
+			// handlers of the enclosing try expressions only.
+			c.scopes[c.scopeIndex].handlers = handlers[:i]
+			if err := c.compileFinally(handlers[i].finally); err != nil {
+				return err
+			}
+		}
+	}
+	c.scopes[c.scopeIndex].handlers = handlers
+
+	c.emit(code.OpReturnValue)
+	return nil
+}
+
+// compileTry compiles a try expression. The block is protected by a handler
+// for the catch clause, which is protected by a handler for the finally
+// clause in turn:
+//
+//	OpTry finally
+//	OpTry catch
+//	<block>
+//	OpEndTry
+//	OpJump done
+//	catch: <catch clause>
+//	done: OpEndTry
+//	<finally clause>
+//	OpJump end
+//	finally: <finally clause>
+//	OpThrow
+//	end:
+//
+// The finally clause is compiled twice: Once for the case that the block or
+// the catch clause completes and once for the case that they throw, after
+// which the error is thrown again.
+func (c *Compiler) compileTry(node *ast.TryExpression) error {
+	handlers := c.scopes[c.scopeIndex].handlers
+
+	finallyPos := -1
+	if node.Finally != nil {
+		finallyPos = c.emit(code.OpTry, 9999)
+		c.scopes[c.scopeIndex].handlers = append(handlers[:len(handlers):len(handlers)], handler{finally: node.Finally})
+	}
+
+	if node.Catch != nil {
+		catchPos := c.emit(code.OpTry, 9999)
+		outer := c.scopes[c.scopeIndex].handlers
+		c.scopes[c.scopeIndex].handlers = append(outer[:len(outer):len(outer)], handler{})
+
+		if err := c.compileBlockValue(node.Block, false); err != nil {
+			return err
+		}
+		c.emit(code.OpEndTry)
+		jumpPos := c.emit(code.OpJump, 9999)
+
+		// The handler starts with the error on the stack.
+		c.scopes[c.scopeIndex].handlers = outer
+		c.changeOperand(catchPos, len(c.currentInstructions()))
+		c.define(node.Parameter)
+		if err := c.compileBlockValue(node.Catch, false); err != nil {
+			return err
+		}
+
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+	} else {
+		if err := c.compileBlockValue(node.Block, false); err != nil {
+			return err
+		}
+	}
+
+	c.scopes[c.scopeIndex].handlers = handlers
+	if node.Finally == nil {
+		return nil
+	}
+
+	c.emit(code.OpEndTry)
+	if err := c.compileFinally(node.Finally); err != nil {
+		return err
+	}
+	jumpPos := c.emit(code.OpJump, 9999)
+
+	c.changeOperand(finallyPos, len(c.currentInstructions()))
+	if err := c.compileFinally(node.Finally); err != nil {
+		return err
+	}
+	c.emit(code.OpThrow)
+
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+
+	return nil
+}
+
+// compileFinally compiles a finally clause, whose value is discarded.
+func (c *Compiler) compileFinally(block *ast.BlockStatement) error {
+	if err := c.compileBlockValue(block, false); err != nil {
+		return err
+	}
+	c.emit(code.OpPop)
+	return nil
+}
+
+// compileImport binds the name of an import statement to the imported module.
+// Each module is compiled once into a function which executes its top level.
+func (c *Compiler) compileImport(node *ast.ImportStatement) error {
+	if node.Program == nil {
+		return fmt.Errorf("module %q has not been loaded", node.Path.Value)
+	}
+
+	index, ok := c.modules[node.File]
+	if !ok {
+		var err error
+		if index, err = c.compileModule(node.File, node.Program); err != nil {
+			return err
+		}
+	}
+
+	c.emit(code.OpImport, index)
+	c.define(node.Name)
+	return nil
+}
+
+// compileModule compiles the top level of a module into a function which
+// returns the module, and returns the constant index of the function. The
+// bindings of the module are globals in a symbol table of their own, see
+// NewModuleSymbolTable, so the module cannot refer to the names of the
+// importing program.
+func (c *Compiler) compileModule(file string, program *ast.Program) (int, error) {
+	// The index is reserved before the module is compiled, so that the
+	// modules it imports can refer to it.
+	index := c.addConstant(nil)
+	c.modules[file] = index
+
+	// The position of the import statement is unrelated to the module.
+	outerTable, outerFile, outerPos := c.symbolTable, c.file, c.pos
+	defer func() { c.symbolTable, c.file, c.pos = outerTable, outerFile, outerPos }()
+
+	c.enterScope()
+	c.symbolTable = NewModuleSymbolTable(outerTable)
+	for i, def := range object.Builtins {
+		c.symbolTable.DefineBuiltin(i, def.Name)
+	}
+	c.file = file
+	c.pos = token.Position{}
+
+	if err := c.Compile(program); err != nil {
+		return 0, err
+	}
+
+	exported := make(map[string]bool)
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || !let.Exported() || exported[let.Name.Value] {
+			continue
+		}
+		exported[let.Name.Value] = true
+
+		symbol, _ := c.symbolTable.Resolve(let.Name.Value)
+		c.emit(code.OpConstant, c.addConstant(&object.String{Value: let.Name.Value}))
+		c.loadSymbol(symbol)
+	}
+	c.emit(code.OpModule, len(exported))
+	c.emit(code.OpReturnValue)
+
+	lines := c.scopes[c.scopeIndex].lines
+	instructions := c.leaveScope()
+
+	c.constants[index] = &object.CompiledFunction{
+		Instructions: instructions,
+		Name:         ModuleFunction,
+		Lines:        lines,
+		File:         file,
+	}
+	return index, nil
+}
+
+func (c *Compiler) compileCall(node *ast.CallExpression, op code.Opcode) error {
+	if err := c.Compile(node.Function); err != nil {
+		return err
+	}
+
+	for _, arg := range node.Arguments {
+		if err := c.Compile(arg); err != nil {
+			return err
+		}
+	}
+
+	c.emit(op, len(node.Arguments))
+
+	return nil
+}
+
+// compileTail compiles an expression whose value is returned from the current
+// function. Calls in this position are compiled to OpTailCall, which reuses
+// the frame of the function. This applies to the branches of if expressions
+// in tail position as well.
+func (c *Compiler) compileTail(exp ast.Expression) error {
+	defer c.enterPos(exp)()
+
+	switch exp := exp.(type) {
+	case *ast.CallExpression:
+		return c.compileCall(exp, code.OpTailCall)
+	case *ast.IfExpression:
+		return c.compileIf(exp, true)
+	default:
+		return c.Compile(exp)
+	}
+}
+
+// compileFunctionBody compiles the body of a function. The value of the last
+// expression statement is returned implicitly. Functions without such a
+// statement return null.
+func (c *Compiler) compileFunctionBody(body *ast.BlockStatement) error {
+	if last, ok := lastExpressionStatement(body); ok {
+		for _, stmt := range body.Statements[:len(body.Statements)-1] {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+
+		defer c.enterPos(last)()
+		if err := c.compileTail(last.Expression); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+		return nil
+	}
+
+	if err := c.Compile(body); err != nil {
+		return err
+	}
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+	return nil
+}
+
+// compileBlockValue compiles a block whose value is used, like a branch of an
+// if expression. The value of the last expression statement stays on the
+// stack. Blocks without such a statement produce null. If tail is true, the
+// value of the block is returned from the current function.
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement, tail bool) error {
+	if last, ok := lastExpressionStatement(block); ok && tail {
+		for _, stmt := range block.Statements[:len(block.Statements)-1] {
+			if err := c.Compile(stmt); err != nil {
+				return err
+			}
+		}
+		return c.compileTail(last.Expression)
+	}
+
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+		Lines:        c.scopes[c.scopeIndex].lines,
+		File:         c.file,
+	}
+}
+
+// SetFile sets the name of the source file which is compiled. It is recorded
+// in the compiled functions, so that stack traces can refer to it.
+func (c *Compiler) SetFile(file string) {
+	c.file = file
+}
+
+// SymbolTable returns the symbol table of the top level of the program.
+func (c *Compiler) SymbolTable() *SymbolTable {
+	return c.symbolTable
+}
+
+// addConstant adds obj to the constant pool and returns its index.
+func (c *Compiler) addConstant(obj object.Object) int {
+	c.constants = append(c.constants, obj)
+	return len(c.constants) - 1
+}
+
+// emit adds an instruction to the current scope and returns its position.
+// Operands which do not fit into the instruction are replaced by 0 after the
+// error has been recorded in c.err.
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	operands = c.checkOperands(op, operands)
+	ins := code.Make(op, operands...)
+	pos := c.addInstruction(ins)
+
+	c.setLastInstruction(op, pos)
+
+	return pos
+}
+
+func (c *Compiler) addInstruction(ins []byte) int {
+	posNewInstruction := len(c.currentInstructions())
+	c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), ins...)
+	c.addLine(posNewInstruction)
+	return posNewInstruction
+}
+
+// addLine records the current source position for the instruction at the
+// given position, unless it continues the position of the previous instruction.
+func (c *Compiler) addLine(pos int) {
+	lines := c.scopes[c.scopeIndex].lines
+	if c.pos.Line == 0 {
+		return
+	}
+	if n := len(lines); n > 0 && lines[n-1].Line == c.pos.Line && lines[n-1].Column == c.pos.Column {
+		return
+	}
+	c.scopes[c.scopeIndex].lines = append(lines, code.LineEntry{Offset: pos, Line: c.pos.Line, Column: c.pos.Column})
+}
+
+func (c *Compiler) setLastInstruction(op code.Opcode, pos int) {
+	previous := c.scopes[c.scopeIndex].lastInstruction
+	last := emittedInstruction{Opcode: op, Position: pos}
+
+	c.scopes[c.scopeIndex].previousInstruction = previous
+	c.scopes[c.scopeIndex].lastInstruction = last
+}
+
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+func (c *Compiler) removeLastPop() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	c.scopes[c.scopeIndex].instructions = c.currentInstructions()[:last.Position]
+	c.scopes[c.scopeIndex].lastInstruction = previous
+
+	lines := c.scopes[c.scopeIndex].lines
+	for len(lines) > 0 && lines[len(lines)-1].Offset >= last.Position {
+		lines = lines[:len(lines)-1]
+	}
+	c.scopes[c.scopeIndex].lines = lines
+}
+
+func (c *Compiler) replaceInstruction(pos int, newInstruction []byte) {
+	ins := c.currentInstructions()
+	copy(ins[pos:], newInstruction)
+}
+
+// changeOperand replaces the operand of the instruction at the given position.
+func (c *Compiler) changeOperand(opPos int, operand int) {
+	op := code.Opcode(c.currentInstructions()[opPos])
+	newInstruction := code.Make(op, c.checkOperands(op, []int{operand})...)
+
+	c.replaceInstruction(opPos, newInstruction)
+}
+
+// checkOperands records an error in c.err if one of the operands of op does
+// not fit into its width. The operands are returned with those replaced by 0.
+func (c *Compiler) checkOperands(op code.Opcode, operands []int) []int {
+	def, err := code.Lookup(byte(op))
+	if err != nil {
+		return operands
+	}
+
+	for i, o := range operands {
+		if o >= 0 && o <= code.MaxOperand(def.OperandWidths[i]) {
+			continue
+		}
+		if c.err == nil {
+			c.err = fmt.Errorf("too many %s", operandName(op, i))
+		}
+		operands = append([]int(nil), operands...)
+		operands[i] = 0
+	}
+	return operands
+}
+
+// operandName describes what the i-th operand of op counts or indexes.
+func operandName(op code.Opcode, i int) string {
+	switch op {
+	case code.OpConstant, code.OpImport, code.OpMember:
+		return "constants"
+	case code.OpClosure:
+		if i == 0 {
+			return "constants"
+		}
+		return "free variables"
+	case code.OpGetFree:
+		return "free variables"
+	case code.OpGetGlobal, code.OpSetGlobal:
+		return "global bindings"
+	case code.OpGetLocal, code.OpSetLocal:
+		return "local bindings in function"
+	case code.OpCall, code.OpTailCall:
+		return "arguments in call"
+	case code.OpArray:
+		return "elements in array literal"
+	case code.OpHash:
+		return "pairs in hash literal"
+	case code.OpModule:
+		return "exports in module"
+	case code.OpJump, code.OpJumpNotTruthy, code.OpTry:
+		return "instructions in function"
+	default:
+		return "operands"
+	}
+}
+
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, compilationScope{instructions: code.Instructions{}})
+	c.scopeIndex++
+
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+
+	c.symbolTable = c.symbolTable.Outer
+
+	return instructions
+}
+
+func (c *Compiler) loadSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpGetGlobal, s.Index)
+	case LocalScope:
+		c.emit(code.OpGetLocal, s.Index)
+	case BuiltinScope:
+		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurrentClosure)
+	}
+}
+
+// define binds the name of ident in the current scope to the value on top of
+// the stack.
+func (c *Compiler) define(ident *ast.Identifier) {
+	symbol := c.symbolTable.Define(ident.Value)
+	if symbol.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, symbol.Index)
+	} else {
+		c.emit(code.OpSetLocal, symbol.Index)
+	}
+}
+
+// enterPos makes the position of node the current position, if it is known.
+// It returns a function which restores the previous position.
+func (c *Compiler) enterPos(node ast.Node) func() {
+	pos := nodePos(node)
+	if pos.Line == 0 {
+		return func() {}
+	}
+
+	outer := c.pos
+	c.pos = pos
+	return func() { c.pos = outer }
+}
+
+// lastExpressionStatement returns the last statement of block if it is an
+// expression statement.
+func lastExpressionStatement(block *ast.BlockStatement) (*ast.ExpressionStatement, bool) {
+	if len(block.Statements) == 0 {
+		return nil, false
+	}
+	last, ok := block.Statements[len(block.Statements)-1].(*ast.ExpressionStatement)
+	return last, ok
+}
+
+// nodePos returns the source position of the token of the given node or the
+// zero position if the node has no position.
+func nodePos(node ast.Node) token.Position {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		return node.Token.Pos
+	case *ast.LetStatement:
+		return node.Token.Pos
+	case *ast.ReturnStatement:
+		return node.Token.Pos
+	case *ast.ThrowStatement:
+		return node.Token.Pos
+	case *ast.Identifier:
+		return node.Token.Pos
+	case *ast.IntegerLiteral:
+		return node.Token.Pos
+	case *ast.StringLiteral:
+		return node.Token.Pos
+	case *ast.BooleanLiteral:
+		return node.Token.Pos
+	case *ast.PrefixExpression:
+		return node.Token.Pos
+	case *ast.InfixExpression:
+		return node.Token.Pos
+	case *ast.IfExpression:
+		return node.Token.Pos
+	case *ast.TryExpression:
+		return node.Token.Pos
+	case *ast.FunctionLiteral:
+		return node.Token.Pos
+	case *ast.CallExpression:
+		return node.Token.Pos
+	case *ast.ArrayLiteral:
+		return node.Token.Pos
+	case *ast.HashLiteral:
+		return node.Token.Pos
+	case *ast.IndexExpression:
+		return node.Token.Pos
+	case *ast.ImportStatement:
+		return node.Token.Pos
+	case *ast.MemberExpression:
+		return node.Token.Pos
+	default:
+		return token.Position{}
+	}
+}
@@ -0,0 +1,778 @@
+package compiler
+
+import (
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/code"
+	"github.com/fabiante/monkeylang/internal/testutil"
+	"github.com/fabiante/monkeylang/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+type compilerTest struct {
+	input                string
+	expectedConstants    []any
+	expectedInstructions []code.Instructions
+}
+
+func TestCompiler_Compile(t *testing.T) {
+	t.Run("integer arithmetic", func(t *testing.T) {
+		runCompilerTests(t, []compilerTest{
+			{
+				input:             "1 + 2",
+				expectedConstants: []any{1, 2},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "1; 2",
+				expectedConstants: []any{1, 2},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpPop),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "1 - 2 * 3 / 4",
+				expectedConstants: []any{1, 2, 3, 4},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpConstant, 2),
+					code.Make(code.OpMul),
+					code.Make(code.OpConstant, 3),
+					code.Make(code.OpDiv),
+					code.Make(code.OpSub),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "-1",
+				expectedConstants: []any{1},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpMinus),
+					code.Make(code.OpPop),
+				},
+			},
+		})
+	})
+
+	t.Run("boolean expressions", func(t *testing.T) {
+		runCompilerTests(t, []compilerTest{
+			{
+				input:             "true",
+				expectedConstants: []any{},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpTrue),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "1 > 2",
+				expectedConstants: []any{1, 2},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpGreaterThan),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "1 < 2",
+				expectedConstants: []any{1, 2},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpLessThan),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "true != false == true",
+				expectedConstants: []any{},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpTrue),
+					code.Make(code.OpFalse),
+					code.Make(code.OpNotEqual),
+					code.Make(code.OpTrue),
+					code.Make(code.OpEqual),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "!true",
+				expectedConstants: []any{},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpTrue),
+					code.Make(code.OpBang),
+					code.Make(code.OpPop),
+				},
+			},
+		})
+	})
+
+	t.Run("conditionals", func(t *testing.T) {
+		runCompilerTests(t, []compilerTest{
+			{
+				input:             "if (true) { 10 }; 3333;",
+				expectedConstants: []any{10, 3333},
+				expectedInstructions: []code.Instructions{
+					// 0000
+					code.Make(code.OpTrue),
+					// 0001
+					code.Make(code.OpJumpNotTruthy, 10),
+					// 0004
+					code.Make(code.OpConstant, 0),
+					// 0007
+					code.Make(code.OpJump, 11),
+					// 0010
+					code.Make(code.OpNull),
+					// 0011
+					code.Make(code.OpPop),
+					// 0012
+					code.Make(code.OpConstant, 1),
+					// 0015
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "if (true) { 10 } else { 20 }; 3333;",
+				expectedConstants: []any{10, 20, 3333},
+				expectedInstructions: []code.Instructions{
+					// 0000
+					code.Make(code.OpTrue),
+					// 0001
+					code.Make(code.OpJumpNotTruthy, 10),
+					// 0004
+					code.Make(code.OpConstant, 0),
+					// 0007
+					code.Make(code.OpJump, 13),
+					// 0010
+					code.Make(code.OpConstant, 1),
+					// 0013
+					code.Make(code.OpPop),
+					// 0014
+					code.Make(code.OpConstant, 2),
+					// 0017
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "if (true) { } else { let x = 1; }",
+				expectedConstants: []any{1},
+				expectedInstructions: []code.Instructions{
+					// 0000
+					code.Make(code.OpTrue),
+					// 0001
+					code.Make(code.OpJumpNotTruthy, 8),
+					// 0004
+					code.Make(code.OpNull),
+					// 0005
+					code.Make(code.OpJump, 15),
+					// 0008
+					code.Make(code.OpConstant, 0),
+					// 0011
+					code.Make(code.OpSetGlobal, 0),
+					// 0014
+					code.Make(code.OpNull),
+					// 0015
+					code.Make(code.OpPop),
+				},
+			},
+		})
+	})
+
+	t.Run("global let statements", func(t *testing.T) {
+		runCompilerTests(t, []compilerTest{
+			{
+				input:             "let one = 1; let two = 2;",
+				expectedConstants: []any{1, 2},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetGlobal, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetGlobal, 1),
+				},
+			},
+			{
+				input:             "let one = 1; let two = one; two;",
+				expectedConstants: []any{1},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetGlobal, 0),
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpSetGlobal, 1),
+					code.Make(code.OpGetGlobal, 1),
+					code.Make(code.OpPop),
+				},
+			},
+		})
+	})
+
+	t.Run("strings, arrays and hashes", func(t *testing.T) {
+		runCompilerTests(t, []compilerTest{
+			{
+				input:             `"mon" + "key"`,
+				expectedConstants: []any{"mon", "key"},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "[]",
+				expectedConstants: []any{},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpArray, 0),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "[1, 2 + 3][0]",
+				expectedConstants: []any{1, 2, 3, 0},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpConstant, 2),
+					code.Make(code.OpAdd),
+					code.Make(code.OpArray, 2),
+					code.Make(code.OpConstant, 3),
+					code.Make(code.OpIndex),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "{1: 2, 3: 4 * 5}",
+				expectedConstants: []any{1, 2, 3, 4, 5},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpConstant, 2),
+					code.Make(code.OpConstant, 3),
+					code.Make(code.OpConstant, 4),
+					code.Make(code.OpMul),
+					code.Make(code.OpHash, 4),
+					code.Make(code.OpPop),
+				},
+			},
+		})
+	})
+
+	t.Run("functions", func(t *testing.T) {
+		runCompilerTests(t, []compilerTest{
+			{
+				input: "fn() { return 5 + 10 }",
+				expectedConstants: []any{
+					5,
+					10,
+					[]code.Instructions{
+						code.Make(code.OpConstant, 0),
+						code.Make(code.OpConstant, 1),
+						code.Make(code.OpAdd),
+						code.Make(code.OpReturnValue),
+					},
+				},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpClosure, 2, 0),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input: "fn() { 1; 2 }",
+				expectedConstants: []any{
+					1,
+					2,
+					[]code.Instructions{
+						code.Make(code.OpConstant, 0),
+						code.Make(code.OpPop),
+						code.Make(code.OpConstant, 1),
+						code.Make(code.OpReturnValue),
+					},
+				},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpClosure, 2, 0),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input: "fn() { }",
+				expectedConstants: []any{
+					[]code.Instructions{
+						code.Make(code.OpReturn),
+					},
+				},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpClosure, 0, 0),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input: "let oneArg = fn(a) { a }; oneArg(24);",
+				expectedConstants: []any{
+					[]code.Instructions{
+						code.Make(code.OpGetLocal, 0),
+						code.Make(code.OpReturnValue),
+					},
+					24,
+				},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpClosure, 0, 0),
+					code.Make(code.OpSetGlobal, 0),
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input: "fn() { let a = 55; let b = 77; a + b }",
+				expectedConstants: []any{
+					55,
+					77,
+					[]code.Instructions{
+						code.Make(code.OpConstant, 0),
+						code.Make(code.OpSetLocal, 0),
+						code.Make(code.OpConstant, 1),
+						code.Make(code.OpSetLocal, 1),
+						code.Make(code.OpGetLocal, 0),
+						code.Make(code.OpGetLocal, 1),
+						code.Make(code.OpAdd),
+						code.Make(code.OpReturnValue),
+					},
+				},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpClosure, 2, 0),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input: "let num = 55; fn() { num }",
+				expectedConstants: []any{
+					55,
+					[]code.Instructions{
+						code.Make(code.OpGetGlobal, 0),
+						code.Make(code.OpReturnValue),
+					},
+				},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetGlobal, 0),
+					code.Make(code.OpClosure, 1, 0),
+					code.Make(code.OpPop),
+				},
+			},
+		})
+	})
+
+	t.Run("closures", func(t *testing.T) {
+		runCompilerTests(t, []compilerTest{
+			{
+				input: "fn(a) { fn(b) { a + b } }",
+				expectedConstants: []any{
+					[]code.Instructions{
+						code.Make(code.OpGetFree, 0),
+						code.Make(code.OpGetLocal, 0),
+						code.Make(code.OpAdd),
+						code.Make(code.OpReturnValue),
+					},
+					[]code.Instructions{
+						code.Make(code.OpGetLocal, 0),
+						code.Make(code.OpClosure, 0, 1),
+						code.Make(code.OpReturnValue),
+					},
+				},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpClosure, 1, 0),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input: "fn(a) { fn(b) { fn(c) { a + b + c } } };",
+				expectedConstants: []any{
+					[]code.Instructions{
+						code.Make(code.OpGetFree, 0),
+						code.Make(code.OpGetFree, 1),
+						code.Make(code.OpAdd),
+						code.Make(code.OpGetLocal, 0),
+						code.Make(code.OpAdd),
+						code.Make(code.OpReturnValue),
+					},
+					[]code.Instructions{
+						code.Make(code.OpGetFree, 0),
+						code.Make(code.OpGetLocal, 0),
+						code.Make(code.OpClosure, 0, 2),
+						code.Make(code.OpReturnValue),
+					},
+					[]code.Instructions{
+						code.Make(code.OpGetLocal, 0),
+						code.Make(code.OpClosure, 1, 1),
+						code.Make(code.OpReturnValue),
+					},
+				},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpClosure, 2, 0),
+					code.Make(code.OpPop),
+				},
+			},
+		})
+	})
+
+	t.Run("recursive functions", func(t *testing.T) {
+		runCompilerTests(t, []compilerTest{
+			{
+				input: "let countDown = fn(x) { countDown(x - 1); }; countDown(1);",
+				expectedConstants: []any{
+					1,
+					[]code.Instructions{
+						code.Make(code.OpCurrentClosure),
+						code.Make(code.OpGetLocal, 0),
+						code.Make(code.OpConstant, 0),
+						code.Make(code.OpSub),
+						code.Make(code.OpTailCall, 1),
+						code.Make(code.OpReturnValue),
+					},
+					1,
+				},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpClosure, 1, 0),
+					code.Make(code.OpSetGlobal, 0),
+					code.Make(code.OpGetGlobal, 0),
+					code.Make(code.OpConstant, 2),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpPop),
+				},
+			},
+		})
+	})
+
+	t.Run("tail calls", func(t *testing.T) {
+		runCompilerTests(t, []compilerTest{
+			{
+				input: "fn(f) { return f(); 1 }",
+				expectedConstants: []any{1, []code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpTailCall, 0),
+					code.Make(code.OpReturnValue),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+				}},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpClosure, 1, 0),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input: "fn(f) { if (true) { f() } else { len([]) } }",
+				expectedConstants: []any{[]code.Instructions{
+					code.Make(code.OpTrue),
+					code.Make(code.OpJumpNotTruthy, 11),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpTailCall, 0),
+					code.Make(code.OpJump, 18),
+					code.Make(code.OpGetBuiltin, 0),
+					code.Make(code.OpArray, 0),
+					code.Make(code.OpTailCall, 1),
+					code.Make(code.OpReturnValue),
+				}},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpClosure, 0, 0),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				// Calls whose value is used are not in tail position.
+				input: "fn(f) { f() + 1; f(); let a = f(); [f()] }",
+				expectedConstants: []any{1, []code.Instructions{
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCall, 0),
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpPop),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCall, 0),
+					code.Make(code.OpPop),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCall, 0),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpCall, 0),
+					code.Make(code.OpArray, 1),
+					code.Make(code.OpReturnValue),
+				}},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpClosure, 1, 0),
+					code.Make(code.OpPop),
+				},
+			},
+			{
+				input:             "return len([]);",
+				expectedConstants: []any{},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpGetBuiltin, 0),
+					code.Make(code.OpArray, 0),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+		})
+	})
+
+	t.Run("top level return", func(t *testing.T) {
+		runCompilerTests(t, []compilerTest{
+			{
+				input:             "return 1; 2;",
+				expectedConstants: []any{1, 2},
+				expectedInstructions: []code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpPop),
+				},
+			},
+		})
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			input         string
+			expectedError string
+		}{
+			{"x;", "undefined variable x"},
+			{"let x = x;", "undefined variable x"},
+			{"fn(a) { b }", "undefined variable b"},
+			{`import "m.mk" as m;`, `module "m.mk" has not been loaded`},
+		}
+
+		for _, test := range tests {
+			t.Run(test.input, func(t *testing.T) {
+				c := NewCompiler()
+				err := c.Compile(testutil.MustParse(t, test.input))
+				assert.EqualError(t, err, test.expectedError)
+			})
+		}
+	})
+}
+
+func TestCompiler_lines(t *testing.T) {
+	input := `let a = 1;
+if (a) {
+	a
+} else {
+	2
+};
+let f = fn() {
+	a
+};`
+
+	c := NewCompiler()
+	require.NoError(t, c.Compile(testutil.MustParse(t, input)))
+	bytecode := c.Bytecode()
+
+	assert.Equal(t, code.LineTable{
+		{Offset: 0, Line: 1, Column: 9},  // OpConstant 0
+		{Offset: 3, Line: 1, Column: 1},  // OpSetGlobal 0
+		{Offset: 6, Line: 2, Column: 5},  // OpGetGlobal 0 (condition)
+		{Offset: 9, Line: 2, Column: 1},  // OpJumpNotTruthy
+		{Offset: 12, Line: 3, Column: 2}, // OpGetGlobal 0 (consequence)
+		{Offset: 15, Line: 2, Column: 1}, // OpJump
+		{Offset: 18, Line: 5, Column: 2}, // OpConstant 1 (alternative)
+		{Offset: 21, Line: 2, Column: 1}, // OpPop
+		{Offset: 22, Line: 7, Column: 9}, // OpClosure
+		{Offset: 26, Line: 7, Column: 1}, // OpSetGlobal 1
+	}, bytecode.Lines)
+
+	fn, ok := bytecode.Constants[2].(*object.CompiledFunction)
+	require.True(t, ok)
+	assert.Equal(t, code.LineTable{{Offset: 0, Line: 8, Column: 2}}, fn.Lines)
+}
+
+func TestCompiler_modules(t *testing.T) {
+	program := testutil.MustParse(t, `import "m.mk" as m; import "m.mk" as n; m.two`)
+	module := testutil.MustParse(t, "let one = 1; export let two = one + 1;")
+	for _, stmt := range program.Statements[:2] {
+		stmt.(*ast.ImportStatement).File = "m.mk"
+		stmt.(*ast.ImportStatement).Program = module
+	}
+
+	c := NewCompiler()
+	require.NoError(t, c.Compile(program))
+	bytecode := c.Bytecode()
+
+	// The module is compiled once and imported by both statements. Its
+	// bindings are the first globals, as it is compiled before the first
+	// import statement defines its name.
+	assertInstructions(t, []code.Instructions{
+		code.Make(code.OpImport, 0),
+		code.Make(code.OpSetGlobal, 2),
+		code.Make(code.OpImport, 0),
+		code.Make(code.OpSetGlobal, 3),
+		code.Make(code.OpGetGlobal, 2),
+		code.Make(code.OpMember, 4),
+		code.Make(code.OpPop),
+	}, bytecode.Instructions)
+	assertConstants(t, []any{
+		[]code.Instructions{
+			code.Make(code.OpConstant, 1),
+			code.Make(code.OpSetGlobal, 0),
+			code.Make(code.OpGetGlobal, 0),
+			code.Make(code.OpConstant, 2),
+			code.Make(code.OpAdd),
+			code.Make(code.OpSetGlobal, 1),
+			code.Make(code.OpConstant, 3),
+			code.Make(code.OpGetGlobal, 1),
+			code.Make(code.OpModule, 1),
+			code.Make(code.OpReturnValue),
+		},
+		1,
+		1,
+		"two",
+		"two",
+	}, bytecode.Constants)
+
+	fn := bytecode.Constants[0].(*object.CompiledFunction)
+	assert.Equal(t, ModuleFunction, fn.Name)
+	assert.Equal(t, "m.mk", fn.File)
+	assert.Equal(t, 0, fn.NumLocals)
+}
+
+func TestCompiler_limits(t *testing.T) {
+	// repeat joins the results of f for 0 to n-1 with sep.
+	repeat := func(n int, sep string, f func(i int) string) string {
+		parts := make([]string, n)
+		for i := range parts {
+			parts[i] = f(i)
+		}
+		return strings.Join(parts, sep)
+	}
+	let := func(i int) string { return fmt.Sprintf("let a%d = true;", i) }
+
+	tests := []struct {
+		name          string
+		limit         int
+		input         func(n int) string
+		expectedError string
+	}{
+		{"constants", 65536, func(n int) string {
+			return strings.Repeat("1;", n)
+		}, "too many constants"},
+		{"globals", 65536, func(n int) string {
+			return repeat(n, "", let)
+		}, "too many global bindings"},
+		{"locals", 256, func(n int) string {
+			return "fn() { " + repeat(n, "", let) + " }"
+		}, "too many local bindings in function"},
+		{"arguments", 255, func(n int) string {
+			return "let f = fn() {}; f(" + repeat(n, ", ", func(int) string { return "true" }) + ");"
+		}, "too many arguments in call"},
+		{"free variables", 255, func(n int) string {
+			return "fn() { " + repeat(n, "", let) + " fn() { [" + repeat(n, ", ", func(i int) string { return fmt.Sprintf("a%d", i) }) + "] } }"
+		}, "too many free variables"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.NoError(t, NewCompiler().Compile(testutil.MustParse(t, test.input(test.limit))))
+			assert.EqualError(t, NewCompiler().Compile(testutil.MustParse(t, test.input(test.limit+1))), test.expectedError)
+		})
+	}
+
+	t.Run("module bindings", func(t *testing.T) {
+		// The bindings of modules are globals, which are shared with the
+		// importing program.
+		for _, n := range []int{65535, 65536} {
+			program := testutil.MustParse(t, `import "m.mk" as m;`)
+			program.Statements[0].(*ast.ImportStatement).File = "m.mk"
+			program.Statements[0].(*ast.ImportStatement).Program = testutil.MustParse(t, repeat(n, "", let))
+
+			err := NewCompiler().Compile(program)
+			if n == 65535 {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, "too many global bindings")
+			}
+		}
+	})
+}
+
+func TestCompiler_scopes(t *testing.T) {
+	c := NewCompiler()
+	require.Equal(t, 0, c.scopeIndex)
+	globalSymbolTable := c.symbolTable
+
+	c.emit(code.OpMul)
+
+	c.enterScope()
+	require.Equal(t, 1, c.scopeIndex)
+	assert.Same(t, globalSymbolTable, c.symbolTable.Outer)
+
+	c.emit(code.OpSub)
+	assert.Len(t, c.scopes[c.scopeIndex].instructions, 1)
+	assert.Equal(t, code.OpSub, c.scopes[c.scopeIndex].lastInstruction.Opcode)
+
+	c.leaveScope()
+	require.Equal(t, 0, c.scopeIndex)
+	assert.Same(t, globalSymbolTable, c.symbolTable)
+
+	c.emit(code.OpAdd)
+	assert.Len(t, c.scopes[c.scopeIndex].instructions, 2)
+	assert.Equal(t, code.OpAdd, c.scopes[c.scopeIndex].lastInstruction.Opcode)
+	assert.Equal(t, code.OpMul, c.scopes[c.scopeIndex].previousInstruction.Opcode)
+}
+
+func runCompilerTests(t *testing.T, tests []compilerTest) {
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("tests[%d]", i), func(t *testing.T) {
+			c := NewCompiler()
+			require.NoError(t, c.Compile(testutil.MustParse(t, test.input)))
+
+			bytecode := c.Bytecode()
+			assertInstructions(t, test.expectedInstructions, bytecode.Instructions)
+			assertConstants(t, test.expectedConstants, bytecode.Constants)
+		})
+	}
+}
+
+func assertInstructions(t *testing.T, expected []code.Instructions, actual code.Instructions) {
+	var concatted code.Instructions
+	for _, ins := range expected {
+		concatted = append(concatted, ins...)
+	}
+
+	// comparing the disassembled instructions gives readable failures
+	assert.Equal(t, concatted.String(), actual.String())
+}
+
+func assertConstants(t *testing.T, expected []any, actual []object.Object) {
+	require.Len(t, actual, len(expected))
+
+	for i, constant := range expected {
+		switch constant := constant.(type) {
+		case int:
+			integer, ok := actual[i].(*object.Integer)
+			require.True(t, ok, "constant %d has unexpected type %T", i, actual[i])
+			assert.Equal(t, int64(constant), integer.Value)
+		case string:
+			str, ok := actual[i].(*object.String)
+			require.True(t, ok, "constant %d has unexpected type %T", i, actual[i])
+			assert.Equal(t, constant, str.Value)
+		case []code.Instructions:
+			fn, ok := actual[i].(*object.CompiledFunction)
+			require.True(t, ok, "constant %d has unexpected type %T", i, actual[i])
+			assertInstructions(t, constant, fn.Instructions)
+		default:
+			panic(fmt.Errorf("unexpected constant type %T", constant))
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package compiler
+
+type SymbolScope string
+
+const (
+	GlobalScope  SymbolScope = "GLOBAL"
+	LocalScope   SymbolScope = "LOCAL"
+	BuiltinScope SymbolScope = "BUILTIN"
+	// FreeScope contains variables of enclosing functions which are used
+	// by a closure.
+	FreeScope SymbolScope = "FREE"
+	// FunctionScope contains the name of the function currently being
+	// compiled, which allows it to refer to itself.
+	FunctionScope SymbolScope = "FUNCTION"
+)
+
+// Symbol is a name bound to a slot in a scope.
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// SymbolTable resolves names to symbols. Each function body has its own
+// table which is enclosed by the table of the surrounding code.
+type SymbolTable struct {
+	Outer *SymbolTable
+
+	// FreeSymbols contains the symbols of enclosing tables, which are
+	// referenced in this table, in the order of their free index.
+	FreeSymbols []Symbol
+
+	store          map[string]Symbol
+	numDefinitions int
+
+	// numGlobals is the number of global bindings, which is shared by the
+	// tables of a program and the modules it imports. It is only used by
+	// tables without an outer table.
+	numGlobals *int
+
+	// later contains the names which are defined later on, see DefineLater.
+	later map[string]bool
+}
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{
+		store:       make(map[string]Symbol),
+		FreeSymbols: make([]Symbol, 0),
+		numGlobals:  new(int),
+	}
+}
+
+// NewModuleSymbolTable returns the table for the top level of a module which
+// is imported by the program whose names are resolved by table. The bindings
+// of the module are globals like those of the program, but are numbered after
+// them, so they never share a slot. The module cannot refer to the names of
+// the program, as they are not defined in the new table.
+func NewModuleSymbolTable(table *SymbolTable) *SymbolTable {
+	for table.Outer != nil {
+		table = table.Outer
+	}
+
+	s := NewSymbolTable()
+	s.numGlobals = table.numGlobals
+	return s
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewSymbolTable()
+	s.Outer = outer
+	return s
+}
+
+// Define binds name to the next free slot. The symbol is global if the table
+// has no outer table and local otherwise. Like in package resolver, defining
+// a name again in the same table reuses its slot. This is required for code
+// which is compiled more than once, like finally clauses.
+func (s *SymbolTable) Define(name string) Symbol {
+	scope := LocalScope
+	if s.Outer == nil {
+		scope = GlobalScope
+	}
+
+	if symbol, ok := s.store[name]; ok && symbol.Scope == scope {
+		return symbol
+	}
+
+	symbol := Symbol{Name: name, Scope: scope}
+	if scope == GlobalScope {
+		symbol.Index = *s.numGlobals
+		*s.numGlobals++
+	} else {
+		symbol.Index = s.numDefinitions
+		s.numDefinitions++
+	}
+	s.store[name] = symbol
+	return symbol
+}
+
+// DefineLater records that name is defined by a later statement. Enclosed
+// tables can resolve it before, which defines it right away. This allows
+// functions to refer to functions declared after them, as they are called
+// only after both have been defined.
+func (s *SymbolTable) DefineLater(name string) {
+	if s.later == nil {
+		s.later = make(map[string]bool)
+	}
+	s.later[name] = true
+}
+
+// DefineBuiltin binds name to the builtin function with the given index.
+func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
+	symbol := Symbol{Name: name, Index: index, Scope: BuiltinScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// DefineFunctionName binds the name of the function whose body this table belongs to.
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{Name: name, Index: 0, Scope: FunctionScope}
+	s.store[name] = symbol
+	return symbol
+}
+
+// Resolve looks up name in this table and its outer tables.
+//
+// Local variables of enclosing functions are turned into free symbols of this table.
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	return s.resolve(name, false)
+}
+
+// resolve looks up name like Resolve. enclosed is true if the lookup started
+// in an enclosed table, which may define the names recorded by DefineLater.
+func (s *SymbolTable) resolve(name string, enclosed bool) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	if !ok && enclosed && s.later[name] {
+		symbol, ok = s.Define(name), true
+	}
+	if ok || s.Outer == nil {
+		return symbol, ok
+	}
+
+	symbol, ok = s.Outer.resolve(name, true)
+	if !ok {
+		return symbol, ok
+	}
+
+	if symbol.Scope == GlobalScope || symbol.Scope == BuiltinScope {
+		return symbol, ok
+	}
+
+	return s.defineFree(symbol), true
+}
+
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{Name: original.Name, Index: len(s.FreeSymbols) - 1, Scope: FreeScope}
+	s.store[original.Name] = symbol
+	return symbol
+}
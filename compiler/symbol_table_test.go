@@ -0,0 +1,119 @@
+package compiler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSymbolTable_Define(t *testing.T) {
+	global := NewSymbolTable()
+	assert.Equal(t, Symbol{Name: "a", Scope: GlobalScope, Index: 0}, global.Define("a"))
+	assert.Equal(t, Symbol{Name: "b", Scope: GlobalScope, Index: 1}, global.Define("b"))
+
+	local := NewEnclosedSymbolTable(global)
+	assert.Equal(t, Symbol{Name: "c", Scope: LocalScope, Index: 0}, local.Define("c"))
+	assert.Equal(t, Symbol{Name: "d", Scope: LocalScope, Index: 1}, local.Define("d"))
+
+	nested := NewEnclosedSymbolTable(local)
+	assert.Equal(t, Symbol{Name: "e", Scope: LocalScope, Index: 0}, nested.Define("e"))
+}
+
+func TestSymbolTable_Define_redefinition(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+	assert.Equal(t, Symbol{Name: "a", Scope: GlobalScope, Index: 0}, global.Define("a"))
+	assert.Equal(t, Symbol{Name: "c", Scope: GlobalScope, Index: 2}, global.Define("c"))
+
+	local := NewEnclosedSymbolTable(global)
+	assert.Equal(t, Symbol{Name: "a", Scope: LocalScope, Index: 0}, local.Define("a"))
+	assert.Equal(t, Symbol{Name: "a", Scope: LocalScope, Index: 0}, local.Define("a"))
+}
+
+func TestSymbolTable_Resolve(t *testing.T) {
+	t.Run("global and local", func(t *testing.T) {
+		global := NewSymbolTable()
+		global.Define("a")
+
+		local := NewEnclosedSymbolTable(global)
+		local.Define("b")
+
+		tests := []Symbol{
+			{Name: "a", Scope: GlobalScope, Index: 0},
+			{Name: "b", Scope: LocalScope, Index: 0},
+		}
+
+		for _, expected := range tests {
+			actual, ok := local.Resolve(expected.Name)
+			require.True(t, ok, "name %s not resolvable", expected.Name)
+			assert.Equal(t, expected, actual)
+		}
+	})
+
+	t.Run("builtins in all scopes", func(t *testing.T) {
+		global := NewSymbolTable()
+		global.DefineBuiltin(0, "len")
+		global.DefineBuiltin(1, "puts")
+
+		local := NewEnclosedSymbolTable(NewEnclosedSymbolTable(global))
+
+		for i, name := range []string{"len", "puts"} {
+			actual, ok := local.Resolve(name)
+			require.True(t, ok, "name %s not resolvable", name)
+			assert.Equal(t, Symbol{Name: name, Scope: BuiltinScope, Index: i}, actual)
+		}
+	})
+
+	t.Run("free variables", func(t *testing.T) {
+		global := NewSymbolTable()
+		global.Define("a")
+
+		first := NewEnclosedSymbolTable(global)
+		first.Define("c")
+
+		second := NewEnclosedSymbolTable(first)
+		second.Define("e")
+
+		tests := []Symbol{
+			{Name: "a", Scope: GlobalScope, Index: 0},
+			{Name: "c", Scope: FreeScope, Index: 0},
+			{Name: "e", Scope: LocalScope, Index: 0},
+		}
+
+		for _, expected := range tests {
+			actual, ok := second.Resolve(expected.Name)
+			require.True(t, ok, "name %s not resolvable", expected.Name)
+			assert.Equal(t, expected, actual)
+		}
+
+		assert.Equal(t, []Symbol{{Name: "c", Scope: LocalScope, Index: 0}}, second.FreeSymbols)
+	})
+
+	t.Run("unresolvable", func(t *testing.T) {
+		local := NewEnclosedSymbolTable(NewSymbolTable())
+
+		_, ok := local.Resolve("x")
+		assert.False(t, ok)
+		assert.Empty(t, local.FreeSymbols)
+	})
+
+	t.Run("function name", func(t *testing.T) {
+		global := NewSymbolTable()
+		global.DefineFunctionName("a")
+
+		actual, ok := global.Resolve("a")
+		require.True(t, ok)
+		assert.Equal(t, Symbol{Name: "a", Scope: FunctionScope, Index: 0}, actual)
+	})
+
+	t.Run("shadowing function name", func(t *testing.T) {
+		global := NewSymbolTable()
+		global.DefineFunctionName("a")
+		global.Define("a")
+
+		actual, ok := global.Resolve("a")
+		require.True(t, ok)
+		assert.Equal(t, Symbol{Name: "a", Scope: GlobalScope, Index: 0}, actual)
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fabiante/monkeylang/disasm"
+	"github.com/fabiante/monkeylang/mkc"
+	"os"
+)
+
+// disasmCmd prints the disassembled bytecode of the given file as described
+// in package disasm. The file may contain source code or a compiled program.
+func disasmCmd(args []string) int {
+	flags := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	optimize := flags.Bool("O", false, "optimize the program before it is compiled")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: monkey disasm [-O] <file | ->")
+		return exitUsage
+	}
+
+	input, err := readSource(flags.Arg(0))
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	bytecode, ok := loadBytecode(sourceName(flags.Arg(0)), input, *optimize)
+	if !ok {
+		return exitError
+	}
+
+	// The source lines are unknown for compiled programs.
+	source := input
+	if mkc.IsCompiled([]byte(input)) {
+		source = ""
+	}
+
+	if err := disasm.Fprint(os.Stdout, bytecode, source); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	return exitOK
+}
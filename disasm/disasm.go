@@ -0,0 +1,137 @@
+// Package disasm prints compiled programs in a human readable form.
+//
+// The output lists the instructions of the program and of every compiled
+// function in the constant pool. Each instruction is prefixed by its offset
+// and the source line it was compiled from:
+//
+//	== main ==
+//	// 1: let x = 5 * 2;
+//	0000    1  OpConstant 0           ; 5
+//	0003    1  OpConstant 1           ; 2
+//	0006    1  OpMul
+//	0007    1  OpSetGlobal 0
+package disasm
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fabiante/monkeylang/code"
+	"github.com/fabiante/monkeylang/compiler"
+	"github.com/fabiante/monkeylang/object"
+	"io"
+	"strings"
+)
+
+// Fprint writes the disassembled bytecode to w. If source is not empty, the
+// source code of a line is printed before the first instruction compiled from
+// it. Functions compiled from imported files are printed without source.
+func Fprint(w io.Writer, bytecode *compiler.Bytecode, source string) error {
+	d := &disassembler{
+		constants: bytecode.Constants,
+	}
+	if source != "" {
+		d.source = strings.Split(source, "\n")
+	}
+
+	d.out.WriteString("== main ==\n")
+	d.instructions(bytecode.Instructions, bytecode.Lines, d.source)
+
+	if len(bytecode.Constants) > 0 {
+		d.out.WriteString("\n== constants ==\n")
+		for i, constant := range bytecode.Constants {
+			_, _ = fmt.Fprintf(&d.out, "%4d  %-18s %s\n", i, constant.Type(), describe(constant))
+		}
+	}
+
+	for i, constant := range bytecode.Constants {
+		fn, ok := constant.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+
+		_, _ = fmt.Fprintf(&d.out, "\n== fn %s (constant %d, %d parameters, %d locals) ==\n",
+			functionName(fn), i, fn.NumParameters, fn.NumLocals)
+		if fn.File == bytecode.File {
+			d.instructions(fn.Instructions, fn.Lines, d.source)
+		} else {
+			d.instructions(fn.Instructions, fn.Lines, nil)
+		}
+	}
+
+	_, err := w.Write(d.out.Bytes())
+	return err
+}
+
+type disassembler struct {
+	out       bytes.Buffer
+	constants []object.Object
+	// source contains the lines of the source code, if it is known.
+	source []string
+}
+
+func (d *disassembler) instructions(ins code.Instructions, lines code.LineTable, source []string) {
+	previousLine := 0
+
+	for i := 0; i < len(ins); {
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			_, _ = fmt.Fprintf(&d.out, "%04d ERROR: %s\n", i, err)
+			i++
+			continue
+		}
+
+		operands, read := code.ReadOperands(def, ins[i+1:])
+
+		line := lines.Line(i)
+		if line != previousLine && line > 0 && line <= len(source) {
+			_, _ = fmt.Fprintf(&d.out, "// %d: %s\n", line, strings.TrimSpace(source[line-1]))
+		}
+		previousLine = line
+
+		instruction := code.FormatInstruction(def, operands)
+		if comment := d.comment(code.Opcode(ins[i]), operands); comment != "" {
+			_, _ = fmt.Fprintf(&d.out, "%04d %4d  %-22s ; %s\n", i, line, instruction, comment)
+		} else {
+			_, _ = fmt.Fprintf(&d.out, "%04d %4d  %s\n", i, line, instruction)
+		}
+
+		i += 1 + read
+	}
+}
+
+// comment explains the operands of instructions which refer to constants or builtins.
+func (d *disassembler) comment(op code.Opcode, operands []int) string {
+	switch op {
+	case code.OpConstant, code.OpClosure, code.OpImport, code.OpMember:
+		if operands[0] < len(d.constants) {
+			return describe(d.constants[operands[0]])
+		}
+		return "invalid constant"
+	case code.OpGetBuiltin:
+		if operands[0] < len(object.Builtins) {
+			return object.Builtins[operands[0]].Name
+		}
+		return "invalid builtin"
+	default:
+		return ""
+	}
+}
+
+// describe returns a short representation of a constant.
+func describe(constant object.Object) string {
+	switch constant := constant.(type) {
+	case *object.String:
+		return fmt.Sprintf("%q", constant.Value)
+	case *object.CompiledFunction:
+		return "fn " + functionName(constant)
+	default:
+		return constant.Inspect()
+	}
+}
+
+func functionName(fn *object.CompiledFunction) string {
+	if fn.Name == "" {
+		return "<anonymous>"
+	}
+	return fn.Name
+}
@@ -0,0 +1,69 @@
+package disasm
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/compiler"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFprint(t *testing.T) {
+	t.Run("functions and constants", func(t *testing.T) {
+		input := `let s = "a";
+let f = fn(x) {
+    len(x) + 1
+};`
+
+		expected := `== main ==
+// 1: let s = "a";
+0000    1  OpConstant 0           ; "a"
+0003    1  OpSetGlobal 0
+// 2: let f = fn(x) {
+0006    2  OpClosure 2 0          ; fn f
+0010    2  OpSetGlobal 1
+
+== constants ==
+   0  STRING             "a"
+   1  INTEGER            1
+   2  COMPILED_FUNCTION  fn f
+
+== fn f (constant 2, 1 parameters, 1 locals) ==
+// 3: len(x) + 1
+0000    3  OpGetBuiltin 0         ; len
+0002    3  OpGetLocal 0
+0004    3  OpCall 1
+0006    3  OpConstant 1           ; 1
+0009    3  OpAdd
+0010    3  OpReturnValue
+`
+
+		assert.Equal(t, expected, disassemble(t, input, input))
+	})
+
+	t.Run("without source", func(t *testing.T) {
+		expected := `== main ==
+0000    1  OpTrue
+0001    1  OpPop
+0002    2  OpFalse
+0003    2  OpPop
+`
+
+		assert.Equal(t, expected, disassemble(t, "true;\nfalse;", ""))
+	})
+}
+
+func disassemble(t *testing.T, input string, source string) string {
+	par := parser.NewParser(lexer.NewLexer(input))
+	program := par.ParseProgram()
+	require.Empty(t, par.Errors())
+
+	c := compiler.NewCompiler()
+	require.NoError(t, c.Compile(program))
+
+	var out bytes.Buffer
+	require.NoError(t, Fprint(&out, c.Bytecode(), source))
+	return out.String()
+}
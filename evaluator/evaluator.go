@@ -0,0 +1,581 @@
+// Package evaluator executes an *ast.Program by walking its tree.
+//
+// Identifiers are looked up by name, unless the program has been resolved by
+// package resolver. Values are then stored in and looked up from the slots
+// given by the bindings of the identifiers.
+//
+// The evaluator is the reference implementation of the semantics of Monkey.
+// The virtual machine must produce the same results for all programs.
+package evaluator
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/object"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// Values without identity are shared by all programs.
+var (
+	True  = &object.Boolean{Value: true}
+	False = &object.Boolean{Value: false}
+	Null  = &object.Null{}
+)
+
+// Eval evaluates node in env. Errors which are thrown and not caught are
+// returned as *object.Error.
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+	if t, ok := result.(*thrown); ok {
+		return t.err
+	}
+	return result
+}
+
+// eval evaluates node in env. Errors which have been thrown by node itself
+// rather than by one of its children are located at node.
+func eval(node ast.Node, env *object.Environment) object.Object {
+	result := evalNode(node, env)
+	if t, ok := result.(*thrown); ok && t.err.Stack == nil {
+		locate(t.err, errorPos(node), env.File(), env.Frame())
+	}
+	return result
+}
+
+func evalNode(node ast.Node, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *ast.Program:
+		return evalProgram(node, env)
+	case *ast.ExpressionStatement:
+		return eval(node.Expression, env)
+	case *ast.BlockStatement:
+		return evalBlockStatement(node, env)
+	case *ast.LetStatement:
+		val := eval(node.Value, env)
+		if isAbrupt(val) {
+			return val
+		}
+		bind(env, node.Name, val)
+		return nil
+	case *ast.ReturnStatement:
+		val := evalTail(node.ReturnValue, env)
+		if isAbrupt(val) {
+			return val
+		}
+		return &object.ReturnValue{Value: val}
+	case *ast.ThrowStatement:
+		return evalThrowStatement(node, env)
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+	case *ast.Identifier:
+		return evalIdentifier(node, env)
+	case *ast.IntegerLiteral:
+		return &object.Integer{Value: node.Value}
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
+	case *ast.BooleanLiteral:
+		return nativeBoolToBooleanObject(node.Value)
+	case *ast.PrefixExpression:
+		right := eval(node.Right, env)
+		if isAbrupt(right) {
+			return right
+		}
+		return evalPrefixExpression(node.Operator, right)
+	case *ast.InfixExpression:
+		left := eval(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		right := eval(node.Right, env)
+		if isAbrupt(right) {
+			return right
+		}
+		return evalInfixExpression(node.Operator, left, right)
+	case *ast.IfExpression:
+		return evalIfExpression(node, env)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	case *ast.FunctionLiteral:
+		return &object.Function{Parameters: node.Parameters, Body: node.Body, Env: env, Name: node.Name}
+	case *ast.CallExpression:
+		function, args, abrupt := evalCall(node, env)
+		if abrupt != nil {
+			return abrupt
+		}
+		return applyFunction(function, args, env, node.Token.Pos)
+	case *ast.ArrayLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isAbrupt(elements[0]) {
+			return elements[0]
+		}
+		return &object.Array{Elements: elements}
+	case *ast.HashLiteral:
+		return evalHashLiteral(node, env)
+	case *ast.IndexExpression:
+		left := eval(node.Left, env)
+		if isAbrupt(left) {
+			return left
+		}
+		index := eval(node.Index, env)
+		if isAbrupt(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
+	case *ast.MemberExpression:
+		obj := eval(node.Object, env)
+		if isAbrupt(obj) {
+			return obj
+		}
+		return evalMemberExpression(obj, node.Property.Value)
+	case nil:
+		return newError(object.InternalError, "missing node")
+	default:
+		return newError(object.InternalError, "unsupported node type %T", node)
+	}
+}
+
+func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	var result object.Object
+
+	for _, stmt := range program.Statements {
+		result = eval(stmt, env)
+
+		switch result := result.(type) {
+		case *object.ReturnValue:
+			if call, ok := result.Value.(*tailCall); ok {
+				return applyFunction(call.fn, call.args, env, call.pos)
+			}
+			return result.Value
+		case *thrown:
+			return result
+		}
+	}
+
+	return result
+}
+
+// evalBlockStatement evaluates the statements of a block. Return values are
+// passed on unwrapped so that they stop the evaluation of enclosing blocks
+// as well. Blocks which do not end in an expression statement produce null.
+func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+	var result object.Object
+
+	for _, stmt := range block.Statements {
+		result = eval(stmt, env)
+		if isAbrupt(result) {
+			return result
+		}
+	}
+
+	if result == nil {
+		return Null
+	}
+	return result
+}
+
+func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	if b := node.Binding; b != nil {
+		if b.Builtin {
+			return object.Builtins[b.Slot].Builtin
+		}
+		// The binding is known, but its let statement may not have been
+		// executed, for example if it is in a branch which was not taken.
+		if val, ok := env.GetAt(b.Depth, b.Slot); ok {
+			return val
+		}
+		return newError(object.ReferenceError, "undefined variable %s", node.Value)
+	}
+
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+
+	if builtin := object.GetBuiltinByName(node.Value); builtin != nil {
+		return builtin
+	}
+
+	return newError(object.ReferenceError, "undefined variable %s", node.Value)
+}
+
+func evalPrefixExpression(operator string, right object.Object) object.Object {
+	switch operator {
+	case "!":
+		return nativeBoolToBooleanObject(!isTruthy(right))
+	case "-":
+		if right.Type() != object.IntegerObj {
+			return newError(object.TypeError, "unknown operator: -%s", right.Type())
+		}
+		value := right.(*object.Integer).Value
+		return &object.Integer{Value: -value}
+	default:
+		return newError(object.TypeError, "unknown operator: %s%s", operator, right.Type())
+	}
+}
+
+func evalInfixExpression(operator string, left, right object.Object) object.Object {
+	switch {
+	case left.Type() == object.IntegerObj && right.Type() == object.IntegerObj:
+		return evalIntegerInfixExpression(operator, left, right)
+	case left.Type() == object.StringObj && right.Type() == object.StringObj:
+		return evalStringInfixExpression(operator, left, right)
+	// All other values are compared by identity. Booleans and null are
+	// singletons, so this compares them by value.
+	case operator == "==":
+		return nativeBoolToBooleanObject(left == right)
+	case operator == "!=":
+		return nativeBoolToBooleanObject(left != right)
+	case left.Type() != right.Type():
+		return newError(object.TypeError, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	default:
+		return newError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.Integer).Value
+	rightVal := right.(*object.Integer).Value
+
+	switch operator {
+	case "+":
+		return &object.Integer{Value: leftVal + rightVal}
+	case "-":
+		return &object.Integer{Value: leftVal - rightVal}
+	case "*":
+		return &object.Integer{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError(object.ArithmeticError, "division by zero")
+		}
+		return &object.Integer{Value: leftVal / rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := eval(ie.Condition, env)
+	if isAbrupt(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return eval(ie.Consequence, env)
+	} else if ie.Alternative != nil {
+		return eval(ie.Alternative, env)
+	} else {
+		return Null
+	}
+}
+
+// evalThrowStatement throws the value of a throw statement. Errors which have
+// been thrown before, like those caught by a catch clause, are thrown again
+// unchanged. All other values are thrown as error whose message is their
+// representation.
+func evalThrowStatement(node *ast.ThrowStatement, env *object.Environment) object.Object {
+	val := eval(node.Value, env)
+	if isAbrupt(val) {
+		return val
+	}
+
+	if err, ok := val.(*object.Error); ok && err.Stack != nil {
+		return &thrown{err: err}
+	}
+	return &thrown{err: &object.Error{Kind: object.ThrownError, Message: val.Inspect()}}
+}
+
+// evalImportStatement binds the name of an import statement to the module
+// imported by it. A module is evaluated when it is imported for the first
+// time, with a frame named "<module>" for its top level.
+func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	if node.Program == nil {
+		return newError(object.InternalError, "module %q has not been loaded", node.Path.Value)
+	}
+
+	module, ok := env.Module(node.File)
+	if !ok {
+		frame, ok := object.NewFrame(moduleFunction, node.Token.Pos, env.File(), env.Frame())
+		if !ok {
+			return throw(object.NewError(object.InternalError, "stack overflow"), node.Token.Pos, env.File(), env.Frame())
+		}
+		moduleEnv := object.NewModuleEnvironment(env, node.File, frame)
+		if result := eval(node.Program, moduleEnv); isAbrupt(result) {
+			return result
+		}
+
+		module = &object.Module{File: node.File, Exports: make(map[string]object.Object)}
+		for _, stmt := range node.Program.Statements {
+			if let, ok := stmt.(*ast.LetStatement); ok && let.Exported() {
+				module.Exports[let.Name.Value] = eval(let.Name, moduleEnv)
+			}
+		}
+		env.SetModule(module)
+	}
+
+	bind(env, node.Name, module)
+	return nil
+}
+
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	module, ok := obj.(*object.Module)
+	if !ok {
+		return newError(object.TypeError, "member access not supported: %s", obj.Type())
+	}
+
+	value, ok := module.Exports[name]
+	if !ok {
+		return newError(object.ReferenceError, "undefined export %s of module %s", name, module.File)
+	}
+	return value
+}
+
+// evalTryExpression evaluates the block of a try expression and the catch
+// clause if the block throws an error. The finally clause is evaluated
+// afterwards in any case. If it is abrupt itself, its result replaces that
+// of the block or the catch clause.
+func evalTryExpression(node *ast.TryExpression, env *object.Environment) object.Object {
+	result := evalTryBlock(node.Block, env)
+
+	if t, ok := result.(*thrown); ok && node.Catch != nil {
+		bind(env, node.Parameter, t.err)
+		result = evalTryBlock(node.Catch, env)
+	}
+
+	if node.Finally != nil {
+		if final := eval(node.Finally, env); isAbrupt(final) {
+			return final
+		}
+	}
+
+	return result
+}
+
+// evalTryBlock evaluates the block or the catch clause of a try expression.
+// Tail calls of return statements are executed right away, so that the try
+// expression sees the errors they throw.
+func evalTryBlock(block *ast.BlockStatement, env *object.Environment) object.Object {
+	result := eval(block, env)
+
+	returnValue, ok := result.(*object.ReturnValue)
+	if !ok {
+		return result
+	}
+	call, ok := returnValue.Value.(*tailCall)
+	if !ok {
+		return result
+	}
+
+	val := applyFunction(call.fn, call.args, env, call.pos)
+	if isAbrupt(val) {
+		return val
+	}
+	return &object.ReturnValue{Value: val}
+}
+
+// evalCall evaluates the function and the arguments of a call. If one of
+// them is abrupt, it is returned as abrupt result.
+func evalCall(node *ast.CallExpression, env *object.Environment) (function object.Object, args []object.Object, abrupt object.Object) {
+	function = eval(node.Function, env)
+	if isAbrupt(function) {
+		return nil, nil, function
+	}
+
+	args = evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isAbrupt(args[0]) {
+		return nil, nil, args[0]
+	}
+
+	return function, args, nil
+}
+
+// evalExpressions evaluates the expressions from left to right. If one of them
+// is abrupt, it is returned as the only element.
+func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+	result := make([]object.Object, 0, len(exps))
+
+	for _, e := range exps {
+		evaluated := eval(e, env)
+		if isAbrupt(evaluated) {
+			return []object.Object{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+
+	return result
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	pairs := make(map[object.HashKey]object.HashPair, len(node.Pairs))
+
+	for _, pair := range node.Pairs {
+		key := eval(pair.Key, env)
+		if isAbrupt(key) {
+			return key
+		}
+
+		value := eval(pair.Value, env)
+		if isAbrupt(value) {
+			return value
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError(object.TypeError, "unusable as hash key: %s", key.Type())
+		}
+
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return &object.Hash{Pairs: pairs}
+}
+
+func evalIndexExpression(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ArrayObj && index.Type() == object.IntegerObj:
+		elements := left.(*object.Array).Elements
+		i := index.(*object.Integer).Value
+		if i < 0 || i >= int64(len(elements)) {
+			return Null
+		}
+		return elements[i]
+	case left.Type() == object.ErrorObj && index.Type() == object.StringObj:
+		if val := left.(*object.Error).Property(index.(*object.String).Value); val != nil {
+			return val
+		}
+		return Null
+	case left.Type() == object.HashObj:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError(object.TypeError, "unusable as hash key: %s", index.Type())
+		}
+		pair, ok := left.(*object.Hash).Pairs[key.HashKey()]
+		if !ok {
+			return Null
+		}
+		return pair.Value
+	default:
+		return newError(object.TypeError, "index operator not supported: %s", left.Type())
+	}
+}
+
+// applyFunction calls fn at position pos of the code evaluated in env. Tail
+// calls of the function body are executed in a loop instead of recursively,
+// so tail recursion does not grow the Go stack.
+//
+// Like in the virtual machine, a function called by a tail call replaces the
+// frame of the function making the call.
+func applyFunction(fn object.Object, args []object.Object, env *object.Environment, pos token.Position) object.Object {
+	caller, call, callFile := env.Frame(), pos, env.File()
+	// current is the frame of the function executing the call and file the
+	// source file of that function. Errors of the call are thrown there.
+	current, file := caller, callFile
+	// tailCalls is the number of frames which have been replaced by tail calls.
+	tailCalls := 0
+
+	for {
+		switch function := fn.(type) {
+		case *object.Function:
+			if len(args) != len(function.Parameters) {
+				return throw(object.NewError(object.TypeError, "wrong number of arguments: want=%d, got=%d", len(function.Parameters), len(args)), pos, file, current)
+			}
+
+			frame, ok := object.NewFrame(object.FunctionName(function.Name), call, callFile, caller)
+			if !ok {
+				return throw(object.NewError(object.InternalError, "stack overflow"), pos, file, current)
+			}
+			frame.TailCalls = tailCalls
+			env := object.NewCallEnvironment(function.Env, frame)
+			for i, param := range function.Parameters {
+				bind(env, param, args[i])
+			}
+
+			evaluated := evalTailBlock(function.Body, env)
+			if returnValue, ok := evaluated.(*object.ReturnValue); ok {
+				evaluated = returnValue.Value
+			}
+			if tc, ok := evaluated.(*tailCall); ok {
+				fn, args = tc.fn, tc.args
+				current, file, pos = frame, env.File(), tc.pos
+				tailCalls++
+				continue
+			}
+			return evaluated
+		case *object.Builtin:
+			result := function.Fn(args...)
+			// Errors which have been thrown before are ordinary values,
+			// for example if a caught error is passed to first.
+			if err, ok := result.(*object.Error); ok && err.Stack == nil {
+				return throw(err, pos, file, current)
+			}
+			if result != nil {
+				return result
+			}
+			return Null
+		default:
+			return throw(object.NewError(object.TypeError, "not a function: %s", fn.Type()), pos, file, current)
+		}
+	}
+}
+
+// bind sets the value of a let statement or parameter. Identifiers which have
+// been resolved are bound by slot, all others by name.
+func bind(env *object.Environment, ident *ast.Identifier, val object.Object) {
+	if ident.Binding != nil {
+		env.SetAt(ident.Binding.Slot, val)
+	} else {
+		env.Set(ident.Value, val)
+	}
+}
+
+func nativeBoolToBooleanObject(value bool) *object.Boolean {
+	if value {
+		return True
+	}
+	return False
+}
+
+// isTruthy reports whether obj counts as true in conditions. Only false and
+// null are not truthy.
+func isTruthy(obj object.Object) bool {
+	switch obj {
+	case Null, False:
+		return false
+	default:
+		return true
+	}
+}
+
+// isAbrupt reports whether obj is a thrown error or the value of a return
+// statement. Both stop the evaluation of enclosing expressions and statements
+// until they reach a try expression, the enclosing function or the top level
+// of the program.
+func isAbrupt(obj object.Object) bool {
+	switch obj.(type) {
+	case *thrown, *object.ReturnValue:
+		return true
+	default:
+		return false
+	}
+}
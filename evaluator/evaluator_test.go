@@ -0,0 +1,175 @@
+package evaluator
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/internal/corpus"
+	"github.com/fabiante/monkeylang/internal/testutil"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/object"
+	"github.com/fabiante/monkeylang/parser"
+	"github.com/fabiante/monkeylang/resolver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	t.Run("corpus", func(t *testing.T) {
+		for _, program := range corpus.Programs {
+			t.Run(program.Name, func(t *testing.T) {
+				result := testEval(t, program.Input)
+				require.NotNil(t, result)
+
+				if program.Error != "" {
+					require.IsType(t, &object.Error{}, result)
+					assert.Equal(t, program.Error, result.(*object.Error).Message)
+				} else {
+					assert.Equal(t, program.Expected, result.Inspect())
+				}
+			})
+		}
+	})
+
+	t.Run("corpus with resolved identifiers", func(t *testing.T) {
+		for _, program := range corpus.Programs {
+			t.Run(program.Name, func(t *testing.T) {
+				result := Eval(resolve(t, program.Input), object.NewEnvironment())
+				require.NotNil(t, result)
+
+				if program.Error != "" {
+					require.IsType(t, &object.Error{}, result)
+					assert.Equal(t, program.Error, result.(*object.Error).Message)
+				} else {
+					assert.Equal(t, program.Expected, result.Inspect())
+				}
+			})
+		}
+	})
+
+	t.Run("resolved binding in branch which was not taken", func(t *testing.T) {
+		result := Eval(resolve(t, "if (false) { let a = 1; } a;"), object.NewEnvironment())
+
+		require.IsType(t, &object.Error{}, result)
+		assert.Equal(t, "undefined variable a", result.(*object.Error).Message)
+	})
+
+	t.Run("stack frames name the file", func(t *testing.T) {
+		program := resolve(t, "let add = fn(a, b) { a + b };\nadd(true, false);")
+		result := Eval(program, object.NewFileEnvironment("math.mk"))
+
+		require.IsType(t, &object.Error{}, result)
+		assert.Equal(t, "TypeError: unknown operator: BOOLEAN + BOOLEAN\n    at add (math.mk:1:24)\n    at math.mk:2:4", result.(*object.Error).Trace())
+	})
+
+	t.Run("stack frames replaced by tail calls", func(t *testing.T) {
+		program := resolve(t, "let add = fn(a, b) { a + b };\nlet g = fn() { add(true, 1) };\ng();")
+		result := Eval(program, object.NewFileEnvironment("math.mk"))
+
+		require.IsType(t, &object.Error{}, result)
+		assert.Equal(t, "TypeError: type mismatch: BOOLEAN + INTEGER\n    at add (math.mk:1:24)\n    ... 1 frame replaced by a tail call\n    at math.mk:3:2", result.(*object.Error).Trace())
+	})
+
+	t.Run("repeated stack frames", func(t *testing.T) {
+		program := resolve(t, "let f = fn(n) { f(n + 1) + 1 };\nf(0);")
+		result := Eval(program, object.NewFileEnvironment("so.mk"))
+
+		require.IsType(t, &object.Error{}, result)
+		assert.Equal(t, "InternalError: stack overflow\n    at f (so.mk:1:18)\n    ... repeated 1022 more times\n    at so.mk:2:2", result.(*object.Error).Trace())
+	})
+
+	t.Run("modules", func(t *testing.T) {
+		program := resolve(t, `import "math.mk" as math; import "math.mk" as again; [math.add(1, 2), again.twice(4)]`)
+		link(t, program, map[string]string{
+			"math.mk": "export let add = fn(a, b) { a + b };\nlet one = 1;\nexport let twice = fn(x) { add(x, x) * one };",
+		})
+
+		assert.Equal(t, "[3, 8]", Eval(program, object.NewEnvironment()).Inspect())
+	})
+
+	t.Run("unexported binding", func(t *testing.T) {
+		program := resolve(t, `import "math.mk" as math; math.one`)
+		link(t, program, map[string]string{"math.mk": "let one = 1;"})
+		result := Eval(program, object.NewEnvironment())
+
+		require.IsType(t, &object.Error{}, result)
+		assert.Equal(t, "ReferenceError: undefined export one of module math.mk\n    at 1:31", result.(*object.Error).Trace())
+	})
+
+	t.Run("stack frames name the module", func(t *testing.T) {
+		program := resolve(t, `import "lib.mk" as lib;`)
+		link(t, program, map[string]string{"lib.mk": `import "err.mk" as err;`, "err.mk": "let x = 0;\n1 / x;"})
+		result := Eval(program, object.NewFileEnvironment("main.mk"))
+
+		require.IsType(t, &object.Error{}, result)
+		assert.Equal(t, "ArithmeticError: division by zero\n    at <module> (err.mk:2:3)\n    at <module> (lib.mk:1:1)\n    at main.mk:1:1", result.(*object.Error).Trace())
+	})
+
+	t.Run("undefined variable", func(t *testing.T) {
+		result := testEval(t, "foobar")
+
+		require.IsType(t, &object.Error{}, result)
+		assert.Equal(t, "undefined variable foobar", result.(*object.Error).Message)
+	})
+
+	t.Run("function object", func(t *testing.T) {
+		result := testEval(t, "fn(x) { x + 2; };")
+
+		require.IsType(t, &object.Function{}, result)
+		fn := result.(*object.Function)
+		require.Len(t, fn.Parameters, 1)
+		assert.Equal(t, "x", fn.Parameters[0].String())
+		assert.Equal(t, "(x + 2)", fn.Body.String())
+	})
+
+	t.Run("let produces no value", func(t *testing.T) {
+		assert.Nil(t, testEval(t, "let a = 1;"))
+	})
+}
+
+func BenchmarkFibonacci(b *testing.B) {
+	b.Run("names", func(b *testing.B) {
+		par := parser.NewParser(lexer.NewLexer(corpus.Fibonacci))
+		program := par.ParseProgram()
+		require.Empty(b, par.Errors())
+
+		benchmarkEval(b, program)
+	})
+
+	b.Run("slots", func(b *testing.B) {
+		benchmarkEval(b, resolve(b, corpus.Fibonacci))
+	})
+}
+
+func benchmarkEval(b *testing.B, program *ast.Program) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result := Eval(program, object.NewEnvironment())
+		require.Equal(b, "75025", result.Inspect())
+	}
+}
+
+// resolve parses input and resolves its identifiers.
+func resolve(t testing.TB, input string) *ast.Program {
+	program := testutil.MustParse(t, input)
+
+	errs, _ := resolver.Resolve(program)
+	require.Empty(t, errs)
+	return program
+}
+
+// link sets the imported programs of program, and of the programs imported by
+// it, to the resolved sources with the imported file names.
+func link(t testing.TB, program *ast.Program, sources map[string]string) {
+	ast.Inspect(program, func(node ast.Node) bool {
+		if stmt, ok := node.(*ast.ImportStatement); ok {
+			stmt.File = stmt.Path.Value
+			stmt.Program = resolve(t, sources[stmt.Path.Value])
+			link(t, stmt.Program, sources)
+		}
+		return true
+	})
+}
+
+func testEval(t *testing.T, input string) object.Object {
+	return Eval(testutil.MustParse(t, input), object.NewEnvironment())
+}
@@ -0,0 +1,81 @@
+package evaluator
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/object"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// tailCall is a call in tail position which has not been executed yet. It is
+// returned from the body of a function instead of the result of the call and
+// executed by applyFunction, which called the function.
+//
+// A tailCall is never visible to programs.
+type tailCall struct {
+	fn   object.Object
+	args []object.Object
+	// pos is the position of the call.
+	pos token.Position
+}
+
+func (t *tailCall) Type() object.ObjectType {
+	return "TAIL_CALL"
+}
+
+func (t *tailCall) Inspect() string {
+	return "tail call"
+}
+
+// evalTail evaluates an expression whose value is returned from a function.
+// Calls of functions are not executed but returned as tailCall. This applies
+// to the branches of if expressions as well.
+func evalTail(exp ast.Expression, env *object.Environment) object.Object {
+	switch exp := exp.(type) {
+	case *ast.CallExpression:
+		function, args, abrupt := evalCall(exp, env)
+		if abrupt != nil {
+			return abrupt
+		}
+		if _, ok := function.(*object.Function); !ok {
+			return applyFunction(function, args, env, exp.Token.Pos)
+		}
+		return &tailCall{fn: function, args: args, pos: exp.Token.Pos}
+	case *ast.IfExpression:
+		condition := eval(exp.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+
+		if isTruthy(condition) {
+			return evalTailBlock(exp.Consequence, env)
+		} else if exp.Alternative != nil {
+			return evalTailBlock(exp.Alternative, env)
+		} else {
+			return Null
+		}
+	default:
+		return eval(exp, env)
+	}
+}
+
+// evalTailBlock evaluates a block whose value is returned from a function.
+// Its last statement is evaluated with evalTail.
+func evalTailBlock(block *ast.BlockStatement, env *object.Environment) object.Object {
+	n := len(block.Statements)
+	if n == 0 {
+		return Null
+	}
+
+	last, ok := block.Statements[n-1].(*ast.ExpressionStatement)
+	if !ok {
+		return evalBlockStatement(block, env)
+	}
+
+	for _, stmt := range block.Statements[:n-1] {
+		if result := eval(stmt, env); isAbrupt(result) {
+			return result
+		}
+	}
+
+	return evalTail(last.Expression, env)
+}
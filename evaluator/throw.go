@@ -0,0 +1,80 @@
+package evaluator
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/object"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// thrown is an error which has been thrown and not been caught yet. Like a
+// return value, it stops the evaluation of enclosing expressions and
+// statements until it reaches a try expression or the top level, where it is
+// returned as *object.Error.
+//
+// A thrown is never visible to programs.
+type thrown struct {
+	err *object.Error
+}
+
+func (t *thrown) Type() object.ObjectType {
+	return "THROWN"
+}
+
+func (t *thrown) Inspect() string {
+	return t.err.Inspect()
+}
+
+// newError throws an error of the given kind with a formatted message. It is
+// located by eval at the node which failed.
+func newError(kind object.ErrorKind, format string, a ...any) object.Object {
+	return &thrown{err: object.NewError(kind, format, a...)}
+}
+
+// throw locates err at position pos of the given source file, which is
+// executed by the function described by frame, and throws it.
+func throw(err *object.Error, pos token.Position, file string, frame *object.Frame) object.Object {
+	locate(err, pos, file, frame)
+	return &thrown{err: err}
+}
+
+// locate sets the position and the stack of err. The stack starts with the
+// function described by frame, which is nil at the top level.
+func locate(err *object.Error, pos token.Position, file string, frame *object.Frame) {
+	err.Pos = pos
+	err.Stack = []object.StackFrame{stackFrame(frame, file, pos)}
+	for ; frame != nil; frame = frame.Caller {
+		err.Stack = append(err.Stack, stackFrame(frame.Caller, frame.CallFile, frame.Call))
+	}
+}
+
+// stackFrame returns the stack frame of the function described by frame, which
+// is nil at the top level, executing pos in file.
+func stackFrame(frame *object.Frame, file string, pos token.Position) object.StackFrame {
+	if frame == nil {
+		return object.StackFrame{File: file, Pos: pos}
+	}
+	return object.StackFrame{Function: frame.Function, File: file, Pos: pos, TailCalls: frame.TailCalls}
+}
+
+// moduleFunction is the function name of the frames of module top levels.
+const moduleFunction = "<module>"
+
+// errorPos returns the position at which node throws errors. Errors of infix
+// operators, calls, index and member expressions are located at their
+// operator, all others at the start of the node.
+func errorPos(node ast.Node) token.Position {
+	switch node := node.(type) {
+	case nil:
+		return token.Position{}
+	case *ast.InfixExpression:
+		return node.Token.Pos
+	case *ast.CallExpression:
+		return node.Token.Pos
+	case *ast.IndexExpression:
+		return node.Token.Pos
+	case *ast.MemberExpression:
+		return node.Token.Pos
+	default:
+		return node.Pos()
+	}
+}
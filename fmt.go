@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/parser"
+	"github.com/fabiante/monkeylang/printer"
+	"os"
+)
+
+// fmtCmd formats the given files. Without files, stdin is formatted.
+//
+// The formatted source is written to stdout unless -w is given, in which case
+// the files are overwritten.
+func fmtCmd(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write result to the source file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		if *write {
+			_, _ = fmt.Fprintln(os.Stderr, "monkey fmt: cannot use -w with stdin")
+			return exitUsage
+		}
+		files = []string{"-"}
+	}
+
+	code := exitOK
+	for _, file := range files {
+		if !formatFile(file, *write) {
+			code = exitError
+		}
+	}
+
+	return code
+}
+
+// formatFile formats a single file and reports whether this was successful.
+func formatFile(name string, write bool) bool {
+	input, err := readSource(name)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+
+	par := parser.NewParser(lexer.NewLexer(input))
+	program := par.ParseProgram()
+	if errs := par.ErrorList(); len(errs) > 0 {
+		printParseErrors(sourceName(name), errs)
+		return false
+	}
+
+	var out bytes.Buffer
+	if err := printer.Fprint(&out, program); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %s\n", sourceName(name), err)
+		return false
+	}
+
+	if !write {
+		_, _ = os.Stdout.Write(out.Bytes())
+		return true
+	}
+
+	if out.String() == input {
+		return true
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+
+	if err := os.WriteFile(name, out.Bytes(), info.Mode().Perm()); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+
+	return true
+}
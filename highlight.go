@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fabiante/monkeylang/highlight"
+	"os"
+)
+
+// highlightCmd prints the given file with syntax highlighting, either colored
+// for the terminal or as HTML for embedding in web pages.
+func highlightCmd(args []string) int {
+	flags := flag.NewFlagSet("highlight", flag.ContinueOnError)
+	format := flags.String("format", "ansi", "print the source in `format` ansi or html")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 || *format != "ansi" && *format != "html" {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: monkey highlight [--format=ansi|html] <file | ->")
+		return exitUsage
+	}
+
+	input, err := readSource(flags.Arg(0))
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	write := highlight.WriteANSI
+	if *format == "html" {
+		write = highlight.WriteHTML
+	}
+	if err := write(os.Stdout, input); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
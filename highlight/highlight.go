@@ -0,0 +1,105 @@
+// Package highlight classifies the tokens of Monkey source code for syntax
+// highlighting.
+//
+// The classified spans can be rendered as text colored with ANSI escape
+// sequences, as HTML with a CSS class per span or as LSP semantic tokens.
+package highlight
+
+import (
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// Class is the highlighting class of a token.
+type Class int
+
+const (
+	Keyword Class = iota
+	Identifier
+	Number
+	String
+	Operator
+	Punctuation
+	Comment
+	// Illegal is the class of characters which do not start a token.
+	Illegal
+)
+
+var classNames = [...]string{
+	Keyword:     "keyword",
+	Identifier:  "identifier",
+	Number:      "number",
+	String:      "string",
+	Operator:    "operator",
+	Punctuation: "punctuation",
+	Comment:     "comment",
+	Illegal:     "illegal",
+}
+
+// String returns the lower case name of the class, which is also used as
+// CSS class by WriteHTML.
+func (c Class) String() string {
+	return classNames[c]
+}
+
+// Span is a classified token of the input.
+type Span struct {
+	Class Class
+	// Start and End are the byte offsets of the first character of the token
+	// and of the character following it. The span of a string literal
+	// includes its quotes.
+	Start int
+	End   int
+}
+
+// Spans classifies the tokens of input. The spans are ordered by offset and
+// do not overlap. The text between them is whitespace.
+func Spans(input string) []Span {
+	var spans []Span
+
+	for t := range lexer.NewLexer(input).All() {
+		span := Span{Class: classify(t.Type), Start: t.Pos.Offset, End: t.Pos.Offset + len(t.Literal)}
+		if t.Type == token.String {
+			// The literal excludes the quotes. The closing quote is missing if
+			// the string is not terminated.
+			span.End += 1
+			if span.End < len(input) && input[span.End] == '"' {
+				span.End++
+			}
+		}
+
+		// The lexer returns an illegal token per character. Consecutive
+		// illegal characters are merged into a single span.
+		if n := len(spans); n > 0 && span.Class == Illegal && spans[n-1].Class == Illegal && spans[n-1].End == span.Start {
+			spans[n-1].End = span.End
+			continue
+		}
+
+		spans = append(spans, span)
+	}
+
+	return spans
+}
+
+func classify(t token.TokenType) Class {
+	switch t {
+	case token.Func, token.Let, token.True, token.False, token.If, token.Else, token.Return,
+		token.Throw, token.Try, token.Catch, token.Finally, token.Import, token.Export, token.As:
+		return Keyword
+	case token.Identifier:
+		return Identifier
+	case token.Int:
+		return Number
+	case token.String:
+		return String
+	case token.Assign, token.Plus, token.Minus, token.Bang, token.Asterisk, token.Slash,
+		token.LT, token.GT, token.EQ, token.NEQ, token.Arrow:
+		return Operator
+	case token.Comment:
+		return Comment
+	case token.Illegal:
+		return Illegal
+	default:
+		return Punctuation
+	}
+}
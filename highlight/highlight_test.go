@@ -0,0 +1,102 @@
+package highlight
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSpans(t *testing.T) {
+	input := "let s = \"a b\"; // c\nif (x != 10) { fn(y) -> int { y } } @é \"open"
+
+	expected := []struct {
+		class Class
+		text  string
+	}{
+		{Keyword, "let"},
+		{Identifier, "s"},
+		{Operator, "="},
+		{String, `"a b"`},
+		{Punctuation, ";"},
+		{Comment, "// c"},
+		{Keyword, "if"},
+		{Punctuation, "("},
+		{Identifier, "x"},
+		{Operator, "!="},
+		{Number, "10"},
+		{Punctuation, ")"},
+		{Punctuation, "{"},
+		{Keyword, "fn"},
+		{Punctuation, "("},
+		{Identifier, "y"},
+		{Punctuation, ")"},
+		{Operator, "->"},
+		{Identifier, "int"},
+		{Punctuation, "{"},
+		{Identifier, "y"},
+		{Punctuation, "}"},
+		{Punctuation, "}"},
+		{Illegal, "@é"},
+		{String, `"open`},
+	}
+
+	spans := Spans(input)
+	require.Len(t, spans, len(expected))
+	for i, span := range spans {
+		assert.Equal(t, expected[i].class, span.Class, "class of span %d", i)
+		assert.Equal(t, expected[i].text, input[span.Start:span.End], "text of span %d", i)
+	}
+}
+
+func TestWriteANSI(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, WriteANSI(&out, "let x = \"a\"; // b\n"))
+
+	assert.Equal(t, "\x1b[35mlet\x1b[0m x = \x1b[32m\"a\"\x1b[0m; \x1b[90m// b\x1b[0m\n", out.String())
+}
+
+func TestWriteHTML(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, WriteHTML(&out, "a < \"<b>\";\n"))
+
+	assert.Equal(t, `<span class="identifier">a</span> <span class="operator">&lt;</span> `+
+		`<span class="string">&#34;&lt;b&gt;&#34;</span><span class="punctuation">;</span>`+"\n", out.String())
+}
+
+func TestSemanticTokens(t *testing.T) {
+	t.Run("relative positions", func(t *testing.T) {
+		input := "let a = 1;\n  a + 2; // c"
+
+		assert.Equal(t, []uint32{
+			0, 0, 3, 0, 0, // let
+			0, 4, 1, 1, 0, // a
+			0, 2, 1, 4, 0, // =
+			0, 2, 1, 2, 0, // 1
+			1, 2, 1, 1, 0, // a
+			0, 2, 1, 4, 0, // +
+			0, 2, 1, 2, 0, // 2
+			0, 3, 4, 5, 0, // // c
+		}, SemanticTokens(input))
+	})
+
+	t.Run("multi-line strings", func(t *testing.T) {
+		input := "\"ab\ncd\n\"; x"
+
+		assert.Equal(t, []uint32{
+			0, 0, 3, 3, 0, // "ab
+			1, 0, 2, 3, 0, // cd
+			1, 0, 1, 3, 0, // "
+			0, 3, 1, 1, 0, // x
+		}, SemanticTokens(input))
+	})
+
+	t.Run("UTF-16 characters", func(t *testing.T) {
+		input := "\"é😀\" x"
+
+		assert.Equal(t, []uint32{
+			0, 0, 5, 3, 0,
+			0, 6, 1, 1, 0,
+		}, SemanticTokens(input))
+	})
+}
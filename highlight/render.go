@@ -0,0 +1,58 @@
+package highlight
+
+import (
+	"bufio"
+	"html"
+	"io"
+)
+
+// ansiColors are the SGR parameters of the classes in terminal output.
+// Classes without color are written as is.
+var ansiColors = map[Class]string{
+	Keyword: "35",
+	Number:  "36",
+	String:  "32",
+	Comment: "90",
+	Illegal: "31;4",
+}
+
+// WriteANSI writes input to w with the tokens colored by ANSI escape
+// sequences.
+func WriteANSI(w io.Writer, input string) error {
+	return render(w, input, func(out *bufio.Writer, class Class, text string) {
+		color, ok := ansiColors[class]
+		if !ok {
+			_, _ = out.WriteString(text)
+			return
+		}
+		_, _ = out.WriteString("\x1b[" + color + "m" + text + "\x1b[0m")
+	})
+}
+
+// WriteHTML writes input to w as HTML. Each token is wrapped in a span element
+// whose CSS class is the name of its class, like
+//
+//	<span class="keyword">let</span>
+//
+// The output is meant to be embedded in a pre element.
+func WriteHTML(w io.Writer, input string) error {
+	return render(w, input, func(out *bufio.Writer, class Class, text string) {
+		_, _ = out.WriteString(`<span class="` + class.String() + `">` + html.EscapeString(text) + "</span>")
+	})
+}
+
+// render writes input to w, passing the text of each span to token. The text
+// between spans is whitespace and written unchanged.
+func render(w io.Writer, input string, token func(out *bufio.Writer, class Class, text string)) error {
+	out := bufio.NewWriter(w)
+
+	pos := 0
+	for _, span := range Spans(input) {
+		_, _ = out.WriteString(input[pos:span.Start])
+		token(out, span.Class, input[span.Start:span.End])
+		pos = span.End
+	}
+	_, _ = out.WriteString(input[pos:])
+
+	return out.Flush()
+}
@@ -0,0 +1,82 @@
+package highlight
+
+import "unicode/utf8"
+
+// SemanticTokenTypes is the legend of the token types returned by
+// SemanticTokens. It uses the standard token types of the Language Server
+// Protocol.
+var SemanticTokenTypes = []string{"keyword", "variable", "number", "string", "operator", "comment"}
+
+// semanticTypes maps classes to their index in SemanticTokenTypes.
+// Punctuation and illegal characters are not reported.
+var semanticTypes = map[Class]uint32{
+	Keyword:    0,
+	Identifier: 1,
+	Number:     2,
+	String:     3,
+	Operator:   4,
+	Comment:    5,
+}
+
+// SemanticTokens returns the tokens of input encoded as LSP semantic tokens.
+// Each token is described by five integers: the line relative to the previous
+// token, the start character relative to the previous token if on the same
+// line, the length, the index of the type in SemanticTokenTypes and
+// modifiers, which are always 0. Characters are counted in UTF-16 code units.
+//
+// Tokens spanning several lines, like string literals containing line
+// breaks, are split into one token per line.
+func SemanticTokens(input string) []uint32 {
+	data := make([]uint32, 0)
+
+	// line and character are the position of offset.
+	offset, line, character := 0, 0, 0
+	advance := func(to int) {
+		for offset < to {
+			r, size := utf8.DecodeRuneInString(input[offset:])
+			offset += size
+			switch {
+			case r == '\n':
+				line++
+				character = 0
+			case r >= 0x10000:
+				character += 2
+			default:
+				character++
+			}
+		}
+	}
+
+	prevLine, prevCharacter := 0, 0
+	emit := func(startLine, startCharacter, length int, typ uint32) {
+		deltaCharacter := startCharacter
+		if startLine == prevLine {
+			deltaCharacter -= prevCharacter
+		}
+		data = append(data, uint32(startLine-prevLine), uint32(deltaCharacter), uint32(length), typ, 0)
+		prevLine, prevCharacter = startLine, startCharacter
+	}
+
+	for _, span := range Spans(input) {
+		typ, ok := semanticTypes[span.Class]
+		if !ok {
+			continue
+		}
+
+		advance(span.Start)
+		for offset < span.End {
+			startLine, startCharacter := line, character
+			end := offset
+			for end < span.End && input[end] != '\n' {
+				end++
+			}
+			advance(end)
+			if character > startCharacter {
+				emit(startLine, startCharacter, character-startCharacter, typ)
+			}
+			advance(min(end+1, span.End))
+		}
+	}
+
+	return data
+}
@@ -0,0 +1,256 @@
+// Package corpus contains Monkey programs together with their expected
+// results. The evaluator and the virtual machine are both tested against it,
+// which ensures they implement the same semantics.
+package corpus
+
+// Program is a program of the corpus. Exactly one of Expected and Error is set.
+type Program struct {
+	Name  string
+	Input string
+	// Expected is the result of the program, as returned by object.Object.Inspect.
+	Expected string
+	// Error is the message of the runtime error the program fails with.
+	Error string
+}
+
+// Fibonacci computes the 25th Fibonacci number recursively. It is used to
+// benchmark function calls.
+const Fibonacci = `
+let fibonacci = fn(x) {
+	if (x == 0) {
+		return 0;
+	} else {
+		if (x == 1) {
+			return 1;
+		} else {
+			fibonacci(x - 1) + fibonacci(x - 2);
+		}
+	}
+};
+fibonacci(25);
+`
+
+var Programs = []Program{
+	// Integers
+	{Name: "integer", Input: "5", Expected: "5"},
+	{Name: "negative integer", Input: "-10", Expected: "-10"},
+	{Name: "arithmetic", Input: "50 / 2 * 2 + 10 - 5", Expected: "55"},
+	{Name: "grouping", Input: "(5 + 10 * 2 + 15 / 3) * 2 + -10", Expected: "50"},
+	{Name: "integer division", Input: "7 / 2", Expected: "3"},
+
+	// Booleans
+	{Name: "boolean", Input: "true", Expected: "true"},
+	{Name: "comparison", Input: "1 < 2", Expected: "true"},
+	{Name: "greater than", Input: "1 > 2", Expected: "false"},
+	{Name: "integer equality", Input: "1 == 1", Expected: "true"},
+	{Name: "integer inequality", Input: "1 != 1", Expected: "false"},
+	{Name: "boolean equality", Input: "(1 < 2) == true", Expected: "true"},
+	{Name: "boolean inequality", Input: "true != false", Expected: "true"},
+	{Name: "mixed equality", Input: "1 == true", Expected: "false"},
+	{Name: "bang", Input: "!true", Expected: "false"},
+	{Name: "double bang", Input: "!!5", Expected: "true"},
+	{Name: "bang null", Input: "!if (false) { 5 }", Expected: "true"},
+
+	// Conditionals
+	{Name: "if", Input: "if (true) { 10 }", Expected: "10"},
+	{Name: "if else", Input: "if (1 > 2) { 10 } else { 20 }", Expected: "20"},
+	{Name: "if without alternative", Input: "if (false) { 10 }", Expected: "null"},
+	{Name: "truthy integer", Input: "if (0) { 10 }", Expected: "10"},
+	{Name: "null is not truthy", Input: "if (if (false) { 1 }) { 10 } else { 20 }", Expected: "20"},
+	{Name: "block ending in let", Input: "if (true) { let a = 1; }", Expected: "null"},
+	{Name: "nested if", Input: "if (true) { if (false) { 1 } else { 2 } }", Expected: "2"},
+
+	// Bindings
+	{Name: "let", Input: "let a = 5; a;", Expected: "5"},
+	{Name: "let expression", Input: "let a = 5 * 5; a;", Expected: "25"},
+	{Name: "let chain", Input: "let a = 5; let b = a; let c = a + b + 5; c;", Expected: "15"},
+	{Name: "rebinding", Input: "let a = 1; let a = a + 1; a;", Expected: "2"},
+
+	// Return statements
+	{Name: "top-level return", Input: "return 10; 9;", Expected: "10"},
+	{Name: "return in block", Input: "if (true) { if (true) { return 10; } return 1; }", Expected: "10"},
+
+	// Strings
+	{Name: "string", Input: `"monkey"`, Expected: "monkey"},
+	{Name: "string concatenation", Input: `"mon" + "key" + "banana"`, Expected: "monkeybanana"},
+	{Name: "string equality", Input: `"a" + "b" == "ab"`, Expected: "true"},
+	{Name: "string inequality", Input: `"a" != "a"`, Expected: "false"},
+
+	// Arrays
+	{Name: "array", Input: "[1, 2 * 2, 3 + 3]", Expected: "[1, 4, 6]"},
+	{Name: "empty array", Input: "[]", Expected: "[]"},
+	{Name: "array index", Input: "[1, 2, 3][1]", Expected: "2"},
+	{Name: "array index expression", Input: "let i = 0; [1][i]", Expected: "1"},
+	{Name: "array index out of range", Input: "[1, 2, 3][3]", Expected: "null"},
+	{Name: "negative array index", Input: "[1][-1]", Expected: "null"},
+	{Name: "nested array index", Input: "[[1, 1, 1]][0][0]", Expected: "1"},
+
+	// Hashes
+	{Name: "hash", Input: `{"one": 1, 2: 2, true: 3}`, Expected: "{2: 2, one: 1, true: 3}"},
+	{Name: "empty hash", Input: "{}", Expected: "{}"},
+	{Name: "hash index", Input: `{"one": 1, "two": 2}["o" + "ne"]`, Expected: "1"},
+	{Name: "hash missing key", Input: `{"one": 1}["two"]`, Expected: "null"},
+	{Name: "hash integer key", Input: "{1: 1, 2: 2}[2]", Expected: "2"},
+	{Name: "hash boolean key", Input: "{true: 5}[true]", Expected: "5"},
+
+	// Functions
+	{Name: "call", Input: "let five = fn() { 5 }; five();", Expected: "5"},
+	{Name: "call with arguments", Input: "let add = fn(a, b) { a + b }; add(1, add(2, 3));", Expected: "6"},
+	{Name: "immediate call", Input: "fn(x) { x * 2 }(4)", Expected: "8"},
+	{Name: "early return", Input: "let f = fn() { return 99; 100; }; f();", Expected: "99"},
+	{Name: "no return value", Input: "let f = fn() { }; f();", Expected: "null"},
+	{Name: "function ending in let", Input: "let f = fn() { let a = 1; }; f();", Expected: "null"},
+	{Name: "first-class functions", Input: "let one = fn() { 1 }; let get = fn() { one }; get()();", Expected: "1"},
+	{Name: "locals", Input: "let f = fn(a) { let b = a * 2; let c = b + 1; c }; f(2) + f(3);", Expected: "12"},
+	{Name: "globals in functions", Input: "let g = 50; let f = fn() { let l = 1; g - l }; f();", Expected: "49"},
+	{Name: "return from nested block", Input: "let f = fn(x) { if (x > 0) { return 1; } 0 }; f(1) + f(-1);", Expected: "1"},
+	{Name: "type annotations", Input: "let add = fn(a: int, b: int) -> int { a + b }; let x: int = add(1, 2); x", Expected: "3"},
+	{Name: "function value", Input: "let add = fn(a, b) { a + b }; add", Expected: "fn(a, b) {\n(a + b)\n}"},
+	{Name: "closure value", Input: "let newAdder = fn(a) { fn(b) { a + b } }; [newAdder(1)]", Expected: "[fn(b) {\n(a + b)\n}]"},
+
+	// Closures
+	{Name: "closure", Input: "let newAdder = fn(a) { fn(b) { a + b } }; let addTwo = newAdder(2); addTwo(3);", Expected: "5"},
+	{
+		Name: "nested closures",
+		Input: `let newAdder = fn(a, b) {
+			let c = a + b;
+			fn(d) { let e = d + c; fn(f) { e + f } }
+		};
+		newAdder(1, 2)(3)(4);`,
+		Expected: "10",
+	},
+	{
+		Name: "recursion",
+		Input: `let countDown = fn(x) { if (x == 0) { return 0; } countDown(x - 1) };
+		countDown(10);`,
+		Expected: "0",
+	},
+	{
+		Name: "recursive closure",
+		Input: `let wrapper = fn() {
+			let countDown = fn(x) { if (x == 0) { return 0; } countDown(x - 1) };
+			countDown(5)
+		};
+		wrapper();`,
+		Expected: "0",
+	},
+	{Name: "fibonacci", Input: Fibonacci, Expected: "75025"},
+	{
+		Name: "mutual recursion",
+		Input: `let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+		let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+		[isEven(10), isOdd(7), isEven(3)];`,
+		Expected: "[true, true, false]",
+	},
+
+	// Tail calls
+	{
+		Name: "tail recursion",
+		Input: `let countDown = fn(x) { if (x == 0) { return "done"; } countDown(x - 1) };
+		countDown(1000000);`,
+		Expected: "done",
+	},
+	{
+		Name: "tail recursion in branches",
+		Input: `let sum = fn(x, acc) { if (x == 0) { acc } else { sum(x - 1, acc + x) } };
+		sum(1000000, 0);`,
+		Expected: "500000500000",
+	},
+	{
+		Name: "tail recursion with return",
+		Input: `let flip = fn(x, acc) { if (x == 0) { return acc; } return flip(x - 1, !acc); };
+		[flip(1000001, true), flip(1000000, true)];`,
+		Expected: "[false, true]",
+	},
+	{Name: "tail call of builtin", Input: "let f = fn(a) { len(a) }; f([1, 2]);", Expected: "2"},
+	{Name: "tail call with wrong arguments", Input: "let f = fn() { f(1) }; f();", Error: "wrong number of arguments: want=0, got=1"},
+	{
+		Name:     "return within expression",
+		Input:    "let f = fn() { let x = if (true) { return 1; }; 2 }; [f(), 3];",
+		Expected: "[1, 3]",
+	},
+	{
+		Name:     "tail call within expression",
+		Input:    "let g = fn() { 1 }; let f = fn() { [if (true) { return g(); }] }; f();",
+		Expected: "1",
+	},
+
+	// Builtins
+	{Name: "len string", Input: `len("four")`, Expected: "4"},
+	{Name: "len array", Input: "len([1, 2, 3])", Expected: "3"},
+	{Name: "first", Input: "first([1, 2])", Expected: "1"},
+	{Name: "first empty", Input: "first([])", Expected: "null"},
+	{Name: "last", Input: "last([1, 2])", Expected: "2"},
+	{Name: "rest", Input: "rest([1, 2, 3])", Expected: "[2, 3]"},
+	{Name: "rest empty", Input: "rest([])", Expected: "null"},
+	{Name: "push", Input: "let a = [1]; let b = push(a, 2); [a, b];", Expected: "[[1], [1, 2]]"},
+	{Name: "puts", Input: "puts()", Expected: "null"},
+	{Name: "builtin as value", Input: "let f = len; f([1]);", Expected: "1"},
+	{
+		Name: "map",
+		Input: `let map = fn(arr, f) {
+			let iter = fn(arr, acc) {
+				if (len(arr) == 0) { acc } else { iter(rest(arr), push(acc, f(first(arr)))) }
+			};
+			iter(arr, [])
+		};
+		map([1, 2, 3], fn(x) { x * x });`,
+		Expected: "[1, 4, 9]",
+	},
+
+	// Runtime errors
+	{Name: "type mismatch", Input: "5 + true;", Error: "type mismatch: INTEGER + BOOLEAN"},
+	{Name: "type mismatch stops program", Input: "5 + true; 5;", Error: "type mismatch: INTEGER + BOOLEAN"},
+	{Name: "unknown prefix operator", Input: "-true", Error: "unknown operator: -BOOLEAN"},
+	{Name: "unknown infix operator", Input: "true + false;", Error: "unknown operator: BOOLEAN + BOOLEAN"},
+	{Name: "unknown string operator", Input: `"a" - "b"`, Error: "unknown operator: STRING - STRING"},
+	{Name: "unknown comparison", Input: "true > false", Error: "unknown operator: BOOLEAN > BOOLEAN"},
+	{Name: "error in block", Input: "if (10 > 1) { true + false; 1 }", Error: "unknown operator: BOOLEAN + BOOLEAN"},
+	{Name: "error in function", Input: "let f = fn() { 1 + [] }; f();", Error: "type mismatch: INTEGER + ARRAY"},
+	{Name: "division by zero", Input: "1 / 0", Error: "division by zero"},
+	{Name: "unusable hash key", Input: `{"name": "Monkey"}[fn(x) { x }];`, Error: "unusable as hash key: FUNCTION"},
+	{Name: "unsupported index", Input: "1[0]", Error: "index operator not supported: INTEGER"},
+	{Name: "call non-function", Input: "1()", Error: "not a function: INTEGER"},
+	{Name: "too few arguments", Input: "fn(a) { a }()", Error: "wrong number of arguments: want=1, got=0"},
+	{Name: "too many arguments", Input: "fn() { 1 }(1)", Error: "wrong number of arguments: want=0, got=1"},
+	{Name: "builtin error", Input: "len(1)", Error: "argument to `len` not supported, got INTEGER"},
+	{Name: "builtin arguments", Input: `len("one", "two")`, Error: "wrong number of arguments: want=1, got=2"},
+
+	// Exceptions
+	{Name: "catch thrown string", Input: `try { throw "boom"; } catch (e) { e["message"] }`, Expected: "boom"},
+	{Name: "catch runtime error", Input: `try { 1 / 0 } catch (e) { e["message"] }`, Expected: "division by zero"},
+	{Name: "try without error", Input: "try { 5 } catch (e) { 0 }", Expected: "5"},
+	{Name: "error position", Input: "let x = 0;\ntry { 1 / x } catch (e) { [e[\"line\"], e[\"column\"]] }", Expected: "[2, 9]"},
+	{
+		Name:     "error kinds",
+		Input:    `let kind = fn(f) { try { f() } catch (e) { e["kind"] } }; [kind(fn() { 1 / 0 }), kind(fn() { throw 1; }), kind(fn() { [1, "a"][1] - 1 })];`,
+		Expected: "[ArithmeticError, Error, TypeError]",
+	},
+	{Name: "missing error property", Input: `try { throw "s"; } catch (e) { e["nope"] }`, Expected: "null"},
+	{
+		Name: "error stack",
+		Input: `let inner = fn() { 1 / 0 };
+let outer = fn() { let y = inner(); y };
+try { outer() } catch (e) { e["stack"] }`,
+		Expected: "[inner (1:22), outer (2:33), 3:12]",
+	},
+	{
+		Name: "rethrow keeps stack",
+		Input: `let inner = fn() { 1 / 0 };
+let g = fn() { try { inner() } catch (e) { throw e; } };
+try { g() } catch (e) { e["stack"] }`,
+		Expected: "[inner (1:22), g (2:27), 3:8]",
+	},
+	{Name: "finally after value", Input: "let a = 0; let r = try { 1 } finally { let a = a + 1; }; [r, a];", Expected: "[1, 1]"},
+	{
+		Name:     "finally after error",
+		Input:    `let a = 0; let r = try { try { throw "x"; } finally { let a = a + 10; } } catch (e) { e["message"] }; [r, a];`,
+		Expected: "[x, 10]",
+	},
+	{Name: "return through finally", Input: "let a = 0; let f = fn() { try { return 1; } finally { let a = 2; } 3 }; [f(), a];", Expected: "[1, 0]"},
+	{Name: "uncaught throw", Input: `throw "boom"; 1;`, Error: "boom"},
+	{Name: "uncaught error through finally", Input: "try { len(1) } finally { 1 };", Error: "argument to `len` not supported, got INTEGER"},
+	{Name: "stack overflow", Input: "let f = fn(n) { f(n + 1) + 1 }; f(0);", Error: "stack overflow"},
+	{Name: "stack overflow is caught", Input: `let f = fn(n) { f(n + 1) + 1 }; try { f(0) } catch (e) { [e["kind"], e["message"]] }`, Expected: "[InternalError, stack overflow]"},
+	{Name: "member of non-module", Input: "let x = 5; x.y", Error: "member access not supported: INTEGER"},
+}
@@ -0,0 +1,20 @@
+// Package testutil contains helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/parser"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// MustParse parses input and fails the test if it contains syntax errors.
+func MustParse(t testing.TB, input string) *ast.Program {
+	t.Helper()
+
+	par := parser.NewParser(lexer.NewLexer(input))
+	program := par.ParseProgram()
+	require.Empty(t, par.Errors())
+	return program
+}
@@ -1,12 +1,37 @@
 package lexer
 
 import (
+	"errors"
+	"fmt"
 	"github.com/fabiante/monkeylang/token"
+	"io"
+	"strings"
+	"unicode/utf8"
 )
 
+// readSize is the number of bytes a lexer created by NewLexerFromReader reads
+// at once.
+const readSize = 4096
+
+// maxEmptyReads is the number of reads returning no data and no error after
+// which a reader is considered broken.
+const maxEmptyReads = 100
+
 type Lexer struct {
 	input string
 
+	// reader provides the rest of the input if the lexer was created by
+	// NewLexerFromReader. input then only holds the buffered part of the
+	// input, which starts at offset.
+	reader io.Reader
+	buf    []byte
+	offset int
+	err    error
+
+	// start is the position of the first char of the current token in input.
+	// Input before it is discarded when more input is read.
+	start int
+
 	// pos is the current position in input (points to current char).
 	pos int
 	// nextPos is the current reading position in input (after current char).
@@ -16,20 +41,67 @@ type Lexer struct {
 	//
 	// Note: Since this is a byte, the lexer can only work with single-byte characters (ASCII).
 	char byte
+
+	// line and column are the position of char in input.
+	line   int
+	column int
+
+	errors []*Error
 }
 
 func NewLexer(input string) *Lexer {
-	lexer := &Lexer{input: input}
+	lexer := &Lexer{input: input, line: 1}
 	lexer.readChar() // advance to first char
 	return lexer
 }
 
+// NewLexerAt returns a lexer which starts reading input at pos instead of
+// its beginning. pos must be the position of a token returned by another
+// lexer for the same input, or of a token which is preceded by the same text.
+// This allows to lex a changed input again starting at the last token before
+// the change.
+func NewLexerAt(input string, pos token.Position) *Lexer {
+	lexer := &Lexer{input: input, nextPos: pos.Offset, line: pos.Line, column: pos.Column - 1}
+	lexer.readChar()
+	return lexer
+}
+
+// NewLexerFromReader returns a lexer which reads its input from r as tokens
+// are requested. Only the input of the current token is kept in memory.
+//
+// Reading stops at the first error returned by r, which is then reported by
+// Err, and the lexer returns token.EOF as if the input ended there.
+func NewLexerFromReader(r io.Reader) *Lexer {
+	lexer := &Lexer{reader: r, buf: make([]byte, readSize), line: 1}
+	lexer.readChar() // advance to first char
+	return lexer
+}
+
+// Err returns the first error other than io.EOF returned by the reader of a
+// lexer created by NewLexerFromReader.
+func (l *Lexer) Err() error {
+	if errors.Is(l.err, io.EOF) {
+		return nil
+	}
+	return l.err
+}
+
+// Errors returns the errors found in the input so far. Each error belongs to a
+// token already returned by NextToken. Unexpected characters and invalid
+// number literals are returned as token.Illegal, unterminated strings as
+// token.String containing the rest of the input.
+func (l *Lexer) Errors() []*Error {
+	return l.errors
+}
+
 func (l *Lexer) NextToken() token.Token {
 	l.skipWhitespace()
+	l.start = l.pos
 
 	var t token.Token
 
 	t.Literal = string(l.char)
+	t.Pos = l.position()
 
 	switch l.char {
 	case '=':
@@ -43,7 +115,13 @@ func (l *Lexer) NextToken() token.Token {
 	case '+':
 		t.Type = token.Plus
 	case '-':
-		t.Type = token.Minus
+		if l.peekChar() == '>' {
+			t.Type = token.Arrow
+			l.readChar()
+			t.Literal = t.Literal + string(l.char)
+		} else {
+			t.Type = token.Minus
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			t.Type = token.NEQ
@@ -55,7 +133,13 @@ func (l *Lexer) NextToken() token.Token {
 	case '*':
 		t.Type = token.Asterisk
 	case '/':
-		t.Type = token.Slash
+		if l.peekChar() == '/' {
+			t.Type = token.Comment
+			t.Literal = l.readComment()
+			return t // readComment already advanced chars
+		} else {
+			t.Type = token.Slash
+		}
 	case '<':
 		t.Type = token.LT
 	case '>':
@@ -68,10 +152,38 @@ func (l *Lexer) NextToken() token.Token {
 		t.Type = token.LBrace
 	case '}':
 		t.Type = token.RBrace
+	case '[':
+		t.Type = token.LBracket
+	case ']':
+		t.Type = token.RBracket
 	case ',':
 		t.Type = token.Comma
 	case ';':
 		t.Type = token.Semicolon
+	case ':':
+		t.Type = token.Colon
+	case '.':
+		t.Type = token.Dot
+	case '"':
+		t.Type = token.String
+		t.Literal = l.readString()
+		if l.char == 0 {
+			l.errorf(t.Pos, "unterminated string")
+		}
+	case '#':
+		if l.offset+l.pos == 0 && l.peekChar() == '!' {
+			// A leading "#!" line is treated like a comment. This allows Monkey
+			// scripts to be executed directly like shell scripts:
+			//
+			//	#!/usr/bin/env -S monkey run
+			t.Type = token.Comment
+			t.Literal = l.readComment()
+			return t // readComment already advanced chars
+		} else {
+			t.Type = token.Illegal
+			t.Literal = l.readIllegal(t.Pos)
+			return t // readIllegal already advanced chars
+		}
 	case 0:
 		t.Type = token.EOF
 		t.Literal = ""
@@ -83,9 +195,21 @@ func (l *Lexer) NextToken() token.Token {
 		} else if isDigit(l.char) {
 			t.Literal = l.readDigit()
 			t.Type = token.Int
+			if isLetter(l.char) {
+				// Letters directly following a number would otherwise be
+				// lexed as a separate identifier.
+				for isLetter(l.char) || isDigit(l.char) {
+					l.readChar()
+				}
+				t.Literal = l.input[l.start:l.pos]
+				t.Type = token.Illegal
+				l.errorf(t.Pos, "invalid number literal %q", t.Literal)
+			}
 			return t // readDigit already advances chars
 		} else {
-			t = newToken(token.Illegal, string(l.char))
+			t.Type = token.Illegal
+			t.Literal = l.readIllegal(t.Pos)
+			return t // readIllegal already advanced chars
 		}
 	}
 
@@ -94,23 +218,23 @@ func (l *Lexer) NextToken() token.Token {
 	return t
 }
 
-func newToken(tokenType token.TokenType, literal string) token.Token {
-	return token.Token{
-		Type:    tokenType,
-		Literal: literal,
-	}
-}
-
 func (l *Lexer) skipWhitespace() {
 	c := l.char
 	for c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+		l.start = l.pos
 		l.readChar()
 		c = l.char
 	}
 }
 
 func (l *Lexer) readChar() {
-	if l.nextPos >= len(l.input) {
+	if l.char == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
+	if l.nextPos >= len(l.input) && !l.fill() {
 		l.char = 0
 	} else {
 		l.char = l.input[l.nextPos]
@@ -120,29 +244,120 @@ func (l *Lexer) readChar() {
 	l.nextPos += 1
 }
 
+// position returns the position of the current char.
+func (l *Lexer) position() token.Position {
+	return token.Position{
+		Offset: l.offset + l.pos,
+		Line:   l.line,
+		Column: l.column,
+	}
+}
+
 func (l *Lexer) peekChar() byte {
-	if l.nextPos >= len(l.input) {
+	if l.nextPos >= len(l.input) && !l.fill() {
 		return 0
 	} else {
 		return l.input[l.nextPos]
 	}
 }
 
+// fill reads more input from the reader and reports whether there is any.
+// The buffered input before the current token is discarded, so positions in
+// input must not be kept across calls.
+func (l *Lexer) fill() bool {
+	if l.reader == nil || l.err != nil {
+		return false
+	}
+
+	l.input = l.input[l.start:]
+	l.offset += l.start
+	l.pos -= l.start
+	l.nextPos -= l.start
+	l.start = 0
+
+	for i := 0; i < maxEmptyReads; i++ {
+		n, err := l.reader.Read(l.buf)
+		l.input += string(l.buf[:n])
+		if err != nil {
+			l.err = err
+		}
+		if n > 0 || err != nil {
+			return n > 0
+		}
+	}
+
+	l.err = io.ErrNoProgress
+	return false
+}
+
+// readIdentifier reads an identifier, which starts with a letter and may
+// contain digits after that.
 func (l *Lexer) readIdentifier() string {
-	pos := l.pos
-	// read until a non-letter is encountered
-	for isLetter(l.char) {
+	// read until neither a letter nor a digit is encountered
+	for isLetter(l.char) || isDigit(l.char) {
+		l.readChar()
+	}
+	return l.input[l.start:l.pos]
+}
+
+// readComment reads a line comment until the end of the line. The line break
+// itself is not part of the comment.
+func (l *Lexer) readComment() string {
+	for l.char != '\n' && l.char != 0 {
 		l.readChar()
 	}
-	return l.input[pos:l.pos]
+	return strings.TrimRight(l.input[l.start:l.pos], "\r")
+}
+
+// readString reads a string literal and returns its content without the quotes.
+// The current char is left on the closing quote.
+func (l *Lexer) readString() string {
+	for {
+		l.readChar()
+		if l.char == '"' || l.char == 0 {
+			break
+		}
+	}
+	return l.input[l.start+1 : l.pos]
 }
 
 func (l *Lexer) readDigit() string {
-	pos := l.pos
 	for isDigit(l.char) {
 		l.readChar()
 	}
-	return l.input[pos:l.pos]
+	return l.input[l.start:l.pos]
+}
+
+// readIllegal reads a character which does not start a token and reports it
+// as an error. All bytes of a multi-byte character are read.
+func (l *Lexer) readIllegal(pos token.Position) string {
+	l.readChar()
+	for !utf8.FullRuneInString(l.input[l.start:l.pos]) && l.char&0xC0 == 0x80 {
+		l.readChar() // continuation byte of a multi-byte character
+	}
+
+	literal := l.input[l.start:l.pos]
+	if r, size := utf8.DecodeRuneInString(literal); r != utf8.RuneError || size > 1 {
+		l.errorf(pos, "unexpected character %q", r)
+	} else {
+		l.errorf(pos, "invalid UTF-8 encoding %q", literal)
+	}
+	return literal
+}
+
+// Error describes an invalid part of the input found by a lexer.
+// Pos is the position of the token containing the error.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+func (l *Lexer) errorf(pos token.Position, format string, a ...any) {
+	l.errors = append(l.errors, &Error{Pos: pos, Msg: fmt.Sprintf(format, a...)})
 }
 
 func isLetter(c byte) bool {
@@ -1,10 +1,14 @@
 package lexer
 
 import (
+	"errors"
 	"github.com/fabiante/monkeylang/token"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestLexer_NextToken(t *testing.T) {
@@ -101,6 +105,39 @@ let result = add(five, ten);`
 		}
 	})
 
+	t.Run("type annotations", func(t *testing.T) {
+		input := `fn(a: int) -> bool {} - >`
+
+		tests := []struct {
+			expectedType    token.TokenType
+			expectedLiteral string
+		}{
+			{token.Func, "fn"},
+			{token.LParen, "("},
+			{token.Identifier, "a"},
+			{token.Colon, ":"},
+			{token.Identifier, "int"},
+			{token.RParen, ")"},
+			{token.Arrow, "->"},
+			{token.Identifier, "bool"},
+			{token.LBrace, "{"},
+			{token.RBrace, "}"},
+			{token.Minus, "-"},
+			{token.GT, ">"},
+			{token.EOF, ""},
+		}
+
+		lexer := NewLexer(input)
+
+		for i, test := range tests {
+			actual := lexer.NextToken()
+			require.NotNil(t, actual, "parsing token %d returned nil", i)
+
+			assert.Equal(t, test.expectedLiteral, actual.Literal, "unexpected token literal %d", i)
+			assert.Equal(t, test.expectedType, actual.Type, "unexpected token type %d", i)
+		}
+	})
+
 	t.Run("operations and comparators", func(t *testing.T) {
 		input := `!-/*5;5 < 10 > 5`
 
@@ -192,4 +229,350 @@ let result = add(five, ten);`
 			assert.Equal(t, test.expectedType, actual.Type, "unexpected token type %d", i)
 		}
 	})
+	t.Run("shebang line", func(t *testing.T) {
+		input := "#!/usr/bin/env -S monkey run\nlet x = 5;"
+
+		tests := []struct {
+			expectedType    token.TokenType
+			expectedLiteral string
+		}{
+			{token.Comment, "#!/usr/bin/env -S monkey run"},
+			{token.Let, "let"},
+			{token.Identifier, "x"},
+			{token.Assign, "="},
+			{token.Int, "5"},
+			{token.Semicolon, ";"},
+			{token.EOF, ""},
+		}
+
+		lexer := NewLexer(input)
+
+		for i, test := range tests {
+			actual := lexer.NextToken()
+			require.NotNil(t, actual, "parsing token %d returned nil", i)
+
+			assert.Equal(t, test.expectedLiteral, actual.Literal, "unexpected token literal %d", i)
+			assert.Equal(t, test.expectedType, actual.Type, "unexpected token type %d", i)
+		}
+	})
+
+	t.Run("shebang only on first line", func(t *testing.T) {
+		input := "5;\n#!"
+
+		tests := []struct {
+			expectedType    token.TokenType
+			expectedLiteral string
+		}{
+			{token.Int, "5"},
+			{token.Semicolon, ";"},
+			{token.Illegal, "#"},
+			{token.Bang, "!"},
+			{token.EOF, ""},
+		}
+
+		lexer := NewLexer(input)
+
+		for i, test := range tests {
+			actual := lexer.NextToken()
+			require.NotNil(t, actual, "parsing token %d returned nil", i)
+
+			assert.Equal(t, test.expectedLiteral, actual.Literal, "unexpected token literal %d", i)
+			assert.Equal(t, test.expectedType, actual.Type, "unexpected token type %d", i)
+		}
+	})
+	t.Run("comments", func(t *testing.T) {
+		input := "// leading\nx / y; // trailing\r\n//"
+
+		tests := []struct {
+			expectedType    token.TokenType
+			expectedLiteral string
+		}{
+			{token.Comment, "// leading"},
+			{token.Identifier, "x"},
+			{token.Slash, "/"},
+			{token.Identifier, "y"},
+			{token.Semicolon, ";"},
+			{token.Comment, "// trailing"},
+			{token.Comment, "//"},
+			{token.EOF, ""},
+		}
+
+		lexer := NewLexer(input)
+
+		for i, test := range tests {
+			actual := lexer.NextToken()
+			require.NotNil(t, actual, "parsing token %d returned nil", i)
+
+			assert.Equal(t, test.expectedLiteral, actual.Literal, "unexpected token literal %d", i)
+			assert.Equal(t, test.expectedType, actual.Type, "unexpected token type %d", i)
+		}
+	})
+
+	t.Run("positions", func(t *testing.T) {
+		input := "let x = 5;\n\n  x == 10 # y"
+
+		tests := []struct {
+			expectedLiteral string
+			expectedPos     token.Position
+		}{
+			{"let", token.Position{Offset: 0, Line: 1, Column: 1}},
+			{"x", token.Position{Offset: 4, Line: 1, Column: 5}},
+			{"=", token.Position{Offset: 6, Line: 1, Column: 7}},
+			{"5", token.Position{Offset: 8, Line: 1, Column: 9}},
+			{";", token.Position{Offset: 9, Line: 1, Column: 10}},
+			{"x", token.Position{Offset: 14, Line: 3, Column: 3}},
+			{"==", token.Position{Offset: 16, Line: 3, Column: 5}},
+			{"10", token.Position{Offset: 19, Line: 3, Column: 8}},
+			{"#", token.Position{Offset: 22, Line: 3, Column: 11}},
+			{"y", token.Position{Offset: 24, Line: 3, Column: 13}},
+			{"", token.Position{Offset: 25, Line: 3, Column: 14}},
+		}
+
+		lexer := NewLexer(input)
+
+		for i, test := range tests {
+			actual := lexer.NextToken()
+
+			assert.Equal(t, test.expectedLiteral, actual.Literal, "unexpected token literal %d", i)
+			assert.Equal(t, test.expectedPos, actual.Pos, "unexpected token position %d", i)
+		}
+	})
+	t.Run("strings, arrays and hashes", func(t *testing.T) {
+		input := `"foobar" "foo bar" "" [1, 2]; {"foo": "bar"}`
+
+		tests := []struct {
+			expectedType    token.TokenType
+			expectedLiteral string
+		}{
+			{token.String, "foobar"},
+			{token.String, "foo bar"},
+			{token.String, ""},
+			{token.LBracket, "["},
+			{token.Int, "1"},
+			{token.Comma, ","},
+			{token.Int, "2"},
+			{token.RBracket, "]"},
+			{token.Semicolon, ";"},
+			{token.LBrace, "{"},
+			{token.String, "foo"},
+			{token.Colon, ":"},
+			{token.String, "bar"},
+			{token.RBrace, "}"},
+			{token.EOF, ""},
+		}
+
+		lexer := NewLexer(input)
+
+		for i, test := range tests {
+			actual := lexer.NextToken()
+			require.NotNil(t, actual, "parsing token %d returned nil", i)
+
+			assert.Equal(t, test.expectedLiteral, actual.Literal, "unexpected token literal %d", i)
+			assert.Equal(t, test.expectedType, actual.Type, "unexpected token type %d", i)
+		}
+	})
+
+	t.Run("modules", func(t *testing.T) {
+		input := `import "lib/math.mk" as math; export let x = math.add;`
+
+		tests := []struct {
+			expectedType    token.TokenType
+			expectedLiteral string
+		}{
+			{token.Import, "import"},
+			{token.String, "lib/math.mk"},
+			{token.As, "as"},
+			{token.Identifier, "math"},
+			{token.Semicolon, ";"},
+			{token.Export, "export"},
+			{token.Let, "let"},
+			{token.Identifier, "x"},
+			{token.Assign, "="},
+			{token.Identifier, "math"},
+			{token.Dot, "."},
+			{token.Identifier, "add"},
+			{token.Semicolon, ";"},
+			{token.EOF, ""},
+		}
+
+		lexer := NewLexer(input)
+
+		for i, test := range tests {
+			actual := lexer.NextToken()
+
+			assert.Equal(t, test.expectedLiteral, actual.Literal, "unexpected token literal %d", i)
+			assert.Equal(t, test.expectedType, actual.Type, "unexpected token type %d", i)
+		}
+	})
+
+	t.Run("identifiers with digits", func(t *testing.T) {
+		input := "let var2 = _tmp3 + x1y2;\nv10-2 3+a4 9"
+
+		tests := []struct {
+			expectedType    token.TokenType
+			expectedLiteral string
+		}{
+			{token.Let, "let"},
+			{token.Identifier, "var2"},
+			{token.Assign, "="},
+			{token.Identifier, "_tmp3"},
+			{token.Plus, "+"},
+			{token.Identifier, "x1y2"},
+			{token.Semicolon, ";"},
+			{token.Identifier, "v10"},
+			{token.Minus, "-"},
+			{token.Int, "2"},
+			{token.Int, "3"},
+			{token.Plus, "+"},
+			{token.Identifier, "a4"},
+			{token.Int, "9"},
+			{token.EOF, ""},
+		}
+
+		lexer := NewLexer(input)
+
+		for i, test := range tests {
+			actual := lexer.NextToken()
+
+			assert.Equal(t, test.expectedLiteral, actual.Literal, "unexpected token literal %d", i)
+			assert.Equal(t, test.expectedType, actual.Type, "unexpected token type %d", i)
+		}
+		assert.Empty(t, lexer.Errors())
+	})
+
+	t.Run("restart at position", func(t *testing.T) {
+		input := "let a = 1;\nlet bc = \"x\";"
+
+		var expected []token.Token
+		lexer := NewLexer(input)
+		for tok := lexer.NextToken(); tok.Type != token.EOF; tok = lexer.NextToken() {
+			expected = append(expected, tok)
+		}
+
+		for i, start := range expected {
+			lexer := NewLexerAt(input, start.Pos)
+			for _, tok := range expected[i:] {
+				assert.Equal(t, tok, lexer.NextToken())
+			}
+			assert.Equal(t, token.EOF, lexer.NextToken().Type)
+		}
+	})
+}
+
+func TestNewLexerFromReader(t *testing.T) {
+	tokens := func(lexer *Lexer) []token.Token {
+		var tokens []token.Token
+		for tok := lexer.NextToken(); tok.Type != token.EOF; tok = lexer.NextToken() {
+			tokens = append(tokens, tok)
+		}
+		return append(tokens, lexer.NextToken())
+	}
+
+	t.Run("same tokens as for a string", func(t *testing.T) {
+		input := "#!/usr/bin/env monkey\nlet add = fn(a, b) -> int { a + b }; // add\n\tlet s = \"x\ny\" != \"z\";\r\nadd(1, 23) >= 4 @\n\"open"
+
+		readers := map[string]func() io.Reader{
+			"whole input":  func() io.Reader { return strings.NewReader(input) },
+			"single bytes": func() io.Reader { return iotest.OneByteReader(strings.NewReader(input)) },
+			"half bytes":   func() io.Reader { return iotest.HalfReader(strings.NewReader(input)) },
+		}
+
+		expected := tokens(NewLexer(input))
+		for name, reader := range readers {
+			lexer := NewLexerFromReader(reader())
+			assert.Equal(t, expected, tokens(lexer), name)
+			assert.NoError(t, lexer.Err(), name)
+		}
+	})
+
+	t.Run("only buffers the current token", func(t *testing.T) {
+		input := strings.Repeat("let x = \"abc\";\n", 1000)
+
+		lexer := NewLexerFromReader(iotest.OneByteReader(strings.NewReader(input)))
+		tok := lexer.NextToken()
+		for ; tok.Type != token.EOF; tok = lexer.NextToken() {
+			require.LessOrEqual(t, len(lexer.input), len(`"abc";`))
+		}
+		assert.Equal(t, token.Position{Offset: len(input), Line: 1001, Column: 1}, tok.Pos)
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		err := errors.New("connection reset")
+		lexer := NewLexerFromReader(io.MultiReader(strings.NewReader("let x"), iotest.ErrReader(err)))
+
+		assert.Equal(t, token.Let, lexer.NextToken().Type)
+		assert.Equal(t, "x", lexer.NextToken().Literal)
+		assert.Equal(t, token.EOF, lexer.NextToken().Type)
+		assert.Equal(t, err, lexer.Err())
+	})
+}
+
+func TestLexer_Errors(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedTokens []token.Token
+		expectedErrors []string
+	}{
+		{
+			input: "a $ b",
+			expectedTokens: []token.Token{
+				{Type: token.Identifier, Literal: "a", Pos: token.Position{Offset: 0, Line: 1, Column: 1}},
+				{Type: token.Illegal, Literal: "$", Pos: token.Position{Offset: 2, Line: 1, Column: 3}},
+				{Type: token.Identifier, Literal: "b", Pos: token.Position{Offset: 4, Line: 1, Column: 5}},
+			},
+			expectedErrors: []string{"1:3: unexpected character '$'"},
+		},
+		{
+			input: "1 # ü\xff",
+			expectedTokens: []token.Token{
+				{Type: token.Int, Literal: "1", Pos: token.Position{Offset: 0, Line: 1, Column: 1}},
+				{Type: token.Illegal, Literal: "#", Pos: token.Position{Offset: 2, Line: 1, Column: 3}},
+				{Type: token.Illegal, Literal: "ü", Pos: token.Position{Offset: 4, Line: 1, Column: 5}},
+				{Type: token.Illegal, Literal: "\xff", Pos: token.Position{Offset: 6, Line: 1, Column: 7}},
+			},
+			expectedErrors: []string{
+				"1:3: unexpected character '#'",
+				"1:5: unexpected character 'ü'",
+				`1:7: invalid UTF-8 encoding "\xff"`,
+			},
+		},
+		{
+			input: "x;\n\"abc\n",
+			expectedTokens: []token.Token{
+				{Type: token.Identifier, Literal: "x", Pos: token.Position{Offset: 0, Line: 1, Column: 1}},
+				{Type: token.Semicolon, Literal: ";", Pos: token.Position{Offset: 1, Line: 1, Column: 2}},
+				{Type: token.String, Literal: "abc\n", Pos: token.Position{Offset: 3, Line: 2, Column: 1}},
+			},
+			expectedErrors: []string{"2:1: unterminated string"},
+		},
+		{
+			input: "12abc + 3x4 5",
+			expectedTokens: []token.Token{
+				{Type: token.Illegal, Literal: "12abc", Pos: token.Position{Offset: 0, Line: 1, Column: 1}},
+				{Type: token.Plus, Literal: "+", Pos: token.Position{Offset: 6, Line: 1, Column: 7}},
+				{Type: token.Illegal, Literal: "3x4", Pos: token.Position{Offset: 8, Line: 1, Column: 9}},
+				{Type: token.Int, Literal: "5", Pos: token.Position{Offset: 12, Line: 1, Column: 13}},
+			},
+			expectedErrors: []string{
+				`1:1: invalid number literal "12abc"`,
+				`1:9: invalid number literal "3x4"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		lexer := NewLexer(test.input)
+
+		var tokens []token.Token
+		for tok := range lexer.All() {
+			tokens = append(tokens, tok)
+		}
+		assert.Equal(t, test.expectedTokens, tokens, test.input)
+
+		var messages []string
+		for _, err := range lexer.Errors() {
+			messages = append(messages, err.Error())
+		}
+		assert.Equal(t, test.expectedErrors, messages, test.input)
+	}
 }
@@ -0,0 +1,35 @@
+package lexer
+
+import (
+	"github.com/fabiante/monkeylang/token"
+	"iter"
+)
+
+// All returns an iterator over the remaining tokens of l. The final
+// token.EOF is not included.
+func (l *Lexer) All() iter.Seq[token.Token] {
+	return func(yield func(token.Token) bool) {
+		for t := l.NextToken(); t.Type != token.EOF; t = l.NextToken() {
+			if !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// Tokenize returns all tokens of src, excluding the final token.EOF, and the
+// errors of the lexer as returned by Lexer.Errors.
+func Tokenize(src string) ([]token.Token, []error) {
+	var tokens []token.Token
+
+	l := NewLexer(src)
+	for t := range l.All() {
+		tokens = append(tokens, t)
+	}
+
+	var errs []error
+	for _, err := range l.Errors() {
+		errs = append(errs, err)
+	}
+	return tokens, errs
+}
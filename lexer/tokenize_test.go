@@ -0,0 +1,62 @@
+package lexer
+
+import (
+	"github.com/fabiante/monkeylang/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLexer_All(t *testing.T) {
+	t.Run("all tokens", func(t *testing.T) {
+		var literals []string
+		for tok := range NewLexer("let x = 1;").All() {
+			literals = append(literals, tok.Literal)
+		}
+		assert.Equal(t, []string{"let", "x", "=", "1", ";"}, literals)
+	})
+
+	t.Run("stop early", func(t *testing.T) {
+		lexer := NewLexer("let x = 1;")
+		for tok := range lexer.All() {
+			if tok.Type == token.Assign {
+				break
+			}
+		}
+		assert.Equal(t, "1", lexer.NextToken().Literal)
+	})
+}
+
+func TestTokenize(t *testing.T) {
+	t.Run("tokens", func(t *testing.T) {
+		tokens, errs := Tokenize("x + 1\n")
+		require.Empty(t, errs)
+		assert.Equal(t, []token.Token{
+			{Type: token.Identifier, Literal: "x", Pos: token.Position{Offset: 0, Line: 1, Column: 1}},
+			{Type: token.Plus, Literal: "+", Pos: token.Position{Offset: 2, Line: 1, Column: 3}},
+			{Type: token.Int, Literal: "1", Pos: token.Position{Offset: 4, Line: 1, Column: 5}},
+		}, tokens)
+	})
+
+	t.Run("empty input", func(t *testing.T) {
+		tokens, errs := Tokenize("")
+		assert.Empty(t, tokens)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("illegal characters", func(t *testing.T) {
+		tokens, errs := Tokenize("a @\né")
+		require.Len(t, tokens, 3)
+		assert.Equal(t, token.Illegal, tokens[1].Type)
+		assert.Equal(t, "é", tokens[2].Literal)
+
+		var messages []string
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+		assert.Equal(t, []string{
+			`1:3: unexpected character '@'`,
+			`2:1: unexpected character 'é'`,
+		}, messages)
+	})
+}
@@ -0,0 +1,156 @@
+// Package lint finds suspicious constructs in programs, like unused bindings
+// or code which can never run.
+//
+// Each check is implemented as a Rule. The findings of the rules are valid
+// programs, which is why they are reported as diagnostics rather than
+// errors. Rules can be enabled and disabled with a Config.
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/token"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Diagnostic is a finding of a rule.
+type Diagnostic struct {
+	// Rule is the name of the rule which reported the diagnostic.
+	Rule string
+	Pos  token.Position
+	Msg  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s (%s)", d.Pos, d.Msg, d.Rule)
+}
+
+// Reporter collects the diagnostics of a rule.
+type Reporter interface {
+	Report(pos token.Position, format string, a ...any)
+}
+
+// Rule is a single check.
+type Rule interface {
+	// Name is the short name of the rule used in configs and diagnostics.
+	Name() string
+	// Doc describes what the rule reports in one sentence.
+	Doc() string
+	// Check reports the findings in program. It must not change the
+	// structure of program.
+	Check(program *ast.Program, r Reporter)
+}
+
+// NewRule creates a Rule from a function.
+func NewRule(name, doc string, check func(program *ast.Program, r Reporter)) Rule {
+	return &funcRule{name: name, doc: doc, check: check}
+}
+
+type funcRule struct {
+	name  string
+	doc   string
+	check func(program *ast.Program, r Reporter)
+}
+
+func (r *funcRule) Name() string {
+	return r.name
+}
+
+func (r *funcRule) Doc() string {
+	return r.doc
+}
+
+func (r *funcRule) Check(program *ast.Program, reporter Reporter) {
+	r.check(program, reporter)
+}
+
+// DefaultRules returns all rules of this package.
+func DefaultRules() []Rule {
+	return []Rule{
+		UnusedLet(),
+		Shadow(),
+		Unreachable(),
+		SelfComparison(),
+		ConstantCondition(),
+		DoubleNegation(),
+	}
+}
+
+// Lint applies the given rules to program and returns their diagnostics
+// sorted by position. Without rules, the DefaultRules are applied.
+func Lint(program *ast.Program, rules ...Rule) []Diagnostic {
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+
+	var diagnostics []Diagnostic
+	for _, rule := range rules {
+		rule.Check(program, &reporter{rule: rule.Name(), diagnostics: &diagnostics})
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Pos.Offset < diagnostics[j].Pos.Offset
+	})
+	return diagnostics
+}
+
+type reporter struct {
+	rule        string
+	diagnostics *[]Diagnostic
+}
+
+func (r *reporter) Report(pos token.Position, format string, a ...any) {
+	*r.diagnostics = append(*r.diagnostics, Diagnostic{Rule: r.rule, Pos: pos, Msg: fmt.Sprintf(format, a...)})
+}
+
+// Config selects the rules to apply. It is usually read from a JSON file
+// which maps rule names to whether they are enabled:
+//
+//	{"rules": {"shadow": false}}
+//
+// Rules which are not mentioned are enabled.
+type Config struct {
+	Rules map[string]bool `json:"rules"`
+}
+
+// ReadConfig reads a JSON encoded Config.
+func ReadConfig(r io.Reader) (*Config, error) {
+	var config Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&config); err != nil {
+		return nil, fmt.Errorf("invalid lint config: %w", err)
+	}
+	return &config, nil
+}
+
+// EnabledRules returns the rules of DefaultRules enabled by the config. It
+// fails if the config refers to an unknown rule.
+func (c *Config) EnabledRules() ([]Rule, error) {
+	all := DefaultRules()
+
+	known := make(map[string]bool, len(all))
+	for _, rule := range all {
+		known[rule.Name()] = true
+	}
+	for name := range c.Rules {
+		if !known[name] {
+			names := make([]string, 0, len(all))
+			for _, rule := range all {
+				names = append(names, rule.Name())
+			}
+			return nil, fmt.Errorf("unknown lint rule %q, known rules are %s", name, strings.Join(names, ", "))
+		}
+	}
+
+	rules := make([]Rule, 0, len(all))
+	for _, rule := range all {
+		if enabled, ok := c.Rules[rule.Name()]; !ok || enabled {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
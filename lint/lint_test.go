@@ -0,0 +1,161 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/fabiante/monkeylang/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule     Rule
+		input    string
+		expected []string
+	}{
+		{UnusedLet(), "let a = 1; let b = 2; b;", []string{"1:5: a is declared but never used (unused-let)"}},
+		{UnusedLet(), "let a = 1; let a = a + 1; a;", nil},
+		{UnusedLet(), "let a = 1; let a = 2; a;", []string{"1:5: a is declared but never used (unused-let)"}},
+		{UnusedLet(), "let f = fn(x, y) { let z = x; if (y) { let w = 1; } }; f(1, 2);", []string{
+			"1:24: z is declared but never used (unused-let)",
+			"1:44: w is declared but never used (unused-let)",
+		}},
+		{UnusedLet(), "let a = 1; let f = fn() { a }; f();", nil},
+		{UnusedLet(), "let f = fn(n) { if (n > 0) { f(n - 1) } }; f(3);", nil},
+		{UnusedLet(), "let _a = 1;", nil},
+		{UnusedLet(), "try { 1 } catch (e) { 2 }", nil},
+		{UnusedLet(), `export let a = 1; import "m.mk" as m; import "n.mk" as n; let b = 2; n.b;`, []string{
+			"1:36: m is declared but never used (unused-let)",
+			"1:63: b is declared but never used (unused-let)",
+		}},
+		{Shadow(), "let a = 1; let f = fn(a) { let len = a; len }; f(a);", []string{
+			"1:23: declaration of a shadows declaration at 1:5 (shadow)",
+			"1:32: declaration of len shadows builtin (shadow)",
+		}},
+		{Unreachable(), "let f = fn() { return 1; let a = 2; a };", []string{"1:26: unreachable code after return (unreachable)"}},
+		{Unreachable(), "return 1; 2;", []string{"1:11: unreachable code after return (unreachable)"}},
+		{Unreachable(), "if (x) { return 1; } else { return 2; } 3;", []string{"1:41: unreachable code after return (unreachable)"}},
+		{Unreachable(), "if (x) { return 1; } 3;", nil},
+		{Unreachable(), `let f = fn() { throw "no"; 1 }; f();`, []string{"1:28: unreachable code after throw (unreachable)"}},
+		{SelfComparison(), "x == x; a[0] != a[0]; x < y; 1 > 1;", []string{
+			"1:3: comparison of x with itself is always true (self-comparison)",
+			"1:14: comparison of an expression with itself is always false (self-comparison)",
+			"1:32: comparison of 1 with itself is always false (self-comparison)",
+		}},
+		{SelfComparison(), "f() == f();", nil},
+		{SelfComparison(), "[1] == [1]; {} == {}; fn(x){x} == fn(x){x}; [x][0] != [x][0];", nil},
+		{ConstantCondition(), `if (true) { 1 } if (1 > 2) { 1 } if ([]) { 1 } if (x) { 1 } if (f()) { 1 } if (1 / 0) { 1 }`, []string{
+			"1:1: condition is always true (constant-condition)",
+			"1:17: condition is always false (constant-condition)",
+			"1:34: condition is always true (constant-condition)",
+		}},
+		{DoubleNegation(), "!!x; !x; !!!y;", []string{
+			"1:1: double negation of x (double-negation)",
+			"1:10: double negation of an expression (double-negation)",
+		}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("tests[%d] %s", i, test.rule.Name()), func(t *testing.T) {
+			var messages []string
+			for _, d := range Lint(testutil.MustParse(t, test.input), test.rule) {
+				messages = append(messages, d.String())
+			}
+			assert.Equal(t, test.expected, messages)
+		})
+	}
+}
+
+func TestLint(t *testing.T) {
+	t.Run("applies default rules in source order", func(t *testing.T) {
+		diagnostics := Lint(testutil.MustParse(t, "let a = !!b; if (1 == 1) { return 1; 2 }"))
+
+		var rules []string
+		for _, d := range diagnostics {
+			rules = append(rules, d.Rule)
+		}
+		assert.Equal(t, []string{"unused-let", "double-negation", "constant-condition", "self-comparison", "unreachable"}, rules)
+	})
+}
+
+func TestConfig(t *testing.T) {
+	t.Run("enables rules not mentioned", func(t *testing.T) {
+		config, err := ReadConfig(strings.NewReader(`{"rules": {"shadow": false, "unreachable": true}}`))
+		require.NoError(t, err)
+
+		rules, err := config.EnabledRules()
+		require.NoError(t, err)
+
+		var names []string
+		for _, rule := range rules {
+			names = append(names, rule.Name())
+		}
+		assert.Equal(t, []string{"unused-let", "unreachable", "self-comparison", "constant-condition", "double-negation"}, names)
+	})
+
+	t.Run("rejects unknown rules", func(t *testing.T) {
+		config, err := ReadConfig(strings.NewReader(`{"rules": {"shadows": false}}`))
+		require.NoError(t, err)
+
+		_, err = config.EnabledRules()
+		assert.ErrorContains(t, err, `unknown lint rule "shadows"`)
+	})
+
+	t.Run("rejects unknown fields", func(t *testing.T) {
+		_, err := ReadConfig(strings.NewReader(`{"rule": {}}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestWriteSARIF(t *testing.T) {
+	rules := []Rule{DoubleNegation()}
+	files := []File{{Name: "a.mk", Diagnostics: Lint(testutil.MustParse(t, "\n  !!x"), rules...)}}
+
+	var out bytes.Buffer
+	require.NoError(t, WriteSARIF(&out, rules, files))
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &log))
+
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	require.Len(t, log.Runs[0].Tool.Driver.Rules, 1)
+	assert.Equal(t, "double-negation", log.Runs[0].Tool.Driver.Rules[0].ID)
+	require.Len(t, log.Runs[0].Results, 1)
+
+	result := log.Runs[0].Results[0]
+	assert.Equal(t, "double-negation", result.RuleID)
+	require.Len(t, result.Locations, 1)
+	assert.Equal(t, "a.mk", result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 2, result.Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Equal(t, 3, result.Locations[0].PhysicalLocation.Region.StartColumn)
+}
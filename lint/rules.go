@@ -0,0 +1,339 @@
+package lint
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/evaluator"
+	"github.com/fabiante/monkeylang/object"
+	"github.com/fabiante/monkeylang/resolver"
+	"github.com/fabiante/monkeylang/token"
+	"strings"
+)
+
+// UnusedLet returns a rule which reports let statements and imports whose
+// binding is never referenced. Names starting with an underscore and exported
+// bindings are exempt.
+func UnusedLet() Rule {
+	return NewRule("unused-let", "Reports let bindings which are never used.", func(program *ast.Program, r Reporter) {
+		u := &unusedLets{reporter: r}
+		u.scope = &letScope{}
+		u.statements(program.Statements)
+		u.close()
+	})
+}
+
+// unusedLets tracks the let bindings of each function scope. Like in package
+// resolver, blocks do not introduce scopes.
+type unusedLets struct {
+	reporter Reporter
+	scope    *letScope
+}
+
+type letScope struct {
+	outer *letScope
+	// bindings maps names to their latest declaration. A redeclaration
+	// hides the previous one, which is reported if it has not been used.
+	bindings map[string]*letBinding
+	all      []*letBinding
+}
+
+type letBinding struct {
+	ident *ast.Identifier
+	// let is false for parameters and exported bindings, which are not
+	// reported.
+	let  bool
+	used bool
+}
+
+func (u *unusedLets) statements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			if _, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+				u.declare(stmt.Name, !stmt.Exported())
+				u.expression(stmt.Value)
+			} else {
+				u.expression(stmt.Value)
+				u.declare(stmt.Name, !stmt.Exported())
+			}
+		case *ast.ImportStatement:
+			u.declare(stmt.Name, true)
+		case *ast.ReturnStatement:
+			u.expression(stmt.ReturnValue)
+		case *ast.ThrowStatement:
+			u.expression(stmt.Value)
+		case *ast.ExpressionStatement:
+			u.expression(stmt.Expression)
+		}
+	}
+}
+
+func (u *unusedLets) expression(exp ast.Expression) {
+	if exp == nil {
+		return
+	}
+
+	ast.Inspect(exp, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			u.scope = &letScope{outer: u.scope}
+			for _, param := range node.Parameters {
+				u.declare(param, false)
+			}
+			if node.Body != nil {
+				u.statements(node.Body.Statements)
+			}
+			u.close()
+			return false
+		case *ast.TryExpression:
+			u.block(node.Block)
+			if node.Parameter != nil {
+				u.declare(node.Parameter, false)
+			}
+			u.block(node.Catch)
+			u.block(node.Finally)
+			return false
+		case *ast.BlockStatement:
+			u.statements(node.Statements)
+			return false
+		case *ast.MemberExpression:
+			// The property is not a reference to a binding.
+			u.expression(node.Object)
+			return false
+		case *ast.Identifier:
+			u.use(node.Value)
+		}
+		return true
+	})
+}
+
+func (u *unusedLets) block(block *ast.BlockStatement) {
+	if block != nil {
+		u.statements(block.Statements)
+	}
+}
+
+func (u *unusedLets) declare(ident *ast.Identifier, let bool) {
+	if u.scope.bindings == nil {
+		u.scope.bindings = make(map[string]*letBinding)
+	}
+	b := &letBinding{ident: ident, let: let}
+	u.scope.bindings[ident.Value] = b
+	u.scope.all = append(u.scope.all, b)
+}
+
+func (u *unusedLets) use(name string) {
+	for scope := u.scope; scope != nil; scope = scope.outer {
+		if b, ok := scope.bindings[name]; ok {
+			b.used = true
+			return
+		}
+	}
+}
+
+// close reports the unused bindings of the current scope and leaves it.
+func (u *unusedLets) close() {
+	for _, b := range u.scope.all {
+		if b.let && !b.used && !strings.HasPrefix(b.ident.Value, "_") {
+			u.reporter.Report(b.ident.Token.Pos, "%s is declared but never used", b.ident.Value)
+		}
+	}
+	u.scope = u.scope.outer
+}
+
+// Shadow returns a rule which reports declarations hiding a binding of an
+// enclosing function or a builtin, as found by package resolver.
+func Shadow() Rule {
+	return NewRule("shadow", "Reports declarations which shadow an outer binding or a builtin.", func(program *ast.Program, r Reporter) {
+		_, warnings := resolver.Resolve(program)
+		for _, w := range warnings {
+			r.Report(w.Pos, "%s", w.Msg)
+		}
+	})
+}
+
+// Unreachable returns a rule which reports statements following a return
+// or throw statement in the same block. An if expression whose branches both
+// return ends the block as well.
+func Unreachable() Rule {
+	return NewRule("unreachable", "Reports code after a return or throw statement.", func(program *ast.Program, r Reporter) {
+		check := func(statements []ast.Statement) {
+			for i, stmt := range statements[:max(len(statements)-1, 0)] {
+				if _, ok := stmt.(*ast.ThrowStatement); ok {
+					r.Report(statementPos(statements[i+1]), "unreachable code after throw")
+					return
+				}
+				if returns(stmt) {
+					r.Report(statementPos(statements[i+1]), "unreachable code after return")
+					return
+				}
+			}
+		}
+
+		check(program.Statements)
+		ast.Inspect(program, func(node ast.Node) bool {
+			if block, ok := node.(*ast.BlockStatement); ok {
+				check(block.Statements)
+			}
+			return true
+		})
+	})
+}
+
+// returns reports whether stmt always executes a return or throw statement.
+func returns(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement:
+		return true
+	case *ast.ExpressionStatement:
+		exp, ok := stmt.Expression.(*ast.IfExpression)
+		return ok && blockReturns(exp.Consequence) && blockReturns(exp.Alternative)
+	default:
+		return false
+	}
+}
+
+func blockReturns(block *ast.BlockStatement) bool {
+	if block == nil {
+		return false
+	}
+	for _, stmt := range block.Statements {
+		if returns(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func statementPos(stmt ast.Statement) (pos token.Position) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token.Pos
+	case *ast.ReturnStatement:
+		return stmt.Token.Pos
+	case *ast.ThrowStatement:
+		return stmt.Token.Pos
+	case *ast.ImportStatement:
+		return stmt.Token.Pos
+	case *ast.ExpressionStatement:
+		return stmt.Token.Pos
+	default:
+		return pos
+	}
+}
+
+// SelfComparison returns a rule which reports comparisons of an expression
+// with itself, like x == x, whose result does not depend on the value.
+// Operands are built from identifiers and scalar literals. Calls may return
+// different values, and array, hash and function literals create a new value
+// each time they are evaluated, which is not equal to the other one.
+func SelfComparison() Rule {
+	return NewRule("self-comparison", "Reports comparisons of a value with itself.", func(program *ast.Program, r Reporter) {
+		ast.Inspect(program, func(node ast.Node) bool {
+			exp, ok := node.(*ast.InfixExpression)
+			if !ok || exp.Left == nil || exp.Right == nil || hasNewValues(exp.Left) {
+				return true
+			}
+
+			var result string
+			switch exp.Operator {
+			case "==":
+				result = "true"
+			case "!=", "<", ">":
+				result = "false"
+			default:
+				return true
+			}
+
+			if exp.Left.String() == exp.Right.String() {
+				r.Report(exp.Token.Pos, "comparison of %s with itself is always %s", describe(exp.Left), result)
+			}
+			return true
+		})
+	})
+}
+
+// describe returns the name of an identifier or literal for messages. Other
+// expressions are described generically.
+func describe(exp ast.Expression) string {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		return exp.Value
+	case *ast.IntegerLiteral, *ast.BooleanLiteral:
+		return exp.TokenLiteral()
+	case *ast.StringLiteral:
+		return `"` + exp.Value + `"`
+	default:
+		return "an expression"
+	}
+}
+
+// hasNewValues reports whether exp contains calls or literals which may
+// evaluate to a different value each time.
+func hasNewValues(exp ast.Expression) bool {
+	found := false
+	ast.Inspect(exp, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.CallExpression, *ast.ArrayLiteral, *ast.HashLiteral, *ast.FunctionLiteral:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// ConstantCondition returns a rule which reports if expressions whose
+// condition does not depend on any binding, so that always the same branch
+// is taken.
+func ConstantCondition() Rule {
+	return NewRule("constant-condition", "Reports if conditions which are always true or always false.", func(program *ast.Program, r Reporter) {
+		ast.Inspect(program, func(node ast.Node) bool {
+			exp, ok := node.(*ast.IfExpression)
+			if !ok || exp.Condition == nil || !isConstant(exp.Condition) {
+				return true
+			}
+
+			value := evaluator.Eval(exp.Condition, object.NewEnvironment())
+			if _, failed := value.(*object.Error); failed {
+				return true
+			}
+
+			truthy := value != evaluator.False && value != evaluator.Null
+			r.Report(exp.Token.Pos, "condition is always %t", truthy)
+			return true
+		})
+	})
+}
+
+// isConstant reports whether the value of exp is the same whenever it is
+// evaluated, because it refers to no bindings.
+func isConstant(exp ast.Expression) bool {
+	constant := true
+	ast.Inspect(exp, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.Identifier, *ast.CallExpression, *ast.FunctionLiteral:
+			constant = false
+		}
+		return constant
+	})
+	return constant
+}
+
+// DoubleNegation returns a rule which reports two consecutive ! operators.
+// They convert a value to a boolean, which is rarely needed, as all values
+// can be used as conditions.
+func DoubleNegation() Rule {
+	return NewRule("double-negation", "Reports double negations like !!x.", func(program *ast.Program, r Reporter) {
+		ast.Inspect(program, func(node ast.Node) bool {
+			exp, ok := node.(*ast.PrefixExpression)
+			if !ok || exp.Operator != "!" {
+				return true
+			}
+			if inner, ok := exp.Right.(*ast.PrefixExpression); ok && inner.Operator == "!" {
+				r.Report(exp.Token.Pos, "double negation of %s", describe(inner.Right))
+				// !!!x is reported once.
+				return false
+			}
+			return true
+		})
+	})
+}
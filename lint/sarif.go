@@ -0,0 +1,101 @@
+package lint
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// File contains the diagnostics of a linted file.
+type File struct {
+	// Name is the path of the file as shown to users.
+	Name        string
+	Diagnostics []Diagnostic
+}
+
+// WriteSARIF writes the diagnostics of files to w as a SARIF 2.1.0 log, which
+// is understood by code scanning tools. rules are the rules which have been
+// applied.
+func WriteSARIF(w io.Writer, rules []Rule, files []File) error {
+	run := sarifRun{Results: make([]sarifResult, 0)}
+	run.Tool.Driver.Name = "monkey vet"
+	run.Tool.Driver.Rules = make([]sarifRule, 0, len(rules))
+	for _, rule := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               rule.Name(),
+			ShortDescription: sarifMessage{Text: rule.Doc()},
+		})
+	}
+
+	for _, file := range files {
+		for _, d := range file.Diagnostics {
+			var loc sarifLocation
+			loc.PhysicalLocation.ArtifactLocation.URI = file.Name
+			loc.PhysicalLocation.Region = sarifRegion{StartLine: d.Pos.Line, StartColumn: d.Pos.Column}
+
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    d.Rule,
+				Level:     "warning",
+				Message:   sarifMessage{Text: d.Msg},
+				Locations: []sarifLocation{loc},
+			})
+		}
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// The following types are the subset of the SARIF format written by WriteSARIF.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name  string      `json:"name"`
+			Rules []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region sarifRegion `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fabiante/monkeylang/lsp"
+	"os"
+)
+
+// lspCmd runs a language server which communicates with an editor over
+// stdin and stdout.
+func lspCmd(args []string) int {
+	if len(args) != 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: monkey lsp")
+		return exitUsage
+	}
+
+	if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,237 @@
+package lsp
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/parser"
+	"github.com/fabiante/monkeylang/resolver"
+	"github.com/fabiante/monkeylang/token"
+	"github.com/fabiante/monkeylang/typecheck"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is an open text document with the results of its analysis.
+type document struct {
+	uri string
+	source
+
+	// file is kept to parse the document incrementally after changes.
+	file      *parser.File
+	program   *ast.Program
+	parseErrs []*parser.Error
+	// resolveErrs and warnings are only set if the document has no syntax errors.
+	resolveErrs []*resolver.Error
+	warnings    []*resolver.Error
+
+	// declarations maps identifiers to the identifiers declaring them.
+	declarations map[*ast.Identifier]*ast.Identifier
+	types        *typecheck.Info
+}
+
+func newDocument(uri string, file *parser.File) *document {
+	d := &document{
+		uri:       uri,
+		source:    newSource(file.Input),
+		file:      file,
+		program:   file.Program,
+		parseErrs: file.Errors,
+	}
+
+	// The program is analyzed even if it contains syntax errors, so that
+	// the parts which could be parsed can still be navigated.
+	d.declarations = resolver.Declarations(d.program)
+	d.types, _ = typecheck.Check(d.program)
+	if len(d.parseErrs) == 0 {
+		d.resolveErrs, d.warnings = resolver.Resolve(d.program)
+	}
+
+	return d
+}
+
+// diagnostics returns the syntax errors of the document or, if there are
+// none, the errors and warnings of the resolver.
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+	for _, err := range d.parseErrs {
+		diagnostics = append(diagnostics, d.diagnostic(err.Pos, SeverityError, "parser", err.Msg))
+	}
+	for _, err := range d.resolveErrs {
+		diagnostics = append(diagnostics, d.diagnostic(err.Pos, SeverityError, "resolver", err.Msg))
+	}
+	for _, w := range d.warnings {
+		diagnostics = append(diagnostics, d.diagnostic(w.Pos, SeverityWarning, "resolver", w.Msg))
+	}
+	return diagnostics
+}
+
+// diagnostic creates a diagnostic spanning the word at pos, or a single
+// character if there is none.
+func (d *document) diagnostic(pos token.Position, severity int, source, msg string) Diagnostic {
+	end := pos.Offset
+	for end < len(d.text) && isWordChar(d.text[end]) {
+		end++
+	}
+	if end == pos.Offset && end < len(d.text) {
+		_, size := utf8.DecodeRuneInString(d.text[end:])
+		end += size
+	}
+
+	return Diagnostic{
+		Range:    Range{Start: d.position(pos.Offset), End: d.position(end)},
+		Severity: severity,
+		Source:   source,
+		Message:  msg,
+	}
+}
+
+// utf16Len returns the number of UTF-16 code units encoding r.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// source is a text with the offsets of its lines, which converts between
+// byte offsets and LSP positions.
+type source struct {
+	text string
+	// lines contains the offset of the first byte of each line.
+	lines []int
+}
+
+func newSource(text string) source {
+	s := source{text: text, lines: []int{0}}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			s.lines = append(s.lines, i+1)
+		}
+	}
+	return s
+}
+
+// position converts a byte offset into an LSP position.
+func (src source) position(offset int) Position {
+	offset = min(max(offset, 0), len(src.text))
+	line := sort.Search(len(src.lines), func(i int) bool { return src.lines[i] > offset }) - 1
+
+	character := 0
+	for _, r := range src.text[src.lines[line]:offset] {
+		character += utf16Len(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+// offset converts an LSP position into a byte offset. Positions beyond the
+// end of a line refer to its end.
+func (src source) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(src.lines) {
+		return len(src.text)
+	}
+
+	start := src.lines[pos.Line]
+	line := src.text[start:]
+	if end := strings.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+
+	character := 0
+	for i, r := range line {
+		if character >= pos.Character {
+			return start + i
+		}
+		character += utf16Len(r)
+	}
+	return start + len(line)
+}
+
+// identRange returns the range of an identifier.
+func (d *document) identRange(ident *ast.Identifier) Range {
+	return Range{
+		Start: d.position(ident.Token.Pos.Offset),
+		End:   d.position(ident.Token.Pos.Offset + len(ident.Value)),
+	}
+}
+
+// identifierAt returns the identifier at the given offset, including the
+// offset right after it, or nil.
+func (d *document) identifierAt(offset int) *ast.Identifier {
+	var found *ast.Identifier
+	ast.Inspect(d.program, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			start := ident.Token.Pos.Offset
+			if start <= offset && offset <= start+len(ident.Value) {
+				found = ident
+			}
+		}
+		return found == nil
+	})
+	return found
+}
+
+// declaration returns the identifier declaring the binding ident refers to.
+// Declaring identifiers are their own declaration. It returns nil for
+// builtins and undefined names.
+func (d *document) declaration(ident *ast.Identifier) *ast.Identifier {
+	if decl, ok := d.declarations[ident]; ok {
+		return decl
+	}
+	if d.isDeclaration(ident) {
+		return ident
+	}
+	return nil
+}
+
+func (d *document) isDeclaration(ident *ast.Identifier) bool {
+	found := false
+	ast.Inspect(d.program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			found = found || node.Name == ident
+		case *ast.ImportStatement:
+			found = found || node.Name == ident
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				found = found || param == ident
+			}
+		case *ast.TryExpression:
+			found = found || node.Parameter == ident
+		}
+		return !found
+	})
+	return found
+}
+
+// references returns the identifiers referring to decl in source order.
+func (d *document) references(decl *ast.Identifier) []*ast.Identifier {
+	var refs []*ast.Identifier
+	for ident, declaration := range d.declarations {
+		if declaration == decl {
+			refs = append(refs, ident)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Token.Pos.Offset < refs[j].Token.Pos.Offset
+	})
+	return refs
+}
+
+// line returns the text of the given zero-based line without surrounding whitespace.
+func (src source) line(line int) string {
+	if line < 0 || line >= len(src.lines) {
+		return ""
+	}
+	text := src.text[src.lines[line]:]
+	if end := strings.IndexByte(text, '\n'); end >= 0 {
+		text = text[:end]
+	}
+	return strings.TrimSpace(text)
+}
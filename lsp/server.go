@@ -0,0 +1,376 @@
+// Package lsp implements a Language Server Protocol server for Monkey.
+//
+// The server communicates with an editor over a byte stream, usually stdin
+// and stdout, using JSON-RPC messages. It supports diagnostics for syntax
+// errors and undefined names, hover information, go to definition, find
+// references, the symbols of a document, formatting and semantic tokens for
+// syntax highlighting.
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/highlight"
+	"github.com/fabiante/monkeylang/parser"
+	"github.com/fabiante/monkeylang/printer"
+	"io"
+	"strings"
+)
+
+// Serve runs a server which reads messages from r and writes messages to w.
+// It returns when the client sends the exit notification or r ends.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &server{
+		out:  w,
+		docs: make(map[string]*document),
+	}
+
+	in := bufio.NewReader(r)
+	for {
+		content, err := readMessage(in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(content, &req); err != nil {
+			if err := s.respond(json.RawMessage("null"), nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			return nil
+		}
+
+		if err := s.handle(&req); err != nil {
+			return err
+		}
+	}
+}
+
+type server struct {
+	out  io.Writer
+	docs map[string]*document
+}
+
+// handler handles a request or notification. The result is ignored for
+// notifications.
+type handler func(s *server, params json.RawMessage) (any, error)
+
+var handlers = map[string]handler{
+	"initialize":                       (*server).initialize,
+	"initialized":                      ignore,
+	"shutdown":                         ignore,
+	"textDocument/didOpen":             (*server).didOpen,
+	"textDocument/didChange":           (*server).didChange,
+	"textDocument/didClose":            (*server).didClose,
+	"textDocument/hover":               (*server).hover,
+	"textDocument/definition":          (*server).definition,
+	"textDocument/references":          (*server).references,
+	"textDocument/documentSymbol":      (*server).documentSymbol,
+	"textDocument/formatting":          (*server).formatting,
+	"textDocument/semanticTokens/full": (*server).semanticTokens,
+}
+
+func ignore(*server, json.RawMessage) (any, error) {
+	return nil, nil
+}
+
+func (s *server) handle(req *request) error {
+	isNotification := len(req.ID) == 0
+
+	h, ok := handlers[req.Method]
+	if !ok {
+		if isNotification {
+			return nil
+		}
+		return s.respond(req.ID, nil, &responseError{Code: codeMethodNotFound, Message: "unsupported method " + req.Method})
+	}
+
+	result, err := h(s, req.Params)
+	if isNotification {
+		return nil
+	}
+
+	var respErr *responseError
+	if err != nil && !errors.As(err, &respErr) {
+		respErr = &responseError{Code: codeInternalError, Message: err.Error()}
+	}
+	return s.respond(req.ID, result, respErr)
+}
+
+func (s *server) respond(id json.RawMessage, result any, respErr *responseError) error {
+	resp := response{JSONRPC: "2.0", ID: id, Error: respErr}
+	if respErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resp.Result = data
+	}
+	return writeMessage(s.out, resp)
+}
+
+func (s *server) notify(method string, params any) error {
+	return writeMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// decode unmarshals the params of a request.
+func decode[T any](params json.RawMessage) (*T, error) {
+	var v T
+	if err := json.Unmarshal(params, &v); err != nil {
+		return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return &v, nil
+}
+
+// document returns the open document with the given URI.
+func (s *server) document(uri string) (*document, error) {
+	doc, ok := s.docs[uri]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("document %s is not open", uri)}
+	}
+	return doc, nil
+}
+
+func (s *server) initialize(json.RawMessage) (any, error) {
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           TextDocumentSyncIncremental,
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			DocumentSymbolProvider:     true,
+			DocumentFormattingProvider: true,
+			SemanticTokensProvider: SemanticTokensOptions{
+				Legend: SemanticTokensLegend{TokenTypes: highlight.SemanticTokenTypes, TokenModifiers: []string{}},
+				Full:   true,
+			},
+		},
+		ServerInfo: ServerInfo{Name: "monkey"},
+	}, nil
+}
+
+func (s *server) didOpen(params json.RawMessage) (any, error) {
+	p, err := decode[DidOpenTextDocumentParams](params)
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.update(p.TextDocument.URI, parser.ParseFile(p.TextDocument.Text))
+}
+
+func (s *server) didChange(params json.RawMessage) (any, error) {
+	p, err := decode[DidChangeTextDocumentParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	// Changes with a range are parsed incrementally. Each change applies
+	// to the text resulting from the previous one.
+	file, src := doc.file, doc.source
+	for i, change := range p.ContentChanges {
+		if change.Range == nil {
+			file = parser.ParseFile(change.Text)
+		} else {
+			file = file.Update(parser.Edit{
+				Start: src.offset(change.Range.Start),
+				End:   src.offset(change.Range.End),
+				Text:  change.Text,
+			})
+		}
+		if i < len(p.ContentChanges)-1 {
+			src = newSource(file.Input)
+		}
+	}
+	return nil, s.update(p.TextDocument.URI, file)
+}
+
+// update analyzes the new version of a document and publishes its diagnostics.
+func (s *server) update(uri string, file *parser.File) error {
+	doc := newDocument(uri, file)
+	s.docs[uri] = doc
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: doc.diagnostics()})
+}
+
+func (s *server) didClose(params json.RawMessage) (any, error) {
+	p, err := decode[DidCloseTextDocumentParams](params)
+	if err != nil {
+		return nil, err
+	}
+	delete(s.docs, p.TextDocument.URI)
+	return nil, s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+}
+
+// identifierAt returns the document and the identifier at the position of
+// a request. The identifier is nil if there is none.
+func (s *server) identifierAt(p *TextDocumentPositionParams) (*document, *ast.Identifier, error) {
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, doc.identifierAt(doc.offset(p.Position)), nil
+}
+
+// hover shows the source line declaring the binding of an identifier and
+// its inferred type.
+func (s *server) hover(params json.RawMessage) (any, error) {
+	p, err := decode[TextDocumentPositionParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, ident, err := s.identifierAt(p)
+	if err != nil || ident == nil {
+		return nil, err
+	}
+
+	var value strings.Builder
+	if decl := doc.declaration(ident); decl != nil {
+		value.WriteString("```monkey\n")
+		value.WriteString(doc.line(decl.Token.Pos.Line - 1))
+		value.WriteString("\n```")
+		if t := doc.types.TypeOf(decl); t != nil {
+			fmt.Fprintf(&value, "\n\n%s: `%s`", ident.Value, t)
+		}
+	} else if ident.Binding != nil && ident.Binding.Builtin {
+		fmt.Fprintf(&value, "builtin function `%s`", ident.Value)
+	} else {
+		return nil, nil
+	}
+
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: value.String()},
+		Range:    doc.identRange(ident),
+	}, nil
+}
+
+func (s *server) definition(params json.RawMessage) (any, error) {
+	p, err := decode[TextDocumentPositionParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, ident, err := s.identifierAt(p)
+	if err != nil || ident == nil {
+		return nil, err
+	}
+
+	decl := doc.declaration(ident)
+	if decl == nil {
+		return nil, nil
+	}
+	return []Location{{URI: doc.uri, Range: doc.identRange(decl)}}, nil
+}
+
+func (s *server) references(params json.RawMessage) (any, error) {
+	p, err := decode[ReferenceParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, ident, err := s.identifierAt(&p.TextDocumentPositionParams)
+	if err != nil || ident == nil {
+		return nil, err
+	}
+
+	locations := make([]Location, 0)
+	decl := doc.declaration(ident)
+	if decl == nil {
+		return locations, nil
+	}
+
+	if p.Context.IncludeDeclaration {
+		locations = append(locations, Location{URI: doc.uri, Range: doc.identRange(decl)})
+	}
+	for _, ref := range doc.references(decl) {
+		locations = append(locations, Location{URI: doc.uri, Range: doc.identRange(ref)})
+	}
+	return locations, nil
+}
+
+// documentSymbol returns the let statements at the top level of a document.
+func (s *server) documentSymbol(params json.RawMessage) (any, error) {
+	p, err := decode[DocumentParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := make([]DocumentSymbol, 0)
+	for _, stmt := range doc.program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || let.Name == nil {
+			continue
+		}
+
+		symbol := DocumentSymbol{
+			Name:           let.Name.Value,
+			Kind:           SymbolKindVariable,
+			Range:          Range{Start: doc.position(let.Token.Pos.Offset), End: doc.position(let.End().Offset)},
+			SelectionRange: doc.identRange(let.Name),
+		}
+		if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+			symbol.Kind = SymbolKindFunction
+		}
+		if t := doc.types.TypeOf(let.Name); t != nil {
+			symbol.Detail = t.String()
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+// formatting replaces the text of a document by its canonical formatting.
+// Documents with syntax errors are not formatted.
+func (s *server) formatting(params json.RawMessage) (any, error) {
+	p, err := decode[DocumentParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	edits := make([]TextEdit, 0)
+	if len(doc.parseErrs) > 0 {
+		return edits, nil
+	}
+
+	var out bytes.Buffer
+	if err := printer.Fprint(&out, doc.program); err != nil {
+		return nil, err
+	}
+	if out.String() != doc.text {
+		edits = append(edits, TextEdit{
+			Range:   Range{End: doc.position(len(doc.text))},
+			NewText: out.String(),
+		})
+	}
+	return edits, nil
+}
+
+func (s *server) semanticTokens(params json.RawMessage) (any, error) {
+	p, err := decode[DocumentParams](params)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return SemanticTokens{Data: highlight.SemanticTokens(doc.text)}, nil
+}
@@ -0,0 +1,226 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+const uri = "file:///test.mk"
+
+// session sends the given messages to a server and returns the messages it
+// wrote in response.
+func session(t *testing.T, messages ...map[string]any) []map[string]any {
+	t.Helper()
+
+	var in bytes.Buffer
+	for _, msg := range messages {
+		msg["jsonrpc"] = "2.0"
+		require.NoError(t, writeMessage(&in, msg))
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, Serve(&in, &out))
+
+	var responses []map[string]any
+	r := bufio.NewReader(&out)
+	for {
+		content, err := readMessage(r)
+		if errors.Is(err, io.EOF) {
+			return responses
+		}
+		require.NoError(t, err)
+
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(content, &resp))
+		responses = append(responses, resp)
+	}
+}
+
+func open(text string) map[string]any {
+	return map[string]any{
+		"method": "textDocument/didOpen",
+		"params": map[string]any{"textDocument": map[string]any{"uri": uri, "languageId": "monkey", "version": 1, "text": text}},
+	}
+}
+
+func call(id int, method string, line, character int) map[string]any {
+	return map[string]any{
+		"id":     id,
+		"method": method,
+		"params": map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"position":     map[string]any{"line": line, "character": character},
+			"context":      map[string]any{"includeDeclaration": true},
+		},
+	}
+}
+
+// result runs a session opening text and sending req, and returns the
+// result of req as JSON.
+func result(t *testing.T, text string, req map[string]any) string {
+	t.Helper()
+
+	responses := session(t, open(text), req)
+	require.Len(t, responses, 2)
+	require.Nil(t, responses[1]["error"])
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	require.NoError(t, enc.Encode(responses[1]["result"]))
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+func rng(startLine, startChar, endLine, endChar int) string {
+	return fmt.Sprintf(`{"end":{"character":%d,"line":%d},"start":{"character":%d,"line":%d}}`, endChar, endLine, startChar, startLine)
+}
+
+func TestServe(t *testing.T) {
+	t.Run("initialize", func(t *testing.T) {
+		responses := session(t,
+			map[string]any{"id": 1, "method": "initialize", "params": map[string]any{}},
+			map[string]any{"method": "initialized", "params": map[string]any{}},
+			map[string]any{"id": 2, "method": "unknown"},
+			map[string]any{"id": 3, "method": "shutdown"},
+			map[string]any{"method": "exit"},
+			map[string]any{"id": 4, "method": "shutdown"},
+		)
+
+		require.Len(t, responses, 3)
+		assert.EqualValues(t, 1, responses[0]["id"])
+		assert.Equal(t, map[string]any{"name": "monkey"}, responses[0]["result"].(map[string]any)["serverInfo"])
+		assert.Equal(t, true, responses[0]["result"].(map[string]any)["capabilities"].(map[string]any)["hoverProvider"])
+		assert.EqualValues(t, codeMethodNotFound, responses[1]["error"].(map[string]any)["code"])
+		assert.Contains(t, responses[2], "result")
+		assert.Nil(t, responses[2]["result"])
+	})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		responses := session(t,
+			open("let a = ;\nb"),
+			map[string]any{
+				"method": "textDocument/didChange",
+				"params": map[string]any{
+					"textDocument":   map[string]any{"uri": uri},
+					"contentChanges": []any{map[string]any{"text": "let a = 1;\nlet f = fn(a) { b };"}},
+				},
+			},
+			map[string]any{"method": "textDocument/didClose", "params": map[string]any{"textDocument": map[string]any{"uri": uri}}},
+		)
+
+		require.Len(t, responses, 3)
+		var diagnostics []string
+		for _, resp := range responses {
+			assert.Equal(t, "textDocument/publishDiagnostics", resp["method"])
+			data, err := json.Marshal(resp["params"].(map[string]any)["diagnostics"])
+			require.NoError(t, err)
+			diagnostics = append(diagnostics, string(data))
+		}
+
+		assert.Equal(t, `[{"message":"no prefix parse fn for Semicolon","range":`+rng(0, 8, 0, 9)+`,"severity":1,"source":"parser"}]`, diagnostics[0])
+		assert.Equal(t, `[`+
+			`{"message":"undefined variable b","range":`+rng(1, 16, 1, 17)+`,"severity":1,"source":"resolver"},`+
+			`{"message":"declaration of a shadows declaration at 1:5","range":`+rng(1, 11, 1, 12)+`,"severity":2,"source":"resolver"}]`, diagnostics[1])
+		assert.Equal(t, `[]`, diagnostics[2])
+	})
+
+	t.Run("incremental changes", func(t *testing.T) {
+		span := func(startLine, startChar, endLine, endChar int) map[string]any {
+			return map[string]any{
+				"start": map[string]any{"line": startLine, "character": startChar},
+				"end":   map[string]any{"line": endLine, "character": endChar},
+			}
+		}
+		responses := session(t,
+			open("let a = 1;\nlet b = a;\nb;"),
+			map[string]any{
+				"method": "textDocument/didChange",
+				"params": map[string]any{
+					"textDocument": map[string]any{"uri": uri},
+					"contentChanges": []any{
+						map[string]any{"range": span(1, 8, 1, 9), "text": "c + 1"},
+						map[string]any{"range": span(0, 0, 0, 0), "text": "// x\n"},
+					},
+				},
+			},
+			call(1, "textDocument/definition", 3, 0),
+		)
+
+		require.Len(t, responses, 3)
+		data, err := json.Marshal(responses[1]["params"].(map[string]any)["diagnostics"])
+		require.NoError(t, err)
+		assert.Equal(t, `[{"message":"undefined variable c","range":`+rng(2, 8, 2, 9)+`,"severity":1,"source":"resolver"}]`, string(data))
+		data, err = json.Marshal(responses[2]["result"])
+		require.NoError(t, err)
+		assert.Equal(t, `[{"range":`+rng(2, 4, 2, 5)+`,"uri":"`+uri+`"}]`, string(data))
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		text := "let add = fn(a, b) {\n  a * b\n};\nadd(1, len(\"x\"));"
+
+		assert.Equal(t, `{"contents":{"kind":"markdown","value":"`+"```monkey\\nlet add = fn(a, b) {\\n```\\n\\nadd: `fn(int, int) -> int`"+`"},"range":`+rng(3, 0, 3, 3)+`}`,
+			result(t, text, call(1, "textDocument/hover", 3, 1)))
+		assert.Equal(t, `{"contents":{"kind":"markdown","value":"`+"```monkey\\nlet add = fn(a, b) {\\n```\\n\\na: `int`"+`"},"range":`+rng(1, 2, 1, 3)+`}`,
+			result(t, text, call(1, "textDocument/hover", 1, 3)))
+		assert.Equal(t, `{"contents":{"kind":"markdown","value":"builtin function `+"`len`"+`"},"range":`+rng(3, 7, 3, 10)+`}`,
+			result(t, text, call(1, "textDocument/hover", 3, 8)))
+		assert.Equal(t, `null`, result(t, text, call(1, "textDocument/hover", 3, 5)))
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		text := "let a = 1;\nlet f = fn(a) {\n  a\n};\na + f(a);"
+
+		assert.Equal(t, `[{"range":`+rng(1, 11, 1, 12)+`,"uri":"`+uri+`"}]`, result(t, text, call(1, "textDocument/definition", 2, 2)))
+		assert.Equal(t, `[{"range":`+rng(0, 4, 0, 5)+`,"uri":"`+uri+`"}]`, result(t, text, call(1, "textDocument/definition", 4, 6)))
+		assert.Equal(t, `[{"range":`+rng(0, 4, 0, 5)+`,"uri":"`+uri+`"}]`, result(t, text, call(1, "textDocument/definition", 0, 4)))
+		assert.Equal(t, `null`, result(t, text, call(1, "textDocument/definition", 4, 2)))
+	})
+
+	t.Run("references", func(t *testing.T) {
+		text := "let a = 1;\nlet f = fn(a) {\n  a\n};\na + f(a);"
+
+		assert.Equal(t, `[`+
+			`{"range":`+rng(0, 4, 0, 5)+`,"uri":"`+uri+`"},`+
+			`{"range":`+rng(4, 0, 4, 1)+`,"uri":"`+uri+`"},`+
+			`{"range":`+rng(4, 6, 4, 7)+`,"uri":"`+uri+`"}]`, result(t, text, call(1, "textDocument/references", 4, 0)))
+		assert.Equal(t, `[`+
+			`{"range":`+rng(1, 11, 1, 12)+`,"uri":"`+uri+`"},`+
+			`{"range":`+rng(2, 2, 2, 3)+`,"uri":"`+uri+`"}]`, result(t, text, call(1, "textDocument/references", 1, 11)))
+	})
+
+	t.Run("document symbols", func(t *testing.T) {
+		text := "let a = \"x\";\nlet f = fn(x) {\n  x * 2\n};\nf(1);"
+
+		assert.Equal(t, `[`+
+			`{"detail":"string","kind":13,"name":"a","range":`+rng(0, 0, 0, 12)+`,"selectionRange":`+rng(0, 4, 0, 5)+`},`+
+			`{"detail":"fn(int) -> int","kind":12,"name":"f","range":`+rng(1, 0, 3, 2)+`,"selectionRange":`+rng(1, 4, 1, 5)+`}]`,
+			result(t, text, call(1, "textDocument/documentSymbol", 0, 0)))
+	})
+
+	t.Run("formatting", func(t *testing.T) {
+		assert.Equal(t, `[{"newText":"let a = 1;\n","range":`+rng(0, 0, 0, 12)+`}]`,
+			result(t, "let   a = 1;", call(1, "textDocument/formatting", 0, 0)))
+		assert.Equal(t, `[]`, result(t, "let a = 1;\n", call(1, "textDocument/formatting", 0, 0)))
+		assert.Equal(t, `[]`, result(t, "let a = ;", call(1, "textDocument/formatting", 0, 0)))
+	})
+
+	t.Run("semantic tokens", func(t *testing.T) {
+		assert.Equal(t, `{"data":[0,0,3,0,0,0,4,1,1,0,0,2,1,4,0,0,2,1,2,0]}`,
+			result(t, "let a = 1;", call(1, "textDocument/semanticTokens/full", 0, 0)))
+	})
+
+	t.Run("document not open", func(t *testing.T) {
+		responses := session(t, call(1, "textDocument/hover", 0, 0))
+
+		require.Len(t, responses, 1)
+		assert.EqualValues(t, codeInvalidParams, responses[0]["error"].(map[string]any)["code"])
+	})
+}
@@ -1,10 +1,76 @@
 package main
 
 import (
+	"fmt"
 	"github.com/fabiante/monkeylang/repl"
 	"os"
+	"sort"
 )
 
+// Exit codes of the monkey command.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// command is a subcommand of the monkey command like "monkey run".
+//
+// run receives the arguments following the command name and returns the exit code.
+type command struct {
+	usage string
+	run   func(args []string) int
+}
+
+var commands = map[string]command{
+	"build":     {usage: "build [-O] [-o out.mkc] <file | ->", run: buildCmd},
+	"check":     {usage: "check [--types] <file | ->", run: checkCmd},
+	"disasm":    {usage: "disasm [-O] <file | ->", run: disasmCmd},
+	"fmt":       {usage: "fmt [-w] [files...]", run: fmtCmd},
+	"highlight": {usage: "highlight [--format=ansi|html] <file | ->", run: highlightCmd},
+	"lsp":       {usage: "lsp", run: lspCmd},
+	"parse":     {usage: "parse [--json] [--trace] <file | ->", run: parseCmd},
+	"run":       {usage: "run [-O] [--check] [--engine=vm|eval] <file | ->", run: runCmd},
+	"tokens":    {usage: "tokens [--format=table|json] <file | ->", run: tokensCmd},
+	"vet":       {usage: "vet [--config file] [--format=text|sarif] [files...]", run: vetCmd},
+}
+
 func main() {
-	repl.Start(os.Stdin, os.Stdout)
+	if len(os.Args) < 2 {
+		repl.Start(os.Stdin, os.Stdout, colorOutput(os.Stdout))
+		return
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		printUsage()
+		os.Exit(exitUsage)
+	}
+
+	os.Exit(cmd.run(os.Args[2:]))
+}
+
+// colorOutput reports whether f is a terminal which should get colored output.
+// Colors are disabled by setting the NO_COLOR environment variable.
+func colorOutput(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func printUsage() {
+	_, _ = fmt.Fprintln(os.Stderr, "usage: monkey [command]")
+	_, _ = fmt.Fprintln(os.Stderr, "\nWithout a command, an interactive REPL is started.\n\ncommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		_, _ = fmt.Fprintf(os.Stderr, "  monkey %s\n", commands[name].usage)
+	}
 }
@@ -0,0 +1,392 @@
+// Package mkc reads and writes compiled programs in the .mkc format.
+//
+// A file starts with the Magic bytes followed by the format Version as a big
+// endian uint16. The rest of the file contains the constant pool and the
+// instructions and line table of the program:
+//
+//	file      = magic version constants function
+//	constants = count constant*
+//	constant  = tag (integer | string | function)
+//	function  = name file source numLocals numParameters instructions lines
+//	lines     = count (offset line column)*
+//
+// Integers are encoded as varints, counts, lengths and the fields of
+// functions as unsigned varints. Strings and instructions are prefixed by
+// their length. The offsets of a line table are stored as the difference to
+// the previous offset. Compiled functions, including nested ones, are stored
+// in the constant pool like any other constant.
+//
+// Read verifies the instructions of all functions, so that damaged files are
+// rejected instead of making the virtual machine fail while they run.
+package mkc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/fabiante/monkeylang/code"
+	"github.com/fabiante/monkeylang/compiler"
+	"github.com/fabiante/monkeylang/object"
+	"io"
+	"math"
+)
+
+// Magic identifies .mkc files.
+const Magic = "\x89MKC"
+
+// Version is the version of the format written by this package. It must be
+// incremented whenever the encoding or the instruction set changes, so that
+// files are never executed by a virtual machine they were not compiled for.
+const Version uint16 = 6
+
+// readChunk is the number of bytes which are allocated at once for reading
+// strings and instructions.
+const readChunk = 4096
+
+// Tags of the constants in the constant pool.
+const (
+	tagInteger  byte = 1
+	tagString   byte = 2
+	tagFunction byte = 3
+)
+
+// ErrNotCompiled is returned by Read if the data does not start with Magic.
+var ErrNotCompiled = errors.New("not a compiled monkey program")
+
+// VersionError is returned by Read if the file has been written with another
+// version of the format.
+type VersionError struct {
+	Version uint16
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("compiled program has format version %d, but version %d is required: recompile it with monkey build", e.Version, Version)
+}
+
+// IsCompiled reports whether data starts with the magic bytes of .mkc files.
+func IsCompiled(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Magic))
+}
+
+// Write encodes bytecode to w.
+func Write(w io.Writer, bytecode *compiler.Bytecode) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+
+	e.bytes([]byte(Magic))
+	var version [2]byte
+	binary.BigEndian.PutUint16(version[:], Version)
+	e.bytes(version[:])
+
+	e.uvarint(len(bytecode.Constants))
+	for _, constant := range bytecode.Constants {
+		e.constant(constant)
+	}
+
+	e.function(&object.CompiledFunction{Instructions: bytecode.Instructions, Lines: bytecode.Lines, File: bytecode.File})
+
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// Read decodes bytecode written by Write.
+func Read(r io.Reader) (*compiler.Bytecode, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+
+	magic := make([]byte, len(Magic))
+	if _, err := io.ReadFull(d.r, magic); err != nil || string(magic) != Magic {
+		return nil, ErrNotCompiled
+	}
+
+	var version [2]byte
+	if _, err := io.ReadFull(d.r, version[:]); err != nil {
+		return nil, fmt.Errorf("reading version: %w", err)
+	}
+	if v := binary.BigEndian.Uint16(version[:]); v != Version {
+		return nil, &VersionError{Version: v}
+	}
+
+	count := d.length()
+	constants := make([]object.Object, 0, min(count, 1024))
+	for i := 0; i < count && d.err == nil; i++ {
+		constants = append(constants, d.constant())
+	}
+
+	main := d.function()
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid compiled program: %w", d.err)
+	}
+
+	if err := verify(main, constants); err != nil {
+		return nil, fmt.Errorf("invalid compiled program: main program: %w", err)
+	}
+	for _, constant := range constants {
+		fn, ok := constant.(*object.CompiledFunction)
+		if !ok {
+			continue
+		}
+		if err := verify(fn, constants); err != nil {
+			name := "anonymous function"
+			if fn.Name != "" {
+				name = "function " + fn.Name
+			}
+			return nil, fmt.Errorf("invalid compiled program: %s: %w", name, err)
+		}
+	}
+
+	return &compiler.Bytecode{
+		Instructions: main.Instructions,
+		Constants:    constants,
+		Lines:        main.Lines,
+		File:         main.File,
+	}, nil
+}
+
+// encoder writes the elements of the format. After the first error, all
+// writes are ignored.
+type encoder struct {
+	w   *bufio.Writer
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) bytes(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *encoder) uvarint(v int) {
+	n := binary.PutUvarint(e.buf[:], uint64(v))
+	e.bytes(e.buf[:n])
+}
+
+func (e *encoder) varint(v int64) {
+	n := binary.PutVarint(e.buf[:], v)
+	e.bytes(e.buf[:n])
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(len(s))
+	e.bytes([]byte(s))
+}
+
+func (e *encoder) constant(constant object.Object) {
+	switch constant := constant.(type) {
+	case *object.Integer:
+		e.bytes([]byte{tagInteger})
+		e.varint(constant.Value)
+	case *object.String:
+		e.bytes([]byte{tagString})
+		e.string(constant.Value)
+	case *object.CompiledFunction:
+		e.bytes([]byte{tagFunction})
+		e.function(constant)
+	default:
+		if e.err == nil {
+			e.err = fmt.Errorf("unsupported constant type %s", constant.Type())
+		}
+	}
+}
+
+func (e *encoder) function(fn *object.CompiledFunction) {
+	e.string(fn.Name)
+	e.string(fn.File)
+	e.string(fn.Source)
+	e.uvarint(fn.NumLocals)
+	e.uvarint(fn.NumParameters)
+
+	e.uvarint(len(fn.Instructions))
+	e.bytes(fn.Instructions)
+
+	e.uvarint(len(fn.Lines))
+	offset := 0
+	for _, entry := range fn.Lines {
+		e.uvarint(entry.Offset - offset)
+		e.uvarint(entry.Line)
+		e.uvarint(entry.Column)
+		offset = entry.Offset
+	}
+}
+
+// decoder reads the elements of the format. After the first error, all
+// reads return zero values.
+type decoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.err = unexpectedEOF(err)
+	}
+	return v
+}
+
+// length reads an unsigned varint which is used as a count or size.
+func (d *decoder) length() int {
+	v := d.uvarint()
+	if v > math.MaxInt32 {
+		d.fail("length %d out of range", v)
+		return 0
+	}
+	return int(v)
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	if err != nil {
+		d.err = unexpectedEOF(err)
+	}
+	return v
+}
+
+// bytes reads n bytes. They are read in chunks rather than allocated at once,
+// so that a damaged length cannot allocate more memory than the file holds.
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	b := bytes.NewBuffer(make([]byte, 0, min(n, readChunk)))
+	if _, err := io.CopyN(b, d.r, int64(n)); err != nil {
+		d.err = unexpectedEOF(err)
+		return nil
+	}
+	return b.Bytes()
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes(d.length()))
+}
+
+func (d *decoder) constant() object.Object {
+	tag := d.bytes(1)
+	if d.err != nil {
+		return nil
+	}
+
+	switch tag[0] {
+	case tagInteger:
+		return &object.Integer{Value: d.varint()}
+	case tagString:
+		return &object.String{Value: d.string()}
+	case tagFunction:
+		return d.function()
+	default:
+		d.fail("unknown constant tag %d", tag[0])
+		return nil
+	}
+}
+
+func (d *decoder) function() *object.CompiledFunction {
+	fn := &object.CompiledFunction{
+		Name:          d.string(),
+		File:          d.string(),
+		Source:        d.string(),
+		NumLocals:     d.length(),
+		NumParameters: d.length(),
+	}
+	fn.Instructions = d.bytes(d.length())
+
+	count := d.length()
+	offset := 0
+	for i := 0; i < count && d.err == nil; i++ {
+		offset += d.length()
+		fn.Lines = append(fn.Lines, code.LineEntry{Offset: offset, Line: d.length(), Column: d.length()})
+	}
+
+	return fn
+}
+
+// verify checks that the instructions of fn can be executed: Each opcode must
+// be defined and followed by its operands, jumps must lead to the start of an
+// instruction and indexes must refer to existing locals, builtins and
+// constants of the right type. Global indexes always fit into the globals of
+// the virtual machine.
+func verify(fn *object.CompiledFunction, constants []object.Object) error {
+	if fn.NumParameters > fn.NumLocals {
+		return fmt.Errorf("%d parameters exceed %d locals", fn.NumParameters, fn.NumLocals)
+	}
+
+	ins := fn.Instructions
+	starts := make(map[int]bool)
+	var jumps []int
+
+	for i := 0; i < len(ins); {
+		starts[i] = true
+
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			return fmt.Errorf("offset %d: %w", i, err)
+		}
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if i+1+width > len(ins) {
+			return fmt.Errorf("offset %d: incomplete operands of %s", i, def.Name)
+		}
+		operands, _ := code.ReadOperands(def, ins[i+1:])
+
+		var invalid bool
+		switch code.Opcode(ins[i]) {
+		case code.OpConstant:
+			invalid = operands[0] >= len(constants)
+		case code.OpClosure, code.OpImport:
+			_, ok := constantAt(constants, operands[0]).(*object.CompiledFunction)
+			invalid = !ok
+		case code.OpMember:
+			_, ok := constantAt(constants, operands[0]).(*object.String)
+			invalid = !ok
+		case code.OpGetLocal, code.OpSetLocal:
+			invalid = operands[0] >= fn.NumLocals
+		case code.OpGetBuiltin:
+			invalid = operands[0] >= len(object.Builtins)
+		case code.OpJump, code.OpJumpNotTruthy, code.OpTry:
+			jumps = append(jumps, operands[0])
+		}
+		if invalid {
+			return fmt.Errorf("offset %d: invalid operand of %s", i, code.FormatInstruction(def, operands))
+		}
+
+		i += 1 + width
+	}
+
+	for _, target := range jumps {
+		if target != len(ins) && !starts[target] {
+			return fmt.Errorf("jump to offset %d, which is not an instruction", target)
+		}
+	}
+	return nil
+}
+
+// constantAt returns the constant with the given index or nil if there is none.
+func constantAt(constants []object.Object, index int) object.Object {
+	if index >= len(constants) {
+		return nil
+	}
+	return constants[index]
+}
+
+func (d *decoder) fail(format string, a ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, a...)
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,147 @@
+package mkc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/fabiante/monkeylang/code"
+	"github.com/fabiante/monkeylang/compiler"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/object"
+	"github.com/fabiante/monkeylang/parser"
+	"github.com/fabiante/monkeylang/vm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"math"
+	"testing"
+)
+
+const program = `let name = "monkey";
+let newAdder = fn(a) {
+	fn(b) { a + b }
+};
+let addTwo = newAdder(2);
+[name, addTwo(-40)]`
+
+func TestReadWrite(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		bytecode := compile(t, program)
+
+		var buf bytes.Buffer
+		require.NoError(t, Write(&buf, bytecode))
+		assert.True(t, IsCompiled(buf.Bytes()))
+
+		read, err := Read(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, bytecode, read)
+
+		machine := vm.NewVM(read)
+		require.NoError(t, machine.Run())
+		assert.Equal(t, "[monkey, -38]", machine.LastPoppedStackElem().Inspect())
+	})
+
+	t.Run("empty program", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Write(&buf, compile(t, "")))
+
+		read, err := Read(&buf)
+		require.NoError(t, err)
+		assert.Empty(t, read.Instructions)
+		assert.Empty(t, read.Constants)
+	})
+
+	t.Run("rejects other versions", func(t *testing.T) {
+		data := encode(t, program)
+		binary.BigEndian.PutUint16(data[len(Magic):], Version+1)
+
+		_, err := Read(bytes.NewReader(data))
+
+		var versionErr *VersionError
+		require.ErrorAs(t, err, &versionErr)
+		assert.Equal(t, Version+1, versionErr.Version)
+		assert.EqualError(t, err, fmt.Sprintf("compiled program has format version %d, but version %d is required: recompile it with monkey build", Version+1, Version))
+	})
+
+	t.Run("rejects source code", func(t *testing.T) {
+		data := []byte("let x = 1;")
+		assert.False(t, IsCompiled(data))
+
+		_, err := Read(bytes.NewReader(data))
+		assert.ErrorIs(t, err, ErrNotCompiled)
+	})
+
+	t.Run("rejects truncated files", func(t *testing.T) {
+		data := encode(t, program)
+
+		_, err := Read(bytes.NewReader(data[:len(data)-3]))
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("rejects lengths exceeding the file", func(t *testing.T) {
+		data := []byte(Magic)
+		data = binary.BigEndian.AppendUint16(data, Version)
+		data = binary.AppendUvarint(data, 1)
+		data = append(data, tagString)
+		data = binary.AppendUvarint(data, math.MaxInt32)
+		data = append(data, "abc"...)
+
+		_, err := Read(bytes.NewReader(data))
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("rejects invalid instructions", func(t *testing.T) {
+		function := &object.CompiledFunction{Name: "f", NumLocals: 1, NumParameters: 1, Instructions: concat(code.Make(code.OpGetLocal, 1))}
+		constants := []object.Object{&object.Integer{Value: 1}, &object.String{Value: "x"}, function}
+
+		tests := []struct {
+			instructions  code.Instructions
+			expectedError string
+		}{
+			{code.Instructions{255}, "main program: offset 0: opcode 255 undefined"},
+			{concat(code.Make(code.OpTrue), code.Make(code.OpConstant, 0)[:2]), "main program: offset 1: incomplete operands of OpConstant"},
+			{concat(code.Make(code.OpConstant, 0xFFFF)), "main program: offset 0: invalid operand of OpConstant 65535"},
+			{concat(code.Make(code.OpClosure, 0, 0)), "main program: offset 0: invalid operand of OpClosure 0 0"},
+			{concat(code.Make(code.OpImport, 1)), "main program: offset 0: invalid operand of OpImport 1"},
+			{concat(code.Make(code.OpGetGlobal, 0), code.Make(code.OpMember, 0)), "main program: offset 3: invalid operand of OpMember 0"},
+			{concat(code.Make(code.OpGetBuiltin, 255)), "main program: offset 0: invalid operand of OpGetBuiltin 255"},
+			{concat(code.Make(code.OpJump, 1), code.Make(code.OpNull)), "main program: jump to offset 1, which is not an instruction"},
+			{concat(code.Make(code.OpClosure, 2, 0)), "function f: offset 0: invalid operand of OpGetLocal 1"},
+		}
+
+		for _, test := range tests {
+			t.Run(test.expectedError, func(t *testing.T) {
+				var buf bytes.Buffer
+				require.NoError(t, Write(&buf, &compiler.Bytecode{Instructions: test.instructions, Constants: constants}))
+
+				_, err := Read(&buf)
+				assert.EqualError(t, err, "invalid compiled program: "+test.expectedError)
+			})
+		}
+	})
+}
+
+func concat(instructions ...[]byte) code.Instructions {
+	var out code.Instructions
+	for _, ins := range instructions {
+		out = append(out, ins...)
+	}
+	return out
+}
+
+func compile(t *testing.T, input string) *compiler.Bytecode {
+	par := parser.NewParser(lexer.NewLexer(input))
+	program := par.ParseProgram()
+	require.Empty(t, par.Errors())
+
+	c := compiler.NewCompiler()
+	c.SetFile("program.mk")
+	require.NoError(t, c.Compile(program))
+	return c.Bytecode()
+}
+
+func encode(t *testing.T, input string) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, compile(t, input)))
+	return buf.Bytes()
+}
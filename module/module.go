@@ -0,0 +1,207 @@
+// Package module loads the files imported by Monkey programs.
+//
+// Imports are loaded before a program runs. The path of an import is looked
+// up relative to the directory of the importing file first and then in each
+// directory of the search path. Every file is loaded only once, however often
+// it is imported, and imports which form a cycle are reported as errors.
+// Files are identified by their absolute paths with symbolic links resolved,
+// so that a file is recognized however it is found.
+//
+// The path and the loaded program of an import are stored in the import
+// statement, where the evaluator and the compiler pick them up.
+package module
+
+import (
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/parser"
+	"github.com/fabiante/monkeylang/resolver"
+	"github.com/fabiante/monkeylang/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Error is a problem found while loading the imports of a file. Pos is the
+// zero position if the problem concerns the file as a whole.
+type Error struct {
+	File string
+	Pos  token.Position
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Pos.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+	return fmt.Sprintf("%s:%s: %s", e.File, e.Pos, e.Msg)
+}
+
+// Loader loads imported files and keeps them for later imports.
+type Loader struct {
+	// SearchPath contains the directories in which imports are looked up if
+	// they are not found relative to the importing file.
+	SearchPath []string
+	// Prepare is called for each imported program after it has been parsed
+	// and before it is resolved, for example to optimize it. It may be nil.
+	Prepare func(program *ast.Program)
+	// Warn is called for each warning found while resolving an imported
+	// program, like declarations shadowing a builtin. It may be nil.
+	Warn func(warning *Error)
+
+	// programs contains the loaded programs by file.
+	programs map[string]*ast.Program
+	// loading contains the files whose imports are being loaded, starting
+	// with the file passed to Load.
+	loading []string
+}
+
+// NewLoader creates a loader which looks up imports in the given directories
+// after the directory of the importing file.
+func NewLoader(searchPath ...string) *Loader {
+	return &Loader{SearchPath: searchPath, programs: make(map[string]*ast.Program)}
+}
+
+// SearchPathFromEnv returns the directories listed in the MONKEYPATH
+// environment variable, which are separated like the directories of PATH.
+func SearchPathFromEnv() []string {
+	return filepath.SplitList(os.Getenv("MONKEYPATH"))
+}
+
+// Load loads the imports of program, which has been parsed from file, and
+// their imports in turn. Imported programs are parsed and resolved.
+//
+// The File and Program of each import statement are set, unless an error is
+// returned for it. File is the absolute path of the imported file.
+func (l *Loader) Load(file string, program *ast.Program) []*Error {
+	file = absolute(file)
+	l.loading = append(l.loading, file)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	var errs []*Error
+	ast.Inspect(program, func(node ast.Node) bool {
+		if stmt, ok := node.(*ast.ImportStatement); ok {
+			errs = append(errs, l.load(file, stmt)...)
+		}
+		return true
+	})
+	return errs
+}
+
+func (l *Loader) load(from string, stmt *ast.ImportStatement) []*Error {
+	path, ok := l.find(from, stmt.Path.Value)
+	if !ok {
+		return []*Error{{File: from, Pos: stmt.Path.Pos(), Msg: fmt.Sprintf("cannot find module %q", stmt.Path.Value)}}
+	}
+
+	for i, file := range l.loading {
+		if file == path {
+			cycle := strings.Join(append(l.loading[i:], path), " -> ")
+			return []*Error{{File: from, Pos: stmt.Path.Pos(), Msg: "import cycle: " + cycle}}
+		}
+	}
+
+	program, ok := l.programs[path]
+	if !ok {
+		var errs []*Error
+		if program, errs = l.parse(path); len(errs) > 0 {
+			return errs
+		}
+		if errs = l.Load(path, program); len(errs) > 0 {
+			return errs
+		}
+		l.programs[path] = program
+	}
+
+	stmt.File = path
+	stmt.Program = program
+	return nil
+}
+
+// find returns the absolute path of the file imported by path from the given
+// file.
+func (l *Loader) find(from, path string) (string, bool) {
+	path = filepath.FromSlash(path)
+	if filepath.IsAbs(path) {
+		return absolute(path), isFile(path)
+	}
+
+	dirs := append([]string{filepath.Dir(from)}, l.SearchPath...)
+	for _, dir := range dirs {
+		if file := filepath.Join(dir, path); isFile(file) {
+			return absolute(file), true
+		}
+	}
+	return "", false
+}
+
+// absolute returns the absolute path of file with symbolic links resolved.
+// If they cannot be resolved, the cleaned absolute path is returned.
+func absolute(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.Clean(file)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// parse reads, parses and resolves the given file.
+func (l *Loader) parse(file string) (*ast.Program, []*Error) {
+	input, err := os.ReadFile(file)
+	if err != nil {
+		return nil, []*Error{{File: file, Msg: err.Error()}}
+	}
+
+	par := parser.NewParser(lexer.NewLexer(string(input)))
+	program := par.ParseProgram()
+	if parseErrs := par.ErrorList(); len(parseErrs) > 0 {
+		errs := make([]*Error, 0, len(parseErrs))
+		for _, err := range parseErrs {
+			errs = append(errs, &Error{File: file, Pos: err.Pos, Msg: err.Msg})
+		}
+		return nil, errs
+	}
+
+	if l.Prepare != nil {
+		l.Prepare(program)
+	}
+
+	var errs []*Error
+	resolveErrs, warnings := resolver.Resolve(program)
+	for _, err := range resolveErrs {
+		errs = append(errs, &Error{File: file, Pos: err.Pos, Msg: err.Msg})
+	}
+	if l.Warn != nil {
+		for _, w := range warnings {
+			l.Warn(&Error{File: file, Pos: w.Pos, Msg: w.Msg})
+		}
+	}
+	errs = append(errs, checkReturns(file, program)...)
+
+	return program, errs
+}
+
+// checkReturns reports return statements at the top level of a module, which
+// would end the module instead of a function.
+func checkReturns(file string, program *ast.Program) []*Error {
+	var errs []*Error
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.ReturnStatement:
+			errs = append(errs, &Error{File: file, Pos: node.Pos(), Msg: "return outside of function in module"})
+		}
+		return true
+	})
+	return errs
+}
@@ -0,0 +1,169 @@
+package module
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoader_Load(t *testing.T) {
+	t.Run("relative imports", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"lib/math.mk": `import "util.mk" as util; export let add = fn(a, b) { a + b };`,
+			"lib/util.mk": "export let id = fn(x) { x };",
+		})
+
+		program := testutil.MustParse(t, `import "lib/math.mk" as math;`)
+		require.Empty(t, NewLoader().Load(filepath.Join(dir, "main.mk"), program))
+
+		math := program.Statements[0].(*ast.ImportStatement)
+		assert.Equal(t, filepath.Join(dir, "lib", "math.mk"), math.File)
+		require.NotNil(t, math.Program)
+
+		util := math.Program.Statements[0].(*ast.ImportStatement)
+		assert.Equal(t, filepath.Join(dir, "lib", "util.mk"), util.File)
+		assert.NotNil(t, util.Program)
+	})
+
+	t.Run("search path", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{"std/strings.mk": "export let s = 1;"})
+
+		program := testutil.MustParse(t, `import "strings.mk" as strings;`)
+		require.Empty(t, NewLoader(filepath.Join(dir, "std")).Load(filepath.Join(dir, "main.mk"), program))
+
+		assert.Equal(t, filepath.Join(dir, "std", "strings.mk"), program.Statements[0].(*ast.ImportStatement).File)
+	})
+
+	t.Run("files are loaded once", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"a.mk": `import "c.mk" as c;`,
+			"b.mk": `import "c.mk" as c;`,
+			"c.mk": "export let c = 1;",
+		})
+		prepared := 0
+		loader := NewLoader()
+		loader.Prepare = func(*ast.Program) { prepared++ }
+
+		program := testutil.MustParse(t, `import "a.mk" as a; import "b.mk" as b; import "c.mk" as c;`)
+		require.Empty(t, loader.Load(filepath.Join(dir, "main.mk"), program))
+
+		a := program.Statements[0].(*ast.ImportStatement).Program
+		b := program.Statements[1].(*ast.ImportStatement).Program
+		c := program.Statements[2].(*ast.ImportStatement).Program
+		assert.Same(t, c, a.Statements[0].(*ast.ImportStatement).Program)
+		assert.Same(t, c, b.Statements[0].(*ast.ImportStatement).Program)
+		assert.Equal(t, 3, prepared)
+	})
+
+	t.Run("relative and absolute paths", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"main.mk":  `import "a.mk" as a;`,
+			"std/a.mk": `import "b.mk" as b;`,
+			"std/b.mk": `import "main.mk" as main;`,
+			"std/c.mk": "export let c = 1;",
+		})
+		chdir(t, dir)
+		prepared := 0
+		loader := NewLoader("std", ".")
+		loader.Prepare = func(*ast.Program) { prepared++ }
+
+		// The file is found relative to main.mk and by the relative search
+		// path, and is loaded once with its absolute path.
+		program := testutil.MustParse(t, `import "std/c.mk" as c1; import "c.mk" as c2;`)
+		require.Empty(t, loader.Load(filepath.Join(dir, "main.mk"), program))
+
+		c1 := program.Statements[0].(*ast.ImportStatement)
+		c2 := program.Statements[1].(*ast.ImportStatement)
+		assert.Equal(t, filepath.Join(dir, "std", "c.mk"), c1.File)
+		assert.Equal(t, c1.File, c2.File)
+		assert.Same(t, c1.Program, c2.Program)
+		assert.Equal(t, 1, prepared)
+
+		// main.mk is found by the relative search path when b.mk imports it,
+		// which closes the cycle.
+		errs := loader.Load(filepath.Join(dir, "main.mk"), testutil.MustParse(t, `import "a.mk" as a;`))
+		require.Len(t, errs, 1)
+		assert.Equal(t, "import cycle: "+strings.Join([]string{
+			filepath.Join(dir, "main.mk"),
+			filepath.Join(dir, "std", "a.mk"),
+			filepath.Join(dir, "std", "b.mk"),
+			filepath.Join(dir, "main.mk"),
+		}, " -> "), errs[0].Msg)
+	})
+
+	t.Run("warnings", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{"lib.mk": "let len = 1; export let f = fn(len) { len };"})
+		var warnings []string
+		loader := NewLoader()
+		loader.Warn = func(w *Error) { warnings = append(warnings, w.Error()) }
+
+		program := testutil.MustParse(t, `import "lib.mk" as lib;`)
+		require.Empty(t, loader.Load(filepath.Join(dir, "main.mk"), program))
+
+		assert.Equal(t, []string{
+			filepath.Join(dir, "lib.mk") + ":1:5: declaration of len shadows builtin",
+			filepath.Join(dir, "lib.mk") + ":1:32: declaration of len shadows declaration at 1:5",
+		}, warnings)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		dir := writeFiles(t, map[string]string{
+			"a.mk":      `import "b.mk" as b;`,
+			"b.mk":      `import "a.mk" as a;`,
+			"self.mk":   `import "self.mk" as self;`,
+			"syntax.mk": "let = 1;",
+			"names.mk":  "export let x = y;",
+			"return.mk": "let f = fn() { return 1; }; return 2;",
+		})
+
+		tests := []struct {
+			input    string
+			expected []string
+		}{
+			{`import "missing.mk" as m;`, []string{`main.mk:1:8: cannot find module "missing.mk"`}},
+			{`import "a.mk" as a;`, []string{"b.mk:1:8: import cycle: a.mk -> b.mk -> a.mk"}},
+			{`import "self.mk" as self;`, []string{"self.mk:1:8: import cycle: self.mk -> self.mk"}},
+			{`import "syntax.mk" as s;`, []string{"syntax.mk:1:5: expected Identifier, got Assign instead", "syntax.mk:1:5: no prefix parse fn for Assign"}},
+			{`import "names.mk" as n;`, []string{"names.mk:1:16: undefined variable y"}},
+			{`import "return.mk" as r;`, []string{"return.mk:1:29: return outside of function in module"}},
+		}
+
+		for _, test := range tests {
+			t.Run(test.input, func(t *testing.T) {
+				errs := NewLoader().Load(filepath.Join(dir, "main.mk"), testutil.MustParse(t, test.input))
+
+				var actual []string
+				for _, err := range errs {
+					actual = append(actual, strings.ReplaceAll(err.Error(), dir+string(filepath.Separator), ""))
+				}
+				assert.Equal(t, test.expected, actual)
+			})
+		}
+	})
+}
+
+// writeFiles writes the given files to a temporary directory and returns it
+// with symbolic links resolved, like the paths of loaded files.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+// chdir changes the working directory to dir until the test has finished.
+func chdir(t *testing.T, dir string) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { require.NoError(t, os.Chdir(wd)) })
+}
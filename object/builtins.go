@@ -0,0 +1,145 @@
+package object
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// BuiltinFunction is the implementation of a builtin. It returns an *Error
+// on failure and nil if there is no result.
+type BuiltinFunction func(args ...Object) Object
+
+type Builtin struct {
+	Fn BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType {
+	return BuiltinObj
+}
+
+func (b *Builtin) Inspect() string {
+	return "builtin function"
+}
+
+// Stdout is the writer used by the puts builtin.
+var Stdout io.Writer = os.Stdout
+
+// Builtins contains all builtin functions. The compiler refers to builtins by
+// their index, so new builtins must be appended at the end.
+var Builtins = []struct {
+	Name    string
+	Builtin *Builtin
+}{
+	{"len", &Builtin{Fn: builtinLen}},
+	{"puts", &Builtin{Fn: builtinPuts}},
+	{"first", &Builtin{Fn: builtinFirst}},
+	{"last", &Builtin{Fn: builtinLast}},
+	{"rest", &Builtin{Fn: builtinRest}},
+	{"push", &Builtin{Fn: builtinPush}},
+}
+
+// GetBuiltinByName returns the builtin with the given name or nil.
+func GetBuiltinByName(name string) *Builtin {
+	for _, def := range Builtins {
+		if def.Name == name {
+			return def.Builtin
+		}
+	}
+	return nil
+}
+
+func builtinLen(args ...Object) Object {
+	if len(args) != 1 {
+		return wrongNumberOfArguments(1, len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *String:
+		return &Integer{Value: int64(len(arg.Value))}
+	case *Array:
+		return &Integer{Value: int64(len(arg.Elements))}
+	default:
+		return NewError(TypeError, "argument to `len` not supported, got %s", args[0].Type())
+	}
+}
+
+func builtinPuts(args ...Object) Object {
+	for _, arg := range args {
+		_, _ = fmt.Fprintln(Stdout, arg.Inspect())
+	}
+	return nil
+}
+
+func builtinFirst(args ...Object) Object {
+	array, err := arrayArgument("first", args)
+	if err != nil {
+		return err
+	}
+	if len(array.Elements) > 0 {
+		return array.Elements[0]
+	}
+	return nil
+}
+
+func builtinLast(args ...Object) Object {
+	array, err := arrayArgument("last", args)
+	if err != nil {
+		return err
+	}
+	if length := len(array.Elements); length > 0 {
+		return array.Elements[length-1]
+	}
+	return nil
+}
+
+// builtinRest returns a new array containing all elements but the first.
+func builtinRest(args ...Object) Object {
+	array, err := arrayArgument("rest", args)
+	if err != nil {
+		return err
+	}
+
+	length := len(array.Elements)
+	if length == 0 {
+		return nil
+	}
+
+	elements := make([]Object, length-1)
+	copy(elements, array.Elements[1:])
+	return &Array{Elements: elements}
+}
+
+// builtinPush returns a new array with the element appended.
+func builtinPush(args ...Object) Object {
+	if len(args) != 2 {
+		return wrongNumberOfArguments(2, len(args))
+	}
+
+	array, ok := args[0].(*Array)
+	if !ok {
+		return NewError(TypeError, "argument to `push` must be ARRAY, got %s", args[0].Type())
+	}
+
+	length := len(array.Elements)
+	elements := make([]Object, length+1)
+	copy(elements, array.Elements)
+	elements[length] = args[1]
+	return &Array{Elements: elements}
+}
+
+func arrayArgument(builtin string, args []Object) (*Array, *Error) {
+	if len(args) != 1 {
+		return nil, wrongNumberOfArguments(1, len(args))
+	}
+
+	array, ok := args[0].(*Array)
+	if !ok {
+		return nil, NewError(TypeError, "argument to `%s` must be ARRAY, got %s", builtin, args[0].Type())
+	}
+	return array, nil
+}
+
+func wrongNumberOfArguments(want, got int) *Error {
+	return NewError(TypeError, "wrong number of arguments: want=%d, got=%d", want, got)
+}
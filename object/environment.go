@@ -0,0 +1,174 @@
+package object
+
+import "github.com/fabiante/monkeylang/token"
+
+// Environment binds names to values for the tree-walking evaluator.
+//
+// Values are either stored by name or, if the program has been resolved by
+// package resolver, in numbered slots.
+type Environment struct {
+	store map[string]Object
+	slots []Object
+	outer *Environment
+	// frame is the call whose environment this is. It is nil at the top level.
+	frame *Frame
+	// file is the name of the source file of the top level. It is only set
+	// on environments without outer environment.
+	file string
+	// modules contains the imported modules by file. It is shared by the
+	// top-level environments of all modules of a program.
+	modules map[string]*Module
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{store: make(map[string]Object)}
+}
+
+// NewFileEnvironment creates the environment of the top level of the given
+// source file. Stack frames of errors thrown by its code carry the name of
+// the file.
+func NewFileEnvironment(file string) *Environment {
+	env := NewEnvironment()
+	env.file = file
+	return env
+}
+
+// NewModuleEnvironment creates the environment of the top level of a module
+// in the given source file, which is imported by code evaluated in importer.
+// The import is described by frame.
+func NewModuleEnvironment(importer *Environment, file string, frame *Frame) *Environment {
+	env := NewFileEnvironment(file)
+	env.frame = frame
+
+	root := importer.root()
+	if root.modules == nil {
+		root.modules = make(map[string]*Module)
+	}
+	env.modules = root.modules
+	return env
+}
+
+// NewEnclosedEnvironment creates an environment for a function call, which
+// falls back to the environment the function was defined in.
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+// NewCallEnvironment creates the environment of a function call, which is
+// described by frame.
+func NewCallEnvironment(outer *Environment, frame *Frame) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.frame = frame
+	return env
+}
+
+// Frame returns the call whose environment e is, or nil at the top level.
+func (e *Environment) Frame() *Frame {
+	return e.frame
+}
+
+// File returns the name of the source file of the code which is evaluated in
+// e. Functions are located in the file they have been defined in.
+func (e *Environment) File() string {
+	return e.root().file
+}
+
+// Module returns the module of the given source file if it has been imported
+// by the program before.
+func (e *Environment) Module(file string) (*Module, bool) {
+	module, ok := e.root().modules[file]
+	return module, ok
+}
+
+// SetModule records that module has been imported by the program.
+func (e *Environment) SetModule(module *Module) {
+	root := e.root()
+	if root.modules == nil {
+		root.modules = make(map[string]*Module)
+	}
+	root.modules[module.File] = module
+}
+
+// root returns the environment of the top level e belongs to.
+func (e *Environment) root() *Environment {
+	for e.outer != nil {
+		e = e.outer
+	}
+	return e
+}
+
+// MaxFrames is the maximum number of frames of a program, including the top
+// level. Calls which would exceed it fail with a stack overflow, so that both
+// the evaluator and the virtual machine fail the same way.
+const MaxFrames = 1024
+
+// Frame is a call of a function by the evaluator.
+type Frame struct {
+	// Function is the name of the called function as shown in stack frames.
+	Function string
+	// Call is the position of the call in the calling function.
+	Call token.Position
+	// CallFile is the name of the source file containing Call.
+	CallFile string
+	// Caller is the frame of the calling function, or nil if the call is
+	// located at the top level.
+	Caller *Frame
+	// Depth is the number of frames below this one, including the top level.
+	Depth int
+	// TailCalls is the number of frames which have been replaced by this
+	// one, see StackFrame.
+	TailCalls int
+}
+
+// NewFrame creates the frame of a call located in caller, which is nil for
+// calls at the top level. It reports false if the frame would exceed MaxFrames.
+func NewFrame(function string, call token.Position, callFile string, caller *Frame) (*Frame, bool) {
+	depth := 1
+	if caller != nil {
+		depth = caller.Depth + 1
+	}
+	if depth >= MaxFrames {
+		return nil, false
+	}
+	return &Frame{Function: function, Call: call, CallFile: callFile, Caller: caller, Depth: depth}, true
+}
+
+// Get looks up name in this environment and its outer environments.
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// Set binds name in this environment.
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+// GetAt returns the value of the given slot of the environment which is
+// depth levels further out. It is used for identifiers which have been
+// resolved by package resolver.
+func (e *Environment) GetAt(depth, slot int) (Object, bool) {
+	env := e
+	for i := 0; i < depth && env != nil; i++ {
+		env = env.outer
+	}
+	if env == nil || slot >= len(env.slots) || env.slots[slot] == nil {
+		return nil, false
+	}
+	return env.slots[slot], true
+}
+
+// SetAt sets the value of the given slot of this environment.
+func (e *Environment) SetAt(slot int, val Object) Object {
+	if slot >= len(e.slots) {
+		e.slots = append(e.slots, make([]Object, slot+1-len(e.slots))...)
+	}
+	e.slots[slot] = val
+	return val
+}
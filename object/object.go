@@ -0,0 +1,436 @@
+// Package object defines the values Monkey programs operate on.
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/code"
+	"github.com/fabiante/monkeylang/token"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// ObjectType is the name of the type of an Object as shown to users, for
+// example in error messages.
+type ObjectType string
+
+const (
+	IntegerObj          ObjectType = "INTEGER"
+	BooleanObj          ObjectType = "BOOLEAN"
+	NullObj             ObjectType = "NULL"
+	StringObj           ObjectType = "STRING"
+	ArrayObj            ObjectType = "ARRAY"
+	HashObj             ObjectType = "HASH"
+	FunctionObj         ObjectType = "FUNCTION"
+	CompiledFunctionObj ObjectType = "COMPILED_FUNCTION"
+	BuiltinObj          ObjectType = "BUILTIN"
+	ReturnValueObj      ObjectType = "RETURN_VALUE"
+	ErrorObj            ObjectType = "ERROR"
+	ModuleObj           ObjectType = "MODULE"
+)
+
+type Object interface {
+	Type() ObjectType
+	// Inspect returns a representation of the object for users.
+	Inspect() string
+}
+
+// Hashable is implemented by objects which can be used as keys of a Hash.
+type Hashable interface {
+	HashKey() HashKey
+}
+
+// HashKey identifies the key of a hash pair. Keys with equal values have equal hash keys.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+type Integer struct {
+	Value int64
+}
+
+func (i *Integer) Type() ObjectType {
+	return IntegerObj
+}
+
+func (i *Integer) Inspect() string {
+	return fmt.Sprintf("%d", i.Value)
+}
+
+type Boolean struct {
+	Value bool
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (b *Boolean) Type() ObjectType {
+	return BooleanObj
+}
+
+func (b *Boolean) Inspect() string {
+	return fmt.Sprintf("%t", b.Value)
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+// Null is the absence of a value, for example the result of an if expression
+// whose condition is false and which has no else branch.
+type Null struct{}
+
+func (n *Null) Type() ObjectType {
+	return NullObj
+}
+
+func (n *Null) Inspect() string {
+	return "null"
+}
+
+type String struct {
+	Value string
+}
+
+func (s *String) Type() ObjectType {
+	return StringObj
+}
+
+func (s *String) Inspect() string {
+	return s.Value
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+type Array struct {
+	Elements []Object
+}
+
+func (a *Array) Type() ObjectType {
+	return ArrayObj
+}
+
+func (a *Array) Inspect() string {
+	elements := make([]string, 0, len(a.Elements))
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+type Hash struct {
+	Pairs map[HashKey]HashPair
+}
+
+func (h *Hash) Type() ObjectType {
+	return HashObj
+}
+
+// Inspect returns the pairs of the hash sorted by their keys, which makes the
+// representation deterministic.
+func (h *Hash) Inspect() string {
+	pairs := make([]string, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+	sort.Strings(pairs)
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// Function is a function of the tree-walking evaluator. It keeps the
+// environment it was defined in.
+type Function struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+	// Name is the name the function is bound to. It is empty for anonymous functions.
+	Name string
+}
+
+func (f *Function) Type() ObjectType {
+	return FunctionObj
+}
+
+func (f *Function) Inspect() string {
+	return FunctionSource(f.Parameters, f.Body)
+}
+
+// FunctionSource returns the representation of a function with the given
+// parameters and body. It is returned by Inspect for the functions of both
+// the evaluator and the virtual machine.
+func FunctionSource(parameters []*ast.Identifier, body *ast.BlockStatement) string {
+	params := make([]string, 0, len(parameters))
+	for _, p := range parameters {
+		params = append(params, p.String())
+	}
+
+	var out bytes.Buffer
+	out.WriteString("fn(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(body.String())
+	out.WriteString("\n}")
+	return out.String()
+}
+
+// CompiledFunction is a function compiled to bytecode.
+type CompiledFunction struct {
+	Instructions code.Instructions
+	// NumLocals is the number of local bindings including the parameters.
+	NumLocals     int
+	NumParameters int
+	// Name is the name the function is bound to. It is empty for anonymous functions.
+	Name string
+	// Lines maps the instructions to the lines of the source code.
+	Lines code.LineTable
+	// File is the name of the source file the function has been compiled
+	// from. It is empty if the name is not known.
+	File string
+	// Source is the representation of the function returned by the Inspect
+	// method of its closures, see FunctionSource. It is empty for the
+	// functions executing the top level of modules.
+	Source string
+}
+
+func (c *CompiledFunction) Type() ObjectType {
+	return CompiledFunctionObj
+}
+
+func (c *CompiledFunction) Inspect() string {
+	if c.Name != "" {
+		return fmt.Sprintf("CompiledFunction[%s]", c.Name)
+	}
+	return fmt.Sprintf("CompiledFunction[%p]", c)
+}
+
+// Closure is a CompiledFunction together with the values of its free variables.
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []Object
+}
+
+// Type returns FunctionObj, because closures are the functions of compiled
+// programs. Users see the same type as for functions of the evaluator.
+func (c *Closure) Type() ObjectType {
+	return FunctionObj
+}
+
+func (c *Closure) Inspect() string {
+	if c.Fn.Source != "" {
+		return c.Fn.Source
+	}
+	if c.Fn.Name != "" {
+		return fmt.Sprintf("Closure[%s]", c.Fn.Name)
+	}
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
+// ReturnValue wraps the value of a return statement while the evaluator
+// unwinds to the enclosing function.
+type ReturnValue struct {
+	Value Object
+}
+
+func (r *ReturnValue) Type() ObjectType {
+	return ReturnValueObj
+}
+
+func (r *ReturnValue) Inspect() string {
+	return r.Value.Inspect()
+}
+
+// Error is a runtime error. It is thrown by failing operations and throw
+// statements and aborts the evaluation of the program unless it is caught.
+//
+// Pos and Stack are set when the error is thrown. Errors returned by builtins
+// are thrown at the position of the call.
+type Error struct {
+	Kind    ErrorKind
+	Message string
+	// Pos is the position at which the error was thrown.
+	Pos token.Position
+	// Stack contains the calls which were active when the error was thrown,
+	// starting with the innermost one. It is nil if the error has not been
+	// thrown yet. Calls in tail position replace the frame of the calling
+	// function, which is therefore missing and only counted by the TailCalls
+	// of the frame which replaced it.
+	Stack []StackFrame
+}
+
+// ErrorKind classifies errors by their cause.
+type ErrorKind string
+
+const (
+	// ThrownError is the kind of values thrown by throw statements.
+	ThrownError ErrorKind = "Error"
+	// TypeError is the kind of operations on values of the wrong type and
+	// calls with the wrong number of arguments.
+	TypeError ErrorKind = "TypeError"
+	// ArithmeticError is the kind of divisions by zero.
+	ArithmeticError ErrorKind = "ArithmeticError"
+	// ReferenceError is the kind of identifiers which are not defined.
+	ReferenceError ErrorKind = "ReferenceError"
+	// InternalError is the kind of errors of the interpreter itself, like a
+	// stack overflow.
+	InternalError ErrorKind = "InternalError"
+)
+
+func (e *Error) Type() ObjectType {
+	return ErrorObj
+}
+
+func (e *Error) Inspect() string {
+	return "ERROR: " + e.Message
+}
+
+// Error returns the message of the error, so that errors of the virtual
+// machine can be returned as Go errors.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Trace returns the kind and the message of the error followed by one line
+// per frame of the stack, like:
+//
+//	TypeError: unknown operator: BOOLEAN + INTEGER
+//	    at add (math.mk:3:5)
+//	    ... 1 frame replaced by a tail call
+//	    at math.mk:7:1
+//
+// Consecutive identical frames, like those of a recursion which overflowed
+// the stack, are printed once followed by the number of repetitions.
+func (e *Error) Trace() string {
+	var out bytes.Buffer
+
+	if e.Kind != "" {
+		out.WriteString(string(e.Kind) + ": ")
+	}
+	out.WriteString(e.Message)
+	for i := 0; i < len(e.Stack); {
+		frame := e.Stack[i]
+		n := 1
+		for i+n < len(e.Stack) && e.Stack[i+n] == frame {
+			n++
+		}
+
+		out.WriteString("\n    at " + frame.String())
+		switch {
+		case frame.TailCalls == 1:
+			out.WriteString("\n    ... 1 frame replaced by a tail call")
+		case frame.TailCalls > 1:
+			_, _ = fmt.Fprintf(&out, "\n    ... %d frames replaced by tail calls", frame.TailCalls)
+		}
+		if n > 1 {
+			_, _ = fmt.Fprintf(&out, "\n    ... repeated %d more times", n-1)
+		}
+
+		i += n
+	}
+
+	return out.String()
+}
+
+// Property returns the value of the property with the given name, which
+// programs access by indexing the error, or nil if there is none:
+//
+//	kind     the kind as string
+//	message  the message as string
+//	line     the line of Pos as integer
+//	column   the column of Pos as integer
+//	stack    the frames of Stack as array of strings
+func (e *Error) Property(name string) Object {
+	switch name {
+	case "kind":
+		return &String{Value: string(e.Kind)}
+	case "message":
+		return &String{Value: e.Message}
+	case "line":
+		return &Integer{Value: int64(e.Pos.Line)}
+	case "column":
+		return &Integer{Value: int64(e.Pos.Column)}
+	case "stack":
+		frames := make([]Object, len(e.Stack))
+		for i, frame := range e.Stack {
+			frames[i] = &String{Value: frame.String()}
+		}
+		return &Array{Elements: frames}
+	default:
+		return nil
+	}
+}
+
+// NewError creates an Error of the given kind with a formatted message.
+func NewError(kind ErrorKind, format string, a ...any) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
+}
+
+// StackFrame is a call which was active when an error was thrown.
+type StackFrame struct {
+	// Function is the name of the called function. It is empty for the top
+	// level of the program.
+	Function string
+	// File is the name of the source file containing Pos. It is empty if the
+	// name is not known, like in the REPL.
+	File string
+	// Pos is the position which was executed within the function.
+	Pos token.Position
+	// TailCalls is the number of frames which have been replaced by this
+	// one, because their function called the next one in tail position.
+	TailCalls int
+}
+
+// String returns the name of the function followed by the location, like
+// "add (math.mk:3:5)". Frames of the top level consist of the location only.
+func (f StackFrame) String() string {
+	location := f.Pos.String()
+	if f.File != "" {
+		location = f.File + ":" + location
+	}
+
+	if f.Function == "" {
+		return location
+	}
+	return fmt.Sprintf("%s (%s)", f.Function, location)
+}
+
+// FunctionName returns the name of a function as shown in stack frames.
+// Anonymous functions are named "<anonymous>".
+func FunctionName(name string) string {
+	if name == "" {
+		return "<anonymous>"
+	}
+	return name
+}
+
+// Module is an imported source file. Programs access its exports with member
+// expressions like math.add.
+type Module struct {
+	// File is the path of the source file.
+	File    string
+	Exports map[string]Object
+}
+
+func (m *Module) Type() ObjectType {
+	return ModuleObj
+}
+
+func (m *Module) Inspect() string {
+	return fmt.Sprintf("Module[%s]", m.File)
+}
@@ -0,0 +1,128 @@
+package optimizer
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+)
+
+// DeadBranchElimination returns a pass which replaces if expressions whose
+// condition is a literal by the branch which is taken:
+//
+//	let x = if (true) { 1 } else { 2 };  =>  let x = 1;
+//
+// If the if expression is a statement, the statements of the taken branch
+// replace it. Blocks do not introduce a scope, so this does not change the
+// meaning of let statements within the branch.
+func DeadBranchElimination() Pass {
+	return NewPass("dead-branch-elimination", func(program *ast.Program) bool {
+		changed := false
+
+		// If expressions used as values can only be replaced by a single expression.
+		ast.Rewrite(program, func(node ast.Node) ast.Node {
+			ie, ok := node.(*ast.IfExpression)
+			if !ok {
+				return node
+			}
+
+			block, constant := takenBranch(ie)
+			if !constant || block == nil || len(block.Statements) != 1 {
+				return node
+			}
+			stmt, ok := block.Statements[0].(*ast.ExpressionStatement)
+			if !ok {
+				return node
+			}
+
+			changed = true
+			return stmt.Expression
+		})
+
+		program.Statements = eliminateBranches(program.Statements, &changed)
+		ast.Inspect(program, func(node ast.Node) bool {
+			if block, ok := node.(*ast.BlockStatement); ok {
+				block.Statements = eliminateBranches(block.Statements, &changed)
+			}
+			return true
+		})
+
+		return changed
+	})
+}
+
+// eliminateBranches replaces if statements with a literal condition by the
+// statements of the branch which is taken.
+func eliminateBranches(statements []ast.Statement, changed *bool) []ast.Statement {
+	result := make([]ast.Statement, 0, len(statements))
+
+	for i, stmt := range statements {
+		es, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			result = append(result, stmt)
+			continue
+		}
+		ie, ok := es.Expression.(*ast.IfExpression)
+		if !ok {
+			result = append(result, stmt)
+			continue
+		}
+
+		block, constant := takenBranch(ie)
+		if !constant {
+			result = append(result, stmt)
+			continue
+		}
+
+		// An if expression without statements produces null. This is the
+		// value of the enclosing block if it is the last statement.
+		if block == nil || len(block.Statements) == 0 {
+			if i == len(statements)-1 {
+				result = append(result, stmt)
+			} else {
+				*changed = true
+			}
+			continue
+		}
+
+		// The value of an if expression whose branch ends in a let statement
+		// is null, while that of the let statement itself is undefined.
+		if i == len(statements)-1 && !producesValue(block.Statements[len(block.Statements)-1]) {
+			result = append(result, stmt)
+			continue
+		}
+
+		*changed = true
+		result = append(result, block.Statements...)
+	}
+
+	return result
+}
+
+// takenBranch returns the branch of an if expression which is taken if its
+// condition is a literal. The branch is nil if the alternative is taken but missing.
+func takenBranch(ie *ast.IfExpression) (block *ast.BlockStatement, constant bool) {
+	var truthy bool
+
+	switch condition := ie.Condition.(type) {
+	case *ast.BooleanLiteral:
+		truthy = condition.Value
+	case *ast.IntegerLiteral, *ast.StringLiteral:
+		truthy = true
+	default:
+		return nil, false
+	}
+
+	if truthy {
+		return ie.Consequence, true
+	}
+	return ie.Alternative, true
+}
+
+// producesValue reports whether stmt determines the value of a block if it is
+// its last statement.
+func producesValue(stmt ast.Statement) bool {
+	switch stmt.(type) {
+	case *ast.ExpressionStatement, *ast.ReturnStatement, *ast.ThrowStatement:
+		return true
+	default:
+		return false
+	}
+}
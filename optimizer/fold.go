@@ -0,0 +1,121 @@
+package optimizer
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/token"
+	"strconv"
+)
+
+// ConstantFolding returns a pass which evaluates prefix and infix expressions
+// whose operands are integer or boolean literals:
+//
+//	60 * 60 * 24  =>  86400
+//	1 < 2         =>  true
+//	!true         =>  false
+//
+// Divisions by zero are kept, so that they still fail at runtime.
+func ConstantFolding() Pass {
+	return NewPass("constant-folding", func(program *ast.Program) bool {
+		changed := false
+
+		ast.Rewrite(program, func(node ast.Node) ast.Node {
+			var folded ast.Expression
+
+			switch node := node.(type) {
+			case *ast.PrefixExpression:
+				folded = foldPrefix(node)
+			case *ast.InfixExpression:
+				folded = foldInfix(node)
+			}
+
+			if folded == nil {
+				return node
+			}
+			changed = true
+			return folded
+		})
+
+		return changed
+	})
+}
+
+func foldPrefix(node *ast.PrefixExpression) ast.Expression {
+	switch right := node.Right.(type) {
+	case *ast.IntegerLiteral:
+		switch node.Operator {
+		case "-":
+			return integerLiteral(node.Token, -right.Value)
+		case "!":
+			// Integers are truthy.
+			return booleanLiteral(node.Token, false)
+		}
+	case *ast.BooleanLiteral:
+		if node.Operator == "!" {
+			return booleanLiteral(node.Token, !right.Value)
+		}
+	}
+	return nil
+}
+
+func foldInfix(node *ast.InfixExpression) ast.Expression {
+	switch left := node.Left.(type) {
+	case *ast.IntegerLiteral:
+		right, ok := node.Right.(*ast.IntegerLiteral)
+		if !ok {
+			return nil
+		}
+
+		switch node.Operator {
+		case "+":
+			return integerLiteral(node.Token, left.Value+right.Value)
+		case "-":
+			return integerLiteral(node.Token, left.Value-right.Value)
+		case "*":
+			return integerLiteral(node.Token, left.Value*right.Value)
+		case "/":
+			if right.Value == 0 {
+				return nil
+			}
+			return integerLiteral(node.Token, left.Value/right.Value)
+		case "<":
+			return booleanLiteral(node.Token, left.Value < right.Value)
+		case ">":
+			return booleanLiteral(node.Token, left.Value > right.Value)
+		case "==":
+			return booleanLiteral(node.Token, left.Value == right.Value)
+		case "!=":
+			return booleanLiteral(node.Token, left.Value != right.Value)
+		}
+	case *ast.BooleanLiteral:
+		right, ok := node.Right.(*ast.BooleanLiteral)
+		if !ok {
+			return nil
+		}
+
+		switch node.Operator {
+		case "==":
+			return booleanLiteral(node.Token, left.Value == right.Value)
+		case "!=":
+			return booleanLiteral(node.Token, left.Value != right.Value)
+		}
+	}
+	return nil
+}
+
+// integerLiteral creates a literal located at the position of the operator it replaces.
+func integerLiteral(operator token.Token, value int64) *ast.IntegerLiteral {
+	return &ast.IntegerLiteral{
+		Token: token.Token{Type: token.Int, Literal: strconv.FormatInt(value, 10), Pos: operator.Pos},
+		Value: value,
+	}
+}
+
+// booleanLiteral creates a literal located at the position of the operator it replaces.
+func booleanLiteral(operator token.Token, value bool) *ast.BooleanLiteral {
+	tok := token.Token{Type: token.False, Literal: "false", Pos: operator.Pos}
+	if value {
+		tok.Type = token.True
+		tok.Literal = "true"
+	}
+	return &ast.BooleanLiteral{Token: tok, Value: value}
+}
@@ -0,0 +1,75 @@
+// Package optimizer simplifies an *ast.Program before it is compiled or
+// evaluated.
+//
+// The optimizations are implemented as passes, which change the program in
+// place. Passes can be combined freely and new passes can be added by
+// implementing Pass. None of the passes changes the result of a program,
+// but runtime errors may be removed together with dead code.
+package optimizer
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+)
+
+// Pass is a single optimization.
+type Pass interface {
+	// Name is a short name of the pass for diagnostics.
+	Name() string
+	// Apply optimizes program in place and reports whether it changed it.
+	Apply(program *ast.Program) bool
+}
+
+// NewPass creates a Pass from a function.
+func NewPass(name string, apply func(program *ast.Program) bool) Pass {
+	return &funcPass{name: name, apply: apply}
+}
+
+type funcPass struct {
+	name  string
+	apply func(program *ast.Program) bool
+}
+
+func (p *funcPass) Name() string {
+	return p.name
+}
+
+func (p *funcPass) Apply(program *ast.Program) bool {
+	return p.apply(program)
+}
+
+// DefaultPasses returns all passes of this package in the order in which
+// they are most effective.
+func DefaultPasses() []Pass {
+	return []Pass{
+		ConstantFolding(),
+		DeadBranchElimination(),
+		UnusedLetRemoval(),
+	}
+}
+
+// maxRounds limits how often the passes are repeated by Optimize.
+const maxRounds = 16
+
+// Optimize applies the given passes to program. Without passes, the
+// DefaultPasses are applied.
+//
+// As one pass can enable another, for example by folding the condition of
+// an if expression, the passes are repeated until none of them changes the
+// program anymore.
+func Optimize(program *ast.Program, passes ...Pass) {
+	if len(passes) == 0 {
+		passes = DefaultPasses()
+	}
+
+	for round := 0; round < maxRounds; round++ {
+		changed := false
+		for _, pass := range passes {
+			if pass.Apply(program) {
+				changed = true
+			}
+		}
+		if !changed {
+			return
+		}
+	}
+}
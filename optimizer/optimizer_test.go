@@ -0,0 +1,166 @@
+package optimizer
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/compiler"
+	"github.com/fabiante/monkeylang/internal/corpus"
+	"github.com/fabiante/monkeylang/internal/testutil"
+	"github.com/fabiante/monkeylang/printer"
+	"github.com/fabiante/monkeylang/vm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type optimizerTest struct {
+	input    string
+	expected string
+}
+
+func TestConstantFolding(t *testing.T) {
+	runOptimizerTests(t, []Pass{ConstantFolding()}, []optimizerTest{
+		{"60 * 60 * 24", "86400;\n"},
+		{"1 + 2 * 3 - 4 / 2", "5;\n"},
+		{"(1 + 2) * x", "3 * x;\n"},
+		{"x * 1 + 2", "x * 1 + 2;\n"},
+		{"1 - 5", "-4;\n"},
+		{"2 * (1 - 5)", "-8;\n"},
+		{"(1 - 5)[0]", "(-4)[0];\n"},
+		{"-(2 + 3)", "-5;\n"},
+		{"--1", "1;\n"},
+		{"1 / 0", "1 / 0;\n"},
+		{"1 < 2", "true;\n"},
+		{"1 > 2", "false;\n"},
+		{"2 == 2", "true;\n"},
+		{"2 != 2", "false;\n"},
+		{"true == false", "false;\n"},
+		{"true != false", "true;\n"},
+		{"1 == true", "1 == true;\n"},
+		{"true + true", "true + true;\n"},
+		{"!true", "false;\n"},
+		{"!!true", "true;\n"},
+		{"!5", "false;\n"},
+		{"-true", "-true;\n"},
+		{"let f = fn() { 1 + 1 };", "let f = fn() {\n    2;\n};\n"},
+		{"[1 + 1, {2 * 2: !false}]", "[2, {4: true}];\n"},
+	})
+}
+
+func TestDeadBranchElimination(t *testing.T) {
+	runOptimizerTests(t, []Pass{DeadBranchElimination()}, []optimizerTest{
+		{"let x = if (true) { 1 } else { 2 };", "let x = 1;\n"},
+		{"let x = if (false) { 1 } else { 2 };", "let x = 2;\n"},
+		{"let x = if (1) { 1 };", "let x = 1;\n"},
+		{"let x = if (false) { 1 };", "let x = if (false) {\n    1;\n};\n"},
+		{"let x = if (true) { puts(1); 2 };", "let x = if (true) {\n    puts(1);\n    2;\n};\n"},
+		{"let x = if (c) { 1 } else { 2 };", "let x = if (c) {\n    1;\n} else {\n    2;\n};\n"},
+		{"if (true) { let a = 1; puts(a); } 2;", "let a = 1;\nputs(a);\n2;\n"},
+		{"if (false) { puts(1); } 2;", "2;\n"},
+		{"if (false) { puts(1); }", "if (false) {\n    puts(1);\n}\n"},
+		{"let f = fn() { if (true) { return 1; } 2 };", "let f = fn() {\n    return 1;\n    2;\n};\n"},
+		{"if (true) { if (false) { 1 } else { puts(2); 3 } } 4;", "puts(2);\n3;\n4;\n"},
+		{"if (true) { let a = 1; }", "if (true) {\n    let a = 1;\n}\n"},
+	})
+}
+
+func TestUnusedLetRemoval(t *testing.T) {
+	runOptimizerTests(t, []Pass{UnusedLetRemoval()}, []optimizerTest{
+		{"let a = 1; let b = 2; b;", "let b = 2;\nb;\n"},
+		{"let a = [1, \"two\", {true: fn() {}}]; 0;", "0;\n"},
+		{"let a = puts(1); 0;", "let a = puts(1);\n0;\n"},
+		{"let a = 1 + 1; 0;", "let a = 1 + 1;\n0;\n"},
+		{"let a = {b: 1}; 0;", "let a = {b: 1};\n0;\n"},
+		{"let a = 1;", "let a = 1;\n"},
+		{"let f = fn(a) { let a = 1; a }; 0;", "0;\n"},
+		{"let f = fn() { let a = 1; let b = 2; }; f();", "let f = fn() {\n    let b = 2;\n};\nf();\n"},
+		{"let a = 1; let f = fn() { a }; f();", "let a = 1;\nlet f = fn() {\n    a;\n};\nf();\n"},
+		{"export let a = 1; let b = 2; m.b; 0;", "export let a = 1;\nm.b;\n0;\n"},
+	})
+}
+
+func TestOptimize(t *testing.T) {
+	t.Run("passes enable each other", func(t *testing.T) {
+		input := `let debug = 1 > 2;
+let day = 60 * 60 * 24;
+if (!debug == true) { puts(day); }
+if (debug) { puts("debugging"); }
+0;`
+
+		assert.Equal(t, "let debug = false;\nlet day = 86400;\nif (!debug == true) {\n    puts(day);\n}\nif (debug) {\n    puts(\"debugging\");\n}\n0;\n", optimize(t, input, ConstantFolding()))
+		assert.Equal(t, "let day = 86400;\nputs(day);\n\n0;\n", optimize(t, input, constantPropagation(), ConstantFolding(), DeadBranchElimination(), UnusedLetRemoval()))
+	})
+
+	t.Run("default passes", func(t *testing.T) {
+		assert.Equal(t, "puts(2);\n", optimize(t, "let a = 1; if (1 < 2) { puts(1 + 1) }"))
+	})
+
+	t.Run("preserves results of corpus", func(t *testing.T) {
+		for _, program := range corpus.Programs {
+			t.Run(program.Name, func(t *testing.T) {
+				optimized := testutil.MustParse(t, program.Input)
+				Optimize(optimized)
+
+				c := compiler.NewCompiler()
+				require.NoError(t, c.Compile(optimized))
+				machine := vm.NewVM(c.Bytecode())
+				err := machine.Run()
+
+				if program.Error != "" {
+					assert.EqualError(t, err, program.Error)
+				} else {
+					require.NoError(t, err)
+					assert.Equal(t, program.Expected, machine.LastPoppedStackElem().Inspect())
+				}
+			})
+		}
+	})
+}
+
+// constantPropagation is a pass as it could be implemented outside of this
+// package. It replaces the identifier debug by the boolean it is bound to.
+func constantPropagation() Pass {
+	return NewPass("debug-propagation", func(program *ast.Program) bool {
+		var binding *ast.LetStatement
+		for _, stmt := range program.Statements {
+			if let, ok := stmt.(*ast.LetStatement); ok && let.Name.Value == "debug" {
+				binding = let
+			}
+		}
+		if binding == nil {
+			return false
+		}
+		value, ok := binding.Value.(*ast.BooleanLiteral)
+		if !ok {
+			return false
+		}
+
+		changed := false
+		ast.Rewrite(program, func(node ast.Node) ast.Node {
+			if ident, ok := node.(*ast.Identifier); ok && ident.Value == "debug" && ident != binding.Name {
+				changed = true
+				return value
+			}
+			return node
+		})
+		return changed
+	})
+}
+
+func runOptimizerTests(t *testing.T, passes []Pass, tests []optimizerTest) {
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("tests[%d]", i), func(t *testing.T) {
+			assert.Equal(t, test.expected, optimize(t, test.input, passes...))
+		})
+	}
+}
+
+func optimize(t *testing.T, input string, passes ...Pass) string {
+	program := testutil.MustParse(t, input)
+	Optimize(program, passes...)
+
+	var out bytes.Buffer
+	require.NoError(t, printer.Fprint(&out, program))
+	return out.String()
+}
@@ -0,0 +1,107 @@
+package optimizer
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+)
+
+// UnusedLetRemoval returns a pass which removes let statements whose name is
+// never referenced and whose value cannot fail or have side effects, like
+// literals and function literals. Exported let statements are kept, as they
+// may be referenced by the programs importing them.
+//
+// Names are compared without regard to scopes, so a let statement is kept if
+// any binding of the same name is used. The last statement of a block is kept
+// as well, because removing it would change the value of the block.
+func UnusedLetRemoval() Pass {
+	return NewPass("unused-let-removal", func(program *ast.Program) bool {
+		used := usedNames(program)
+		changed := false
+
+		program.Statements = removeUnusedLets(program.Statements, used, &changed)
+		ast.Inspect(program, func(node ast.Node) bool {
+			if block, ok := node.(*ast.BlockStatement); ok {
+				block.Statements = removeUnusedLets(block.Statements, used, &changed)
+			}
+			return true
+		})
+
+		return changed
+	})
+}
+
+func removeUnusedLets(statements []ast.Statement, used map[string]bool, changed *bool) []ast.Statement {
+	result := make([]ast.Statement, 0, len(statements))
+
+	for i, stmt := range statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if ok && i < len(statements)-1 && !let.Exported() && !used[let.Name.Value] && isPure(let.Value) {
+			*changed = true
+			continue
+		}
+		result = append(result, stmt)
+	}
+
+	return result
+}
+
+// usedNames returns the names of all identifiers which are referenced in
+// program. The names of let statements, imports, parameters and catch clauses
+// and the properties of member expressions are not references.
+func usedNames(program *ast.Program) map[string]bool {
+	bindings := make(map[*ast.Identifier]bool)
+	used := make(map[string]bool)
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			bindings[node.Name] = true
+		case *ast.ImportStatement:
+			bindings[node.Name] = true
+		case *ast.MemberExpression:
+			bindings[node.Property] = true
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				bindings[param] = true
+			}
+		case *ast.TryExpression:
+			bindings[node.Parameter] = true
+		case *ast.Identifier:
+			if !bindings[node] {
+				used[node.Value] = true
+			}
+		}
+		return true
+	})
+
+	return used
+}
+
+// isPure reports whether evaluating exp can neither fail nor have side effects.
+func isPure(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.BooleanLiteral, *ast.StringLiteral, *ast.FunctionLiteral:
+		return true
+	case *ast.ArrayLiteral:
+		for _, element := range exp.Elements {
+			if !isPure(element) {
+				return false
+			}
+		}
+		return true
+	case *ast.HashLiteral:
+		for _, pair := range exp.Pairs {
+			// Only literals are guaranteed to be usable as keys.
+			switch pair.Key.(type) {
+			case *ast.IntegerLiteral, *ast.BooleanLiteral, *ast.StringLiteral:
+			default:
+				return false
+			}
+			if !isPure(pair.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
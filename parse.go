@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/fabiante/monkeylang/ast/astjson"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/parser"
+	"os"
+)
+
+// parseCmd parses the given file and prints its AST. By default, the debug
+// representation of each statement is printed. With -json, the AST is printed
+// as JSON as described in package astjson. With -trace, the parse functions are
+// traced to stderr.
+func parseCmd(args []string) int {
+	flags := flag.NewFlagSet("parse", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the AST as JSON")
+	trace := flags.Bool("trace", false, "print a trace of the parse functions to stderr")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: monkey parse [--json] [--trace] <file | ->")
+		return exitUsage
+	}
+
+	input, err := readSource(flags.Arg(0))
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	var opts []parser.Option
+	if *trace {
+		opts = append(opts, parser.WithTrace(os.Stderr))
+	}
+
+	par := parser.NewParser(lexer.NewLexer(input), opts...)
+	program := par.ParseProgram()
+	if errs := par.ErrorList(); len(errs) > 0 {
+		printParseErrors(sourceName(flags.Arg(0)), errs)
+		return exitError
+	}
+
+	if !*asJSON {
+		for _, stmt := range program.Statements {
+			_, _ = fmt.Println(stmt.String())
+		}
+		return exitOK
+	}
+
+	data, err := astjson.Marshal(program)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	out.WriteString("\n")
+
+	_, _ = os.Stdout.Write(out.Bytes())
+	return exitOK
+}
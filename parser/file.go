@@ -0,0 +1,311 @@
+package parser
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/token"
+	"sort"
+	"strings"
+)
+
+// File is a parsed source text which can be parsed again incrementally after
+// it has been edited, like the documents of an editor.
+type File struct {
+	Input   string
+	Program *ast.Program
+	// Errors contains the errors of the lexer followed by the errors of all
+	// statements in source order. Like in Parser.ErrorList, errors following
+	// an error of the lexer on the same line are left out.
+	Errors []*Error
+
+	// entries describes the top-level statements of the program including
+	// those which could not be parsed.
+	entries []entry
+	// lexerErrors are the errors of the lexer ordered by offset.
+	lexerErrors []*Error
+}
+
+// entry is the result of parsing a single top-level statement.
+type entry struct {
+	// first is the first token of the statement.
+	first token.Token
+	// stmt is nil if the statement could not be parsed.
+	stmt ast.Statement
+	errs []*Error
+}
+
+// Edit describes the replacement of a part of the input of a File.
+type Edit struct {
+	// Start and End are the byte offsets of the replaced text in the old
+	// input.
+	Start int
+	End   int
+	// Text is the new text.
+	Text string
+}
+
+// ParseFile parses input into a File.
+func ParseFile(input string) *File {
+	p := NewParser(lexer.NewLexer(input))
+	entries := p.parseEntries(nil)
+	return newFile(input, entries, p.comments, lexerErrors(p.lexer.Errors()))
+}
+
+func newFile(input string, entries []entry, comments []token.Token, lexerErrs []*Error) *File {
+	f := &File{Input: input, Program: ast.NewProgram(), entries: entries, lexerErrors: lexerErrs}
+	var parserErrs []*Error
+	for _, e := range entries {
+		if e.stmt != nil {
+			f.Program.Statements = append(f.Program.Statements, e.stmt)
+		}
+		parserErrs = append(parserErrs, e.errs...)
+	}
+	f.Errors = mergeErrors(lexerErrs, parserErrs)
+	f.Program.Comments = comments
+	return f
+}
+
+// Update applies edit to the input of f and parses the result. The result is
+// the same as that of ParseFile for the new input, but top-level statements
+// which are not affected by the edit are reused instead of being parsed again.
+//
+// Statements following the edit are shared with f and their positions are
+// updated in place, so f must not be used after calling Update.
+func (f *File) Update(edit Edit) *File {
+	input := f.Input[:edit.Start] + edit.Text + f.Input[edit.End:]
+
+	// The parser looks one token ahead, so a statement depends on the
+	// first token of the following statement. Statements are reused up to
+	// the last one whose following first token ends before the edit, and
+	// parsing is restarted at that token.
+	restart := sort.Search(len(f.entries), func(i int) bool {
+		return f.entries[i].first.End().Offset >= edit.Start
+	}) - 1
+
+	lex := lexer.NewLexer(input)
+	var prefix []entry
+	var comments []token.Token
+	var lexerErrs []*Error
+	if restart >= 0 {
+		start := f.entries[restart].first.Pos
+		lex = lexer.NewLexerAt(input, start)
+		prefix = f.entries[:restart]
+		comments = between(f.Program.Comments, commentOffset, 0, start.Offset)
+		lexerErrs = between(f.lexerErrors, errorOffset, 0, start.Offset)
+	}
+
+	// Statements which start after the edit are reused once the parser
+	// arrives at the start of one of them. From there on, the same tokens
+	// follow as before the edit.
+	delta := newShift(f.Input, input, edit)
+	sync := -1
+	p := NewParser(lex)
+	entries := p.parseEntries(func(first token.Token) bool {
+		old := first.Pos.Offset - delta.offset
+		if old < edit.End {
+			return false
+		}
+		i := sort.Search(len(f.entries), func(i int) bool { return f.entries[i].first.Pos.Offset >= old })
+		if i < len(f.entries) && f.entries[i].first.Pos.Offset == old {
+			sync = i
+			return true
+		}
+		return false
+	})
+
+	entries = append(prefix[:len(prefix):len(prefix)], entries...)
+	if sync < 0 {
+		comments = append(comments, p.comments...)
+		lexerErrs = append(lexerErrs, lexerErrors(lex.Errors())...)
+		return newFile(input, entries, comments, lexerErrs)
+	}
+
+	// The new lexer has already read the tokens following the start of the
+	// reused statements, so its comments and errors are only used up to it.
+	syncOffset := f.entries[sync].first.Pos.Offset
+	comments = append(comments, between(p.comments, commentOffset, 0, syncOffset+delta.offset)...)
+	for _, comment := range between(f.Program.Comments, commentOffset, syncOffset, len(f.Input)) {
+		comment.Pos = delta.position(comment.Pos)
+		comments = append(comments, comment)
+	}
+	lexerErrs = append(lexerErrs, between(lexerErrors(lex.Errors()), errorOffset, 0, syncOffset+delta.offset)...)
+	for _, err := range between(f.lexerErrors, errorOffset, syncOffset, len(f.Input)) {
+		err.Pos = delta.position(err.Pos)
+		lexerErrs = append(lexerErrs, err)
+	}
+	for _, e := range f.entries[sync:] {
+		entries = append(entries, delta.entry(e))
+	}
+	return newFile(input, entries, comments, lexerErrs)
+}
+
+// parseEntries parses top-level statements until the end of the input. If
+// stop is not nil, it is called with the first token of each statement and
+// parsing stops if it returns true.
+func (p *Parser) parseEntries(stop func(first token.Token) bool) []entry {
+	var entries []entry
+
+	for !p.currTokenIs(token.EOF) {
+		if stop != nil && stop(p.currToken) {
+			break
+		}
+
+		e := entry{first: p.currToken}
+		errs := len(p.errors)
+		e.stmt = p.parseStatement()
+		e.errs = p.errors[errs:len(p.errors):len(p.errors)]
+		entries = append(entries, e)
+
+		p.nextToken()
+	}
+
+	return entries
+}
+
+// between returns the elements of s, which is ordered by offset, whose offsets
+// are in [start, end), or nil if there are none. Appending to the result does
+// not modify s.
+func between[T any](s []T, offset func(T) int, start, end int) []T {
+	i := sort.Search(len(s), func(i int) bool { return offset(s[i]) >= start })
+	j := sort.Search(len(s), func(i int) bool { return offset(s[i]) >= end })
+	if i == j {
+		return nil
+	}
+	return s[i:j:j]
+}
+
+func commentOffset(comment token.Token) int { return comment.Pos.Offset }
+
+func errorOffset(err *Error) int { return err.Pos.Offset }
+
+// shift maps positions following an edit in the old input to the new input.
+type shift struct {
+	offset int
+	line   int
+	// editLine is the line on which the edit ends in the old input.
+	// Columns are only shifted on this line.
+	editLine int
+	column   int
+}
+
+func newShift(oldInput, newInput string, edit Edit) shift {
+	oldEnd := positionAt(oldInput, edit.End)
+	newEnd := positionAt(newInput, edit.Start+len(edit.Text))
+	return shift{
+		offset:   newEnd.Offset - oldEnd.Offset,
+		line:     newEnd.Line - oldEnd.Line,
+		editLine: oldEnd.Line,
+		column:   newEnd.Column - oldEnd.Column,
+	}
+}
+
+// positionAt returns the position of the given offset in input.
+func positionAt(input string, offset int) token.Position {
+	lineStart := strings.LastIndexByte(input[:offset], '\n') + 1
+	return token.Position{
+		Offset: offset,
+		Line:   strings.Count(input[:offset], "\n") + 1,
+		Column: offset - lineStart + 1,
+	}
+}
+
+func (s shift) position(pos token.Position) token.Position {
+	if pos.Line == s.editLine {
+		pos.Column += s.column
+	}
+	pos.Line += s.line
+	pos.Offset += s.offset
+	return pos
+}
+
+func (s shift) token(t *token.Token) {
+	t.Pos = s.position(t.Pos)
+}
+
+// entry updates the positions of e and its statement.
+func (s shift) entry(e entry) entry {
+	s.token(&e.first)
+	for _, err := range e.errs {
+		err.Pos = s.position(err.Pos)
+	}
+	if e.stmt == nil {
+		return e
+	}
+
+	ast.Inspect(e.stmt, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.LetStatement:
+			if n.Exported() {
+				s.token(&n.Export)
+			}
+			s.token(&n.Token)
+			s.semicolon(&n.Semicolon)
+		case *ast.ImportStatement:
+			s.token(&n.Token)
+			s.semicolon(&n.Semicolon)
+		case *ast.ReturnStatement:
+			s.token(&n.Token)
+			s.semicolon(&n.Semicolon)
+		case *ast.ThrowStatement:
+			s.token(&n.Token)
+			s.semicolon(&n.Semicolon)
+		case *ast.ExpressionStatement:
+			s.token(&n.Token)
+			s.semicolon(&n.Semicolon)
+		case *ast.BlockStatement:
+			s.token(&n.Token)
+			s.token(&n.RBrace)
+		case *ast.Identifier:
+			s.token(&n.Token)
+		case *ast.IntegerLiteral:
+			s.token(&n.Token)
+		case *ast.BooleanLiteral:
+			s.token(&n.Token)
+		case *ast.StringLiteral:
+			s.token(&n.Token)
+		case *ast.PrefixExpression:
+			s.token(&n.Token)
+		case *ast.InfixExpression:
+			s.token(&n.Token)
+		case *ast.IfExpression:
+			s.token(&n.Token)
+		case *ast.TryExpression:
+			s.token(&n.Token)
+		case *ast.FunctionLiteral:
+			s.token(&n.Token)
+		case *ast.CallExpression:
+			s.token(&n.Token)
+			s.token(&n.RParen)
+		case *ast.ArrayLiteral:
+			s.token(&n.Token)
+			s.token(&n.RBracket)
+		case *ast.IndexExpression:
+			s.token(&n.Token)
+			s.token(&n.RBracket)
+		case *ast.MemberExpression:
+			s.token(&n.Token)
+		case *ast.HashLiteral:
+			s.token(&n.Token)
+			s.token(&n.RBrace)
+		case *ast.NamedType:
+			s.token(&n.Token)
+		case *ast.ArrayType:
+			s.token(&n.Token)
+			s.token(&n.RBracket)
+		case *ast.HashType:
+			s.token(&n.Token)
+			s.token(&n.RBrace)
+		case *ast.FunctionType:
+			s.token(&n.Token)
+		}
+		return true
+	})
+	return e
+}
+
+// semicolon shifts the optional semicolon of a statement.
+func (s shift) semicolon(t *token.Token) {
+	if t.Type == token.Semicolon {
+		s.token(t)
+	}
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFile_Update(t *testing.T) {
+	input := `let add = fn(a, b) { a + b }; // add
+let s = "x
+y";
+if (add(1, 2) > 2) { puts(s) } else { [1, 2][0] }
+// between
+let h = {"a": 1}
+h["a"]
+return -add(3, 4);
+`
+
+	t.Run("same as parsing the new input", func(t *testing.T) {
+		inputs := []string{input, "let a = 1 $;\n@ let b = 2x;\nb + \"c\n"}
+		texts := []string{"", " ", "\n", "x", "1", ";", "(", ")", "}", "\"", "//", "$", "let z = 1;", "fn(q) { q }", "else"}
+
+		for _, input := range inputs {
+			for start := 0; start <= len(input); start++ {
+				for _, length := range []int{0, 1, 3} {
+					end := min(start+length, len(input))
+					for _, text := range texts {
+						edit := Edit{Start: start, End: end, Text: text}
+						expected := ParseFile(input[:start] + text + input[end:])
+						actual := ParseFile(input).Update(edit)
+
+						name := fmt.Sprintf("%q %+v", input, edit)
+						require.Equal(t, expected.Input, actual.Input, name)
+						require.Equal(t, expected.Program, actual.Program, name)
+						require.Equal(t, expected.Errors, actual.Errors, name)
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("reuses unchanged statements", func(t *testing.T) {
+		old := ParseFile(input)
+		statements := old.Program.Statements
+		require.Len(t, statements, 6)
+
+		// Change the condition of the if expression.
+		offset := len("let add = fn(a, b) { a + b }; // add\nlet s = \"x\ny\";\nif (add(1, 2) > ")
+		f := old.Update(Edit{Start: offset, End: offset + 1, Text: "20"})
+
+		require.Empty(t, f.Errors)
+		require.Len(t, f.Program.Statements, 6)
+		assert.Same(t, statements[0], f.Program.Statements[0])
+		assert.Same(t, statements[1], f.Program.Statements[1])
+		assert.NotSame(t, statements[2], f.Program.Statements[2])
+		assert.Same(t, statements[3], f.Program.Statements[3])
+		assert.Same(t, statements[4], f.Program.Statements[4])
+		assert.Same(t, statements[5], f.Program.Statements[5])
+
+		assert.Equal(t, "if(add(1, 2) > 20) puts(s)else ([1, 2][0])", f.Program.Statements[2].String())
+		assert.Equal(t, "6:1", f.Program.Statements[3].Pos().String())
+		assert.Equal(t, "7:7", f.Program.Statements[4].End().String())
+		assert.Equal(t, "5:1", f.Program.Comments[1].Pos.String())
+	})
+
+	t.Run("updates error positions", func(t *testing.T) {
+		f := ParseFile("let a = 1;\nlet b = ;\n")
+		require.Len(t, f.Errors, 1)
+		assert.Equal(t, "2:9: no prefix parse fn for Semicolon", f.Errors[0].Error())
+
+		f = f.Update(Edit{Start: 0, End: 0, Text: "let c = 2;\n"})
+		require.Len(t, f.Errors, 1)
+		assert.Equal(t, "3:9: no prefix parse fn for Semicolon", f.Errors[0].Error())
+	})
+
+	t.Run("updates lexer error positions", func(t *testing.T) {
+		f := ParseFile("let a = 1;\nlet b = 2 $;\n")
+		require.Len(t, f.Errors, 1)
+		assert.Equal(t, "2:11: unexpected character '$'", f.Errors[0].Error())
+
+		f = f.Update(Edit{Start: 0, End: 0, Text: "let c = 2;\n"})
+		require.Len(t, f.Errors, 1)
+		assert.Equal(t, "3:11: unexpected character '$'", f.Errors[0].Error())
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/fabiante/monkeylang/ast"
 	"github.com/fabiante/monkeylang/lexer"
 	"github.com/fabiante/monkeylang/token"
+	"io"
 	"strconv"
 )
 
@@ -22,13 +23,20 @@ type Parser struct {
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 
-	errors []string
+	// comments collects all comments skipped by nextToken.
+	comments []token.Token
+
+	errors []*Error
+
+	// tracer receives the trace of parse functions if tracing is enabled, see WithTrace.
+	tracer     io.Writer
+	traceLevel int
 }
 
-func NewParser(lexer *lexer.Lexer) *Parser {
+func NewParser(lexer *lexer.Lexer, opts ...Option) *Parser {
 	p := &Parser{
 		lexer:          lexer,
-		errors:         make([]string, 0),
+		errors:         make([]*Error, 0),
 		prefixParseFns: make(map[token.TokenType]prefixParseFn),
 		infixParseFns:  make(map[token.TokenType]infixParseFn),
 	}
@@ -40,6 +48,12 @@ func NewParser(lexer *lexer.Lexer) *Parser {
 	p.registerPrefixParseFn(token.Bang, p.parsePrefixExpression)
 	p.registerPrefixParseFn(token.Minus, p.parsePrefixExpression)
 	p.registerPrefixParseFn(token.LParen, p.parseGroupedExpression)
+	p.registerPrefixParseFn(token.String, p.parseStringLiteral)
+	p.registerPrefixParseFn(token.If, p.parseIfExpression)
+	p.registerPrefixParseFn(token.Try, p.parseTryExpression)
+	p.registerPrefixParseFn(token.Func, p.parseFunctionLiteral)
+	p.registerPrefixParseFn(token.LBracket, p.parseArrayLiteral)
+	p.registerPrefixParseFn(token.LBrace, p.parseHashLiteral)
 
 	p.registerInfixParseFn(token.EQ, p.parseInfixExpression)
 	p.registerInfixParseFn(token.NEQ, p.parseInfixExpression)
@@ -49,6 +63,13 @@ func NewParser(lexer *lexer.Lexer) *Parser {
 	p.registerInfixParseFn(token.Minus, p.parseInfixExpression)
 	p.registerInfixParseFn(token.Slash, p.parseInfixExpression)
 	p.registerInfixParseFn(token.Asterisk, p.parseInfixExpression)
+	p.registerInfixParseFn(token.LParen, p.parseCallExpression)
+	p.registerInfixParseFn(token.LBracket, p.parseIndexExpression)
+	p.registerInfixParseFn(token.Dot, p.parseMemberExpression)
+
+	for _, opt := range opts {
+		opt(p)
+	}
 
 	p.nextToken()
 	p.nextToken()
@@ -58,29 +79,47 @@ func NewParser(lexer *lexer.Lexer) *Parser {
 func (p *Parser) ParseProgram() *ast.Program {
 	prog := ast.NewProgram()
 
-	for !p.currTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			prog.Statements = append(prog.Statements, stmt)
+	for _, e := range p.parseEntries(nil) {
+		if e.stmt != nil {
+			prog.Statements = append(prog.Statements, e.stmt)
 		}
-		p.nextToken()
 	}
 
+	prog.Comments = p.comments
+
 	return prog
 }
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.currToken.Type {
 	case token.Let:
-		return p.parseLetStatement()
+		// avoid returning a typed nil pointer as non-nil statement
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case token.Return:
 		return p.parseReturnStatement()
+	case token.Throw:
+		return p.parseThrowStatement()
+	case token.Import:
+		if stmt := p.parseImportStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.Export:
+		if stmt := p.parseExportStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
+	defer p.trace("parseLetStatement")()
+
 	stmt := &ast.LetStatement{
 		Token: p.currToken,
 		Name:  nil,
@@ -96,40 +135,128 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		Value: p.currToken.Literal,
 	}
 
+	if p.peekTokenIs(token.Colon) {
+		p.nextToken()
+		if stmt.Name.Type = p.parseTypeAnnotation(); stmt.Name.Type == nil {
+			return nil
+		}
+	}
+
 	if !p.expectPeek(token.Assign) {
 		return nil
 	}
 
-	// TODO: Parse expression instead of ignoring it and skipping to semicolon
-	for !p.currTokenIs(token.Semicolon) {
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(lowest)
+
+	// Functions know the name they are bound to. This allows them to refer
+	// to themselves, which is required for recursion.
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		fn.Name = stmt.Name.Value
+	}
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+		stmt.Semicolon = p.currToken
+	}
+
+	return stmt
+}
+
+// parseExportStatement parses a let statement preceded by the export keyword.
+func (p *Parser) parseExportStatement() *ast.LetStatement {
+	defer p.trace("parseExportStatement")()
+
+	export := p.currToken
+	if !p.expectPeek(token.Let) {
+		return nil
+	}
+
+	stmt := p.parseLetStatement()
+	if stmt != nil {
+		stmt.Export = export
+	}
+	return stmt
+}
+
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	defer p.trace("parseImportStatement")()
+
+	stmt := &ast.ImportStatement{
+		Token: p.currToken,
+	}
+
+	if !p.expectPeek(token.String) {
+		return nil
+	}
+	stmt.Path = &ast.StringLiteral{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeek(token.As) || !p.expectPeek(token.Identifier) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	if p.peekTokenIs(token.Semicolon) {
 		p.nextToken()
+		stmt.Semicolon = p.currToken
 	}
 
 	return stmt
 }
 
 func (p *Parser) parseReturnStatement() ast.Statement {
+	defer p.trace("parseReturnStatement")()
+
 	stmt := &ast.ReturnStatement{
 		Token:       p.currToken,
 		ReturnValue: nil,
 	}
 
-	// TODO: Parse expression instead of ignoring it and skipping to semicolon
-	for !p.currTokenIs(token.Semicolon) {
+	p.nextToken()
+
+	stmt.ReturnValue = p.parseExpression(lowest)
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+		stmt.Semicolon = p.currToken
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseThrowStatement() ast.Statement {
+	defer p.trace("parseThrowStatement")()
+
+	stmt := &ast.ThrowStatement{
+		Token: p.currToken,
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(lowest)
+
+	if p.peekTokenIs(token.Semicolon) {
 		p.nextToken()
+		stmt.Semicolon = p.currToken
 	}
 
 	return stmt
 }
 
 func (p *Parser) parseExpressionStatement() ast.Statement {
+	defer p.trace("parseExpressionStatement")()
+
+	// The token has to be read before parsing the expression, which advances
+	// currToken. The evaluation order within composite literals is unspecified.
 	stmt := &ast.ExpressionStatement{
-		Token:      p.currToken,
-		Expression: p.parseExpression(lowest),
+		Token: p.currToken,
 	}
+	stmt.Expression = p.parseExpression(lowest)
 
 	if p.peekTokenIs(token.Semicolon) {
 		p.nextToken()
+		stmt.Semicolon = p.currToken
 	}
 
 	return stmt
@@ -137,6 +264,8 @@ func (p *Parser) parseExpressionStatement() ast.Statement {
 
 // parseExpression parses an expression starting with the currToken and the given precedence.
 func (p *Parser) parseExpression(precedence precedence) ast.Expression {
+	defer p.traceExpression("parseExpression", precedence)()
+
 	prefix := p.prefixParseFns[p.currToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.currToken.Type)
@@ -157,13 +286,21 @@ func (p *Parser) parseExpression(precedence precedence) ast.Expression {
 	return leftExp
 }
 
+// nextToken advances both currToken and peekToken. Comments are not relevant
+// for parsing and are therefore skipped and collected in comments.
 func (p *Parser) nextToken() {
 	p.currToken = p.peekToken
 	peek := p.lexer.NextToken()
+	for peek.Type == token.Comment {
+		p.comments = append(p.comments, peek)
+		peek = p.lexer.NextToken()
+	}
 	p.peekToken = peek
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
+	defer p.trace("parseIdentifier")()
+
 	return &ast.Identifier{
 		Token: p.currToken,
 		Value: p.currToken.Literal,
@@ -171,11 +308,13 @@ func (p *Parser) parseIdentifier() ast.Expression {
 }
 
 func (p *Parser) parseIntLiteral() ast.Expression {
+	defer p.trace("parseIntLiteral")()
+
 	literal := p.currToken.Literal
 
 	value, err := strconv.ParseInt(literal, 0, 64)
 	if err != nil {
-		p.errors = append(p.errors, fmt.Sprintf("could not parse %s as integer", literal))
+		p.errorf(p.currToken.Pos, "could not parse %s as integer", literal)
 		return nil
 	}
 
@@ -186,6 +325,8 @@ func (p *Parser) parseIntLiteral() ast.Expression {
 }
 
 func (p *Parser) parseBoolLiteral() ast.Expression {
+	defer p.trace("parseBoolLiteral")()
+
 	return &ast.BooleanLiteral{
 		Token: p.currToken,
 		Value: p.currTokenIs(token.True),
@@ -193,6 +334,8 @@ func (p *Parser) parseBoolLiteral() ast.Expression {
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
+	defer p.traceExpression("parsePrefixExpression", prefix)()
+
 	exp := &ast.PrefixExpression{
 		Token:    p.currToken,
 		Operator: p.currToken.Literal,
@@ -207,6 +350,8 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 }
 
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	defer p.trace("parseGroupedExpression")()
+
 	p.nextToken()
 
 	exp := p.parseExpression(lowest)
@@ -219,6 +364,8 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+	defer p.traceExpression("parseInfixExpression", p.currPrecedence())()
+
 	exp := &ast.InfixExpression{
 		Token:    p.currToken,
 		Operator: p.currToken.Literal,
@@ -233,6 +380,409 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+func (p *Parser) parseStringLiteral() ast.Expression {
+	defer p.trace("parseStringLiteral")()
+
+	return &ast.StringLiteral{
+		Token: p.currToken,
+		Value: p.currToken.Literal,
+	}
+}
+
+// parseBlockStatement parses statements until the closing brace of the block.
+// It expects currToken to be the opening brace.
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	defer p.trace("parseBlockStatement")()
+
+	block := &ast.BlockStatement{
+		Token:      p.currToken,
+		Statements: make([]ast.Statement, 0),
+	}
+
+	p.nextToken()
+
+	for !p.currTokenIs(token.RBrace) && !p.currTokenIs(token.EOF) {
+		stmt := p.parseStatement()
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+		p.nextToken()
+	}
+
+	if !p.currTokenIs(token.RBrace) {
+		p.errorf(p.currToken.Pos, "expected closing brace of block, got end of input")
+		return nil
+	}
+
+	block.RBrace = p.currToken
+
+	return block
+}
+
+func (p *Parser) parseIfExpression() ast.Expression {
+	defer p.trace("parseIfExpression")()
+
+	exp := &ast.IfExpression{
+		Token: p.currToken,
+	}
+
+	// The condition is usually wrapped in parentheses, which are parsed
+	// as grouped expression.
+	p.nextToken()
+	exp.Condition = p.parseExpression(lowest)
+
+	if !p.expectPeek(token.LBrace) {
+		return nil
+	}
+
+	exp.Consequence = p.parseBlockStatement()
+	if exp.Consequence == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.Else) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBrace) {
+			return nil
+		}
+
+		exp.Alternative = p.parseBlockStatement()
+		if exp.Alternative == nil {
+			return nil
+		}
+	}
+
+	return exp
+}
+
+func (p *Parser) parseTryExpression() ast.Expression {
+	defer p.trace("parseTryExpression")()
+
+	exp := &ast.TryExpression{
+		Token: p.currToken,
+	}
+
+	if !p.expectPeek(token.LBrace) {
+		return nil
+	}
+
+	exp.Block = p.parseBlockStatement()
+	if exp.Block == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.Catch) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LParen) || !p.expectPeek(token.Identifier) {
+			return nil
+		}
+		exp.Parameter = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+		if !p.expectPeek(token.RParen) || !p.expectPeek(token.LBrace) {
+			return nil
+		}
+
+		exp.Catch = p.parseBlockStatement()
+		if exp.Catch == nil {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.Finally) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBrace) {
+			return nil
+		}
+
+		exp.Finally = p.parseBlockStatement()
+		if exp.Finally == nil {
+			return nil
+		}
+	}
+
+	if exp.Catch == nil && exp.Finally == nil {
+		if !p.peekTokenIs(token.Illegal) {
+			p.errorf(p.peekToken.Pos, "expected %s or %s, got %s instead", token.Catch, token.Finally, p.peekToken.Type)
+		}
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	defer p.trace("parseFunctionLiteral")()
+
+	fn := &ast.FunctionLiteral{
+		Token: p.currToken,
+	}
+
+	if !p.expectPeek(token.LParen) {
+		return nil
+	}
+
+	fn.Parameters = p.parseFunctionParameters()
+	if fn.Parameters == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.Arrow) {
+		p.nextToken()
+		if fn.ReturnType = p.parseTypeAnnotation(); fn.ReturnType == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.LBrace) {
+		return nil
+	}
+
+	fn.Body = p.parseBlockStatement()
+	if fn.Body == nil {
+		return nil
+	}
+
+	return fn
+}
+
+// parseFunctionParameters parses a comma separated list of identifiers, each
+// optionally followed by a type annotation. It
+// expects currToken to be the opening parenthesis and returns nil on errors.
+func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+	params := make([]*ast.Identifier, 0)
+
+	if p.peekTokenIs(token.RParen) {
+		p.nextToken()
+		return params
+	}
+
+	for {
+		if !p.expectPeek(token.Identifier) {
+			return nil
+		}
+
+		param := &ast.Identifier{
+			Token: p.currToken,
+			Value: p.currToken.Literal,
+		}
+		if p.peekTokenIs(token.Colon) {
+			p.nextToken()
+			if param.Type = p.parseTypeAnnotation(); param.Type == nil {
+				return nil
+			}
+		}
+		params = append(params, param)
+
+		if !p.peekTokenIs(token.Comma) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RParen) {
+		return nil
+	}
+
+	return params
+}
+
+// parseTypeAnnotation parses the type following a colon or arrow, which is
+// expected to be currToken. It returns nil on errors.
+func (p *Parser) parseTypeAnnotation() ast.TypeExpression {
+	p.nextToken()
+	return p.parseType()
+}
+
+// parseType parses a type starting with currToken. It returns nil on errors.
+func (p *Parser) parseType() ast.TypeExpression {
+	defer p.trace("parseType")()
+
+	switch p.currToken.Type {
+	case token.Identifier:
+		return &ast.NamedType{Token: p.currToken, Name: p.currToken.Literal}
+	case token.LBracket:
+		typ := &ast.ArrayType{Token: p.currToken}
+		p.nextToken()
+		if typ.Element = p.parseType(); typ.Element == nil || !p.expectPeek(token.RBracket) {
+			return nil
+		}
+		typ.RBracket = p.currToken
+		return typ
+	case token.LBrace:
+		typ := &ast.HashType{Token: p.currToken}
+		p.nextToken()
+		if typ.Key = p.parseType(); typ.Key == nil || !p.expectPeek(token.Colon) {
+			return nil
+		}
+		if typ.Value = p.parseTypeAnnotation(); typ.Value == nil || !p.expectPeek(token.RBrace) {
+			return nil
+		}
+		typ.RBrace = p.currToken
+		return typ
+	case token.Func:
+		typ := &ast.FunctionType{Token: p.currToken, Parameters: make([]ast.TypeExpression, 0)}
+		if !p.expectPeek(token.LParen) {
+			return nil
+		}
+		for !p.peekTokenIs(token.RParen) {
+			param := p.parseTypeAnnotation()
+			if param == nil {
+				return nil
+			}
+			typ.Parameters = append(typ.Parameters, param)
+			if !p.peekTokenIs(token.RParen) && !p.expectPeek(token.Comma) {
+				return nil
+			}
+		}
+		p.nextToken()
+		if !p.expectPeek(token.Arrow) {
+			return nil
+		}
+		if typ.ReturnType = p.parseTypeAnnotation(); typ.ReturnType == nil {
+			return nil
+		}
+		return typ
+	default:
+		if !p.currTokenIs(token.Illegal) {
+			p.errorf(p.currToken.Pos, "expected type, got %s", p.currToken.Type)
+		}
+		return nil
+	}
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	defer p.traceExpression("parseCallExpression", call)()
+
+	exp := &ast.CallExpression{
+		Token:    p.currToken,
+		Function: function,
+	}
+
+	exp.Arguments = p.parseExpressionList(token.RParen)
+	if exp.Arguments == nil {
+		return nil
+	}
+	exp.RParen = p.currToken
+
+	return exp
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	defer p.trace("parseArrayLiteral")()
+
+	array := &ast.ArrayLiteral{
+		Token: p.currToken,
+	}
+
+	array.Elements = p.parseExpressionList(token.RBracket)
+	if array.Elements == nil {
+		return nil
+	}
+	array.RBracket = p.currToken
+
+	return array
+}
+
+// parseExpressionList parses a comma separated list of expressions until the
+// given end token. It expects currToken to be the token before the first
+// expression and returns nil on errors.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := make([]ast.Expression, 0)
+
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+
+	for {
+		p.nextToken()
+		list = append(list, p.parseExpression(lowest))
+
+		if !p.peekTokenIs(token.Comma) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return list
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	defer p.traceExpression("parseIndexExpression", index)()
+
+	exp := &ast.IndexExpression{
+		Token: p.currToken,
+		Left:  left,
+	}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(lowest)
+
+	if !p.expectPeek(token.RBracket) {
+		return nil
+	}
+	exp.RBracket = p.currToken
+
+	return exp
+}
+
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	defer p.traceExpression("parseMemberExpression", index)()
+
+	exp := &ast.MemberExpression{
+		Token:  p.currToken,
+		Object: left,
+	}
+
+	if !p.expectPeek(token.Identifier) {
+		return nil
+	}
+	exp.Property = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	return exp
+}
+
+func (p *Parser) parseHashLiteral() ast.Expression {
+	defer p.trace("parseHashLiteral")()
+
+	hash := &ast.HashLiteral{
+		Token: p.currToken,
+		Pairs: make([]ast.HashPair, 0),
+	}
+
+	for !p.peekTokenIs(token.RBrace) {
+		p.nextToken()
+		key := p.parseExpression(lowest)
+
+		if !p.expectPeek(token.Colon) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(lowest)
+
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(token.RBrace) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBrace) {
+		return nil
+	}
+	hash.RBrace = p.currToken
+
+	return hash
+}
+
 // expectPeek checks if the next token is of the given type,
 //
 // If it is, it will advance to the next token by calling nextToken and return true.
@@ -248,18 +798,86 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
+// peekError reports that the peek token is not of type t. Illegal tokens are
+// not reported, as the lexer already reported an error for each of them.
 func (p *Parser) peekError(t token.TokenType) {
-	err := fmt.Sprintf("expected token type %d, got %d instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, err)
+	if p.peekTokenIs(token.Illegal) {
+		return
+	}
+	p.errorf(p.peekToken.Pos, "expected %s, got %s instead", t, p.peekToken.Type)
 }
 
+// noPrefixParseFnError reports that no expression starts with a token of type
+// t. Like in peekError, illegal tokens are not reported.
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	err := fmt.Sprintf("no prefix parse fn for token type %d", t)
-	p.errors = append(p.errors, err)
+	if t == token.Illegal {
+		return
+	}
+	p.errorf(p.currToken.Pos, "no prefix parse fn for %s", t)
 }
 
+// Errors returns the messages of all errors found while parsing.
 func (p *Parser) Errors() []string {
-	return p.errors
+	errs := p.ErrorList()
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Msg)
+	}
+	return messages
+}
+
+// ErrorList returns all errors found while parsing together with their
+// positions. The errors of the lexer come first, as they are usually the
+// cause of the errors of the parser. Errors of the parser which follow an
+// error of the lexer on the same line are left out, see mergeErrors.
+func (p *Parser) ErrorList() []*Error {
+	return mergeErrors(lexerErrors(p.lexer.Errors()), p.errors)
+}
+
+// mergeErrors returns the errors of the lexer followed by those of the
+// parser. An error of the parser which is located after an error of the lexer
+// on the same line is usually only caused by it, for example by the tokens
+// following an unexpected character, and is therefore left out.
+func mergeErrors(lexerErrs, parserErrs []*Error) []*Error {
+	// first maps lines to the column of the first lexer error on them.
+	first := make(map[int]int, len(lexerErrs))
+	for _, err := range lexerErrs {
+		if column, ok := first[err.Pos.Line]; !ok || err.Pos.Column < column {
+			first[err.Pos.Line] = err.Pos.Column
+		}
+	}
+
+	merged := append([]*Error(nil), lexerErrs...)
+	for _, err := range parserErrs {
+		if column, ok := first[err.Pos.Line]; ok && err.Pos.Column >= column {
+			continue
+		}
+		merged = append(merged, err)
+	}
+	return merged
+}
+
+func lexerErrors(errs []*lexer.Error) []*Error {
+	converted := make([]*Error, len(errs))
+	for i, err := range errs {
+		converted[i] = &Error{Pos: err.Pos, Msg: err.Msg}
+	}
+	return converted
+}
+
+// Error is a syntax error.
+type Error struct {
+	// Pos is the position of the token at which the error was detected.
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+func (p *Parser) errorf(pos token.Position, format string, a ...any) {
+	p.errors = append(p.errors, &Error{Pos: pos, Msg: fmt.Sprintf(format, a...)})
 }
 
 func (p *Parser) currTokenIs(t token.TokenType) bool {
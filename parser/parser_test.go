@@ -3,10 +3,12 @@ package parser
 import (
 	"fmt"
 	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/internal/corpus"
 	"github.com/fabiante/monkeylang/lexer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -21,15 +23,101 @@ func TestParser_ParseProgram(t *testing.T) {
 		require.Len(t, par.Errors(), 1, "unexpected error count")
 	})
 
+	t.Run("error positions", func(t *testing.T) {
+		par := NewParser(lexer.NewLexer("let x = 1;\nlet y 5;\n)"))
+		_ = par.ParseProgram()
+
+		var errs []string
+		for _, err := range par.ErrorList() {
+			errs = append(errs, err.Error())
+		}
+		assert.Equal(t, []string{
+			"2:7: expected Assign, got Int instead",
+			"3:1: no prefix parse fn for RParen",
+		}, errs)
+	})
+
+	t.Run("lexer errors", func(t *testing.T) {
+		par := NewParser(lexer.NewLexer("let x = 1 @ 2;\nlet y = 3a;\nlet @ = 4;\nlet z 5;\nputs(\"a);"))
+		_ = par.ParseProgram()
+
+		var errs []string
+		for _, err := range par.ErrorList() {
+			errs = append(errs, err.Error())
+		}
+		assert.Equal(t, []string{
+			"1:11: unexpected character '@'",
+			`2:9: invalid number literal "3a"`,
+			"3:5: unexpected character '@'",
+			"5:6: unterminated string",
+			"4:7: expected Assign, got Int instead",
+		}, errs)
+	})
+
+	t.Run("errors following lexer errors", func(t *testing.T) {
+		for _, input := range []string{"5 % 2;", "puts(1 @ 2);", "let x = [1, %];"} {
+			par := NewParser(lexer.NewLexer(input))
+			_ = par.ParseProgram()
+
+			assert.Len(t, par.ErrorList(), 1, input)
+		}
+	})
+
+	t.Run("node spans", func(t *testing.T) {
+		input := "let f = fn(x: [int]) -> {string: int} {\n  x[0] + g(\"a\nb\")\n};\n-[1, {}]\nreturn (h);"
+		par := NewParser(lexer.NewLexer(input))
+		program := par.ParseProgram()
+		requireNoParserErrors(t, par)
+
+		var spans []string
+		ast.Inspect(program, func(node ast.Node) bool {
+			if node != nil {
+				spans = append(spans, fmt.Sprintf("%T %s-%s %q", node, node.Pos(), node.End(), input[node.Pos().Offset:node.End().Offset]))
+			}
+			return true
+		})
+		assert.Equal(t, []string{
+			`*ast.Program 1:1-6:12 ` + fmt.Sprintf("%q", input),
+			`*ast.LetStatement 1:1-4:3 "let f = fn(x: [int]) -> {string: int} {\n  x[0] + g(\"a\nb\")\n};"`,
+			`*ast.Identifier 1:5-1:6 "f"`,
+			`*ast.FunctionLiteral 1:9-4:2 "fn(x: [int]) -> {string: int} {\n  x[0] + g(\"a\nb\")\n}"`,
+			`*ast.Identifier 1:12-1:13 "x"`,
+			`*ast.ArrayType 1:15-1:20 "[int]"`,
+			`*ast.NamedType 1:16-1:19 "int"`,
+			`*ast.HashType 1:25-1:38 "{string: int}"`,
+			`*ast.NamedType 1:26-1:32 "string"`,
+			`*ast.NamedType 1:34-1:37 "int"`,
+			`*ast.BlockStatement 1:39-4:2 "{\n  x[0] + g(\"a\nb\")\n}"`,
+			`*ast.ExpressionStatement 2:3-3:4 "x[0] + g(\"a\nb\")"`,
+			`*ast.InfixExpression 2:3-3:4 "x[0] + g(\"a\nb\")"`,
+			`*ast.IndexExpression 2:3-2:7 "x[0]"`,
+			`*ast.Identifier 2:3-2:4 "x"`,
+			`*ast.IntegerLiteral 2:5-2:6 "0"`,
+			`*ast.CallExpression 2:10-3:4 "g(\"a\nb\")"`,
+			`*ast.Identifier 2:10-2:11 "g"`,
+			`*ast.StringLiteral 2:12-3:3 "\"a\nb\""`,
+			`*ast.ExpressionStatement 5:1-5:9 "-[1, {}]"`,
+			`*ast.PrefixExpression 5:1-5:9 "-[1, {}]"`,
+			`*ast.ArrayLiteral 5:2-5:9 "[1, {}]"`,
+			`*ast.IntegerLiteral 5:3-5:4 "1"`,
+			`*ast.HashLiteral 5:6-5:8 "{}"`,
+			`*ast.ReturnStatement 6:1-6:12 "return (h);"`,
+			`*ast.Identifier 6:9-6:10 "h"`,
+		}, spans)
+	})
+
 	t.Run("let statement", func(t *testing.T) {
-		input := `let x = 5;let y= 10;let foobar = 123;`
+		input := `let x = 5;let y= 10;let foobar = 123;let b = true;let z = y;`
 
 		tests := []struct {
 			expectedIdentifier string
+			expectedValue      any
 		}{
-			{"x"},
-			{"y"},
-			{"foobar"},
+			{"x", 5},
+			{"y", 10},
+			{"foobar", 123},
+			{"b", true},
+			{"z", "y"},
 		}
 
 		lex := lexer.NewLexer(input)
@@ -43,6 +131,8 @@ func TestParser_ParseProgram(t *testing.T) {
 		for i, test := range tests {
 			stmt := program.Statements[i]
 			assertLetStatement(t, test.expectedIdentifier, stmt)
+
+			assertLiteral(t, test.expectedValue, stmt.(*ast.LetStatement).Value)
 		}
 	})
 
@@ -59,6 +149,27 @@ func TestParser_ParseProgram(t *testing.T) {
 
 		stmt := program.Statements[0]
 		assertReturnStatement(t, stmt)
+		assertInfixExpression(t, 12, "+", 5, stmt.(*ast.ReturnStatement).ReturnValue)
+	})
+
+	t.Run("comments", func(t *testing.T) {
+		input := `// leading
+let x = 5; // trailing
+// last`
+
+		lex := lexer.NewLexer(input)
+		par := NewParser(lex)
+
+		program := par.ParseProgram()
+		requireNoParserErrors(t, par)
+		require.NotNil(t, program)
+		require.Len(t, program.Statements, 1)
+		assertLetStatement(t, "x", program.Statements[0])
+
+		require.Len(t, program.Comments, 3)
+		assert.Equal(t, "// leading", program.Comments[0].Literal)
+		assert.Equal(t, "// trailing", program.Comments[1].Literal)
+		assert.Equal(t, "// last", program.Comments[2].Literal)
 	})
 
 	t.Run("identifier expression", func(t *testing.T) {
@@ -79,6 +190,18 @@ func TestParser_ParseProgram(t *testing.T) {
 		assertIdentifier(t, "foobar", stmtExpression.Expression)
 	})
 
+	t.Run("identifier with digits", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `x1;`)
+
+		assertIdentifier(t, "x1", stmt.Expression)
+	})
+
+	t.Run("expression statement token is first token", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `a + b;`)
+
+		assert.Equal(t, "a", stmt.Token.Literal)
+	})
+
 	t.Run("integer literal expression", func(t *testing.T) {
 		input := `5;`
 
@@ -191,6 +314,322 @@ func TestParser_ParseProgram(t *testing.T) {
 		}
 	})
 
+	t.Run("string literal expression", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `"hello world";`)
+
+		literal, ok := stmt.Expression.(*ast.StringLiteral)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+		assert.Equal(t, "hello world", literal.Value)
+	})
+
+	t.Run("if expression", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `if (x < y) { x }`)
+
+		exp, ok := stmt.Expression.(*ast.IfExpression)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+
+		assertInfixExpression(t, "x", "<", "y", exp.Condition)
+		require.Len(t, exp.Consequence.Statements, 1)
+		consequence, ok := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+		require.True(t, ok, "statement has unexpected type %T", exp.Consequence.Statements[0])
+		assertIdentifier(t, "x", consequence.Expression)
+		assert.Nil(t, exp.Alternative)
+	})
+
+	t.Run("if else expression", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `if (x < y) { x } else { y; }`)
+
+		exp, ok := stmt.Expression.(*ast.IfExpression)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+
+		assertInfixExpression(t, "x", "<", "y", exp.Condition)
+		require.Len(t, exp.Consequence.Statements, 1)
+		require.NotNil(t, exp.Alternative)
+		require.Len(t, exp.Alternative.Statements, 1)
+		alternative, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+		require.True(t, ok, "statement has unexpected type %T", exp.Alternative.Statements[0])
+		assertIdentifier(t, "y", alternative.Expression)
+	})
+
+	t.Run("throw statement", func(t *testing.T) {
+		par := NewParser(lexer.NewLexer(`throw "boom";`))
+		program := par.ParseProgram()
+		requireNoParserErrors(t, par)
+		require.Len(t, program.Statements, 1)
+
+		stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+		require.True(t, ok, "stmt has unexpected type %T", program.Statements[0])
+		str, ok := stmt.Value.(*ast.StringLiteral)
+		require.True(t, ok, "value has unexpected type %T", stmt.Value)
+		assert.Equal(t, "boom", str.Value)
+	})
+
+	t.Run("try expression", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `try { x } catch (e) { e } finally { y }`)
+
+		exp, ok := stmt.Expression.(*ast.TryExpression)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+
+		require.Len(t, exp.Block.Statements, 1)
+		assertIdentifier(t, "e", exp.Parameter)
+		require.NotNil(t, exp.Catch)
+		require.Len(t, exp.Catch.Statements, 1)
+		require.NotNil(t, exp.Finally)
+		require.Len(t, exp.Finally.Statements, 1)
+	})
+
+	t.Run("try expression without catch", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `try { x } finally { y }`)
+
+		exp, ok := stmt.Expression.(*ast.TryExpression)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+		assert.Nil(t, exp.Parameter)
+		assert.Nil(t, exp.Catch)
+		require.NotNil(t, exp.Finally)
+	})
+
+	t.Run("try expression without catch and finally", func(t *testing.T) {
+		par := NewParser(lexer.NewLexer(`try { x };`))
+		_ = par.ParseProgram()
+
+		assert.Equal(t, []string{"expected Catch or Finally, got Semicolon instead"}, par.Errors())
+	})
+
+	t.Run("import statement", func(t *testing.T) {
+		par := NewParser(lexer.NewLexer(`import "lib/math.mk" as math;`))
+		program := par.ParseProgram()
+		requireNoParserErrors(t, par)
+		require.Len(t, program.Statements, 1)
+
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		require.True(t, ok, "stmt has unexpected type %T", program.Statements[0])
+		assert.Equal(t, "lib/math.mk", stmt.Path.Value)
+		assertIdentifier(t, "math", stmt.Name)
+		assert.Equal(t, `import "lib/math.mk" as math;`, stmt.String())
+	})
+
+	t.Run("export statement", func(t *testing.T) {
+		par := NewParser(lexer.NewLexer(`export let x = 1; let y = 2;`))
+		program := par.ParseProgram()
+		requireNoParserErrors(t, par)
+		require.Len(t, program.Statements, 2)
+
+		assertLetStatement(t, "x", program.Statements[0])
+		assert.True(t, program.Statements[0].(*ast.LetStatement).Exported())
+		assert.Equal(t, 1, program.Statements[0].Pos().Column)
+		assertLetStatement(t, "y", program.Statements[1])
+		assert.False(t, program.Statements[1].(*ast.LetStatement).Exported())
+	})
+
+	t.Run("member expression", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `math.add(1, 2)`)
+
+		call, ok := stmt.Expression.(*ast.CallExpression)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+		exp, ok := call.Function.(*ast.MemberExpression)
+		require.True(t, ok, "function has unexpected type %T", call.Function)
+		assertIdentifier(t, "math", exp.Object)
+		assertIdentifier(t, "add", exp.Property)
+	})
+
+	t.Run("function literal", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `fn(x, y) { x + y; }`)
+
+		fn, ok := stmt.Expression.(*ast.FunctionLiteral)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+
+		require.Len(t, fn.Parameters, 2)
+		assertIdentifier(t, "x", fn.Parameters[0])
+		assertIdentifier(t, "y", fn.Parameters[1])
+
+		require.Len(t, fn.Body.Statements, 1)
+		body, ok := fn.Body.Statements[0].(*ast.ExpressionStatement)
+		require.True(t, ok, "statement has unexpected type %T", fn.Body.Statements[0])
+		assertInfixExpression(t, "x", "+", "y", body.Expression)
+	})
+
+	t.Run("function parameters", func(t *testing.T) {
+		tests := []struct {
+			input          string
+			expectedParams []string
+		}{
+			{"fn() {};", []string{}},
+			{"fn(x) {};", []string{"x"}},
+			{"fn(x, y, z) {};", []string{"x", "y", "z"}},
+		}
+
+		for _, test := range tests {
+			t.Run(test.input, func(t *testing.T) {
+				stmt := parseSingleExpressionStatement(t, test.input)
+
+				fn, ok := stmt.Expression.(*ast.FunctionLiteral)
+				require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+
+				require.Len(t, fn.Parameters, len(test.expectedParams))
+				for i, param := range test.expectedParams {
+					assertIdentifier(t, param, fn.Parameters[i])
+				}
+			})
+		}
+	})
+
+	t.Run("function name from let statement", func(t *testing.T) {
+		par := NewParser(lexer.NewLexer(`let myFunction = fn() { };`))
+		program := par.ParseProgram()
+		requireNoParserErrors(t, par)
+		require.Len(t, program.Statements, 1)
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		require.True(t, ok, "statement has unexpected type %T", program.Statements[0])
+
+		fn, ok := stmt.Value.(*ast.FunctionLiteral)
+		require.True(t, ok, "value has unexpected type %T", stmt.Value)
+		assert.Equal(t, "myFunction", fn.Name)
+	})
+
+	t.Run("type annotations", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"let x: int = 5;", "let x: int = 5;"},
+			{"let xs: [string] = [];", "let xs: [string] = [];"},
+			{"let h: {string: [int]} = {};", "let h: {string: [int]} = {};"},
+			{"let f: fn(int, bool) -> fn() -> int = g;", "let f: fn(int, bool) -> fn() -> int = g;"},
+			{"fn(a: int, b) -> bool { a }", "fn(a: int, b) -> bool a"},
+			{"fn() -> [int] { [] }", "fn() -> [int] []"},
+		}
+
+		for _, test := range tests {
+			t.Run(test.input, func(t *testing.T) {
+				par := NewParser(lexer.NewLexer(test.input))
+				program := par.ParseProgram()
+				requireNoParserErrors(t, par)
+				assert.Equal(t, test.expected, program.String())
+			})
+		}
+	})
+
+	t.Run("invalid type annotations", func(t *testing.T) {
+		inputs := []string{
+			`let x: = 5;`,
+			`let x: int 5;`,
+			`let x: [int = [];`,
+			`let h: {string} = {};`,
+			`let f: fn(int) = g;`,
+			`fn(a: 1) {}`,
+			`fn() -> {}`,
+		}
+
+		for _, input := range inputs {
+			t.Run(input, func(t *testing.T) {
+				par := NewParser(lexer.NewLexer(input))
+				_ = par.ParseProgram()
+				assert.NotEmpty(t, par.Errors())
+			})
+		}
+	})
+
+	t.Run("type annotation errors", func(t *testing.T) {
+		par := NewParser(lexer.NewLexer(`let x: 1 = 5;`))
+		_ = par.ParseProgram()
+
+		assert.Equal(t, "expected type, got Int", par.Errors()[0])
+	})
+
+	t.Run("call expression", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `add(1, 2 * 3, 4 + 5);`)
+
+		exp, ok := stmt.Expression.(*ast.CallExpression)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+
+		assertIdentifier(t, "add", exp.Function)
+		require.Len(t, exp.Arguments, 3)
+		assertLiteral(t, 1, exp.Arguments[0])
+		assertInfixExpression(t, 2, "*", 3, exp.Arguments[1])
+		assertInfixExpression(t, 4, "+", 5, exp.Arguments[2])
+	})
+
+	t.Run("array literal", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `[1, 2 * 2, 3 + 3]`)
+
+		array, ok := stmt.Expression.(*ast.ArrayLiteral)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+
+		require.Len(t, array.Elements, 3)
+		assertLiteral(t, 1, array.Elements[0])
+		assertInfixExpression(t, 2, "*", 2, array.Elements[1])
+		assertInfixExpression(t, 3, "+", 3, array.Elements[2])
+	})
+
+	t.Run("empty array literal", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `[]`)
+
+		array, ok := stmt.Expression.(*ast.ArrayLiteral)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+		assert.Empty(t, array.Elements)
+	})
+
+	t.Run("index expression", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `myArray[1 + 1]`)
+
+		exp, ok := stmt.Expression.(*ast.IndexExpression)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+
+		assertIdentifier(t, "myArray", exp.Left)
+		assertInfixExpression(t, 1, "+", 1, exp.Index)
+	})
+
+	t.Run("hash literal", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `{"one": 1, "two": 0 + 2, true: 3}`)
+
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+
+		require.Len(t, hash.Pairs, 3)
+		assert.Equal(t, "one", hash.Pairs[0].Key.String())
+		assertLiteral(t, 1, hash.Pairs[0].Value)
+		assert.Equal(t, "two", hash.Pairs[1].Key.String())
+		assertInfixExpression(t, 0, "+", 2, hash.Pairs[1].Value)
+		assertLiteral(t, true, hash.Pairs[2].Key)
+		assertLiteral(t, 3, hash.Pairs[2].Value)
+	})
+
+	t.Run("empty hash literal", func(t *testing.T) {
+		stmt := parseSingleExpressionStatement(t, `{}`)
+
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		require.True(t, ok, "expression has unexpected type %T", stmt.Expression)
+		assert.Empty(t, hash.Pairs)
+	})
+
+	t.Run("invalid blocks and lists", func(t *testing.T) {
+		inputs := []string{
+			`if (x) { x`,
+			`fn(x, 1) {}`,
+			`fn(x) x`,
+			`add(1, 2`,
+			`[1, 2`,
+			`{1: 2 3: 4}`,
+			`a[1`,
+			`try { x }`,
+			`try { x } catch { x }`,
+			`try { x } catch (1) { x }`,
+			`import "a.mk";`,
+			`import a as b;`,
+			`export fn() {};`,
+			`math.1`,
+		}
+
+		for _, input := range inputs {
+			t.Run(input, func(t *testing.T) {
+				par := NewParser(lexer.NewLexer(input))
+				_ = par.ParseProgram()
+				assert.NotEmpty(t, par.Errors())
+			})
+		}
+	})
+
 	t.Run("operator precedence", func(t *testing.T) {
 		tests := []struct {
 			input    string
@@ -262,6 +701,27 @@ func TestParser_ParseProgram(t *testing.T) {
 				"-(5 + 5)",
 				"(-(5 + 5))",
 			},
+			// tests oriented around calls and indices
+			{
+				"a + add(b * c) + d",
+				"((a + add((b * c))) + d)",
+			},
+			{
+				"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))",
+				"add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))",
+			},
+			{
+				"a * [1, 2, 3, 4][b * c] * d",
+				"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+			},
+			{
+				"add(a * b[2], b[1], 2 * [1, 2][1])",
+				"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+			},
+			{
+				"-m.a[1] * m.f(2).b",
+				"((-((m.a)[1])) * ((m.f)(2).b))",
+			},
 		}
 
 		for i, test := range tests {
@@ -314,6 +774,8 @@ func assertLiteral(t *testing.T, value any, node ast.Expression) {
 		assertIntegerLiteral(t, int64(v), node)
 	case bool:
 		assertBooleanLiteral(t, v, node)
+	case string:
+		assertIdentifier(t, v, node)
 	default:
 		panic(fmt.Errorf("unexpected value type %T", v))
 	}
@@ -357,6 +819,22 @@ func assertInfixExpression(t *testing.T, left any, operator string, right any, n
 	assertLiteral(t, right, exp.Right)
 }
 
+// parseSingleExpressionStatement parses the input which must consist of a
+// single expression statement.
+func parseSingleExpressionStatement(t *testing.T, input string) *ast.ExpressionStatement {
+	par := NewParser(lexer.NewLexer(input))
+
+	program := par.ParseProgram()
+	requireNoParserErrors(t, par)
+	require.NotNil(t, program)
+	require.Len(t, program.Statements, 1)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	require.True(t, ok, "stmt has unexpected type %T", program.Statements[0])
+
+	return stmt
+}
+
 func requireNoParserErrors(t *testing.T, p *Parser) {
 	errs := p.Errors()
 
@@ -371,3 +849,19 @@ func requireNoParserErrors(t *testing.T, p *Parser) {
 
 	t.FailNow()
 }
+
+func BenchmarkParser_ParseProgram(b *testing.B) {
+	// About 2,000 lines of code without tracing, which must not slow down
+	// parsing when it is disabled.
+	input := strings.Repeat(corpus.Fibonacci, 2000/strings.Count(corpus.Fibonacci, "\n"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		par := NewParser(lexer.NewLexer(input))
+		par.ParseProgram()
+		if errs := par.Errors(); len(errs) > 0 {
+			b.Fatal(errs)
+		}
+	}
+}
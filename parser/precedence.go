@@ -1,6 +1,9 @@
 package parser
 
-import "github.com/fabiante/monkeylang/token"
+import (
+	"fmt"
+	"github.com/fabiante/monkeylang/token"
+)
 
 type precedence int
 
@@ -13,8 +16,27 @@ const (
 	product
 	prefix
 	call
+	index
 )
 
+var precedenceNames = map[precedence]string{
+	lowest:      "lowest",
+	equals:      "equals",
+	lessgreater: "lessgreater",
+	sum:         "sum",
+	product:     "product",
+	prefix:      "prefix",
+	call:        "call",
+	index:       "index",
+}
+
+func (p precedence) String() string {
+	if name, ok := precedenceNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("precedence(%d)", int(p))
+}
+
 // precedences maps token types to their precedence, used by Parser when parsing
 // expressions.
 var precedences = map[token.TokenType]precedence{
@@ -26,4 +48,26 @@ var precedences = map[token.TokenType]precedence{
 	token.Minus:    sum,
 	token.Slash:    product,
 	token.Asterisk: product,
+	token.LParen:   call,
+	token.LBracket: index,
+	token.Dot:      index,
+}
+
+const (
+	// LowestPrecedence is the precedence of a whole expression, for example in an expression statement.
+	LowestPrecedence = int(lowest)
+	// PrefixPrecedence is the precedence of prefix operators like "-" and "!".
+	PrefixPrecedence = int(prefix)
+)
+
+// Precedence returns the precedence of the given infix operator token type.
+// Token types which are no infix operators have the lowest precedence.
+//
+// Higher values bind tighter. This is useful for code which turns expressions
+// back into source code and has to decide where parentheses are required.
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
+		return int(p)
+	}
+	return int(lowest)
 }
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Option configures a Parser created by NewParser.
+type Option func(p *Parser)
+
+// WithTrace enables tracing of the parse functions to w.
+//
+// For each parse function, an indented BEGIN and END line is printed with the
+// current token and, for expressions, the precedence:
+//
+//	BEGIN parseExpressionStatement (Int "1")
+//		BEGIN parseExpression (Int "1", precedence lowest)
+//		...
+//
+// This is useful to debug the precedence of operators.
+func WithTrace(w io.Writer) Option {
+	return func(p *Parser) {
+		p.tracer = w
+	}
+}
+
+// untrace is returned by trace and traceExpression if tracing is disabled.
+// It is shared, so that disabled tracing costs no allocations.
+var untrace = func() {}
+
+// trace prints the beginning of the parse function fn if tracing is enabled.
+// The returned function prints its end and is meant to be deferred:
+//
+//	defer p.trace("parseIdentifier")()
+func (p *Parser) trace(fn string) func() {
+	if p.tracer == nil {
+		return untrace
+	}
+	return p.traceMessage(fn, p.traceToken())
+}
+
+// traceExpression works like trace but also prints the precedence the expression
+// is parsed with.
+func (p *Parser) traceExpression(fn string, precedence precedence) func() {
+	if p.tracer == nil {
+		return untrace
+	}
+	return p.traceMessage(fn, fmt.Sprintf("%s, precedence %s", p.traceToken(), precedence))
+}
+
+// traceMessage prints the beginning of fn with the given details. It must only
+// be called if tracing is enabled.
+func (p *Parser) traceMessage(fn string, details string) func() {
+	p.tracePrint(fmt.Sprintf("BEGIN %s (%s)", fn, details))
+	p.traceLevel++
+
+	return func() {
+		p.traceLevel--
+		p.tracePrint(fmt.Sprintf("END %s (%s)", fn, p.traceToken()))
+	}
+}
+
+func (p *Parser) traceToken() string {
+	return fmt.Sprintf("%s %q", p.currToken.Type, p.currToken.Literal)
+}
+
+func (p *Parser) tracePrint(line string) {
+	_, _ = fmt.Fprintf(p.tracer, "%s%s\n", strings.Repeat("\t", p.traceLevel), line)
+}
@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"bytes"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWithTrace(t *testing.T) {
+	var out bytes.Buffer
+
+	par := NewParser(lexer.NewLexer("-a * b;"), WithTrace(&out))
+	_ = par.ParseProgram()
+	requireNoParserErrors(t, par)
+
+	expected := `BEGIN parseExpressionStatement (Minus "-")
+	BEGIN parseExpression (Minus "-", precedence lowest)
+		BEGIN parsePrefixExpression (Minus "-", precedence prefix)
+			BEGIN parseExpression (Identifier "a", precedence prefix)
+				BEGIN parseIdentifier (Identifier "a")
+				END parseIdentifier (Identifier "a")
+			END parseExpression (Identifier "a")
+		END parsePrefixExpression (Identifier "a")
+		BEGIN parseInfixExpression (Asterisk "*", precedence product)
+			BEGIN parseExpression (Identifier "b", precedence product)
+				BEGIN parseIdentifier (Identifier "b")
+				END parseIdentifier (Identifier "b")
+			END parseExpression (Identifier "b")
+		END parseInfixExpression (Identifier "b")
+	END parseExpression (Identifier "b")
+END parseExpressionStatement (Semicolon ";")
+`
+
+	assert.Equal(t, expected, out.String())
+}
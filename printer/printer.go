@@ -0,0 +1,492 @@
+// Package printer turns an *ast.Program back into canonically formatted source code.
+//
+// Other than the String methods of ast nodes, which are meant for debugging,
+// the printer only emits parentheses where they are required by operator
+// precedence and preserves the comments of the program.
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/parser"
+	"github.com/fabiante/monkeylang/token"
+	"io"
+	"strconv"
+)
+
+// Indent is the string used to indent one nesting level.
+const Indent = "    "
+
+// Fprint writes the canonically formatted source code of program to w.
+func Fprint(w io.Writer, program *ast.Program) error {
+	p := &printer{
+		comments: program.Comments,
+	}
+
+	for _, stmt := range program.Statements {
+		if err := p.statement(stmt); err != nil {
+			return err
+		}
+	}
+
+	p.flushComments(-1)
+	p.endLine()
+
+	_, err := w.Write(p.out.Bytes())
+	return err
+}
+
+type printer struct {
+	out bytes.Buffer
+
+	// comments contains all comments which have not been printed yet.
+	comments []token.Token
+
+	// indent is the current nesting level.
+	indent int
+
+	// line is the source line of the last printed node or comment.
+	line int
+
+	// lineOpen is true if the current output line has not been terminated yet.
+	// This allows trailing comments to be appended to it.
+	lineOpen bool
+
+	// blockStart is true if the next line is the first or last line within
+	// a block. These lines are never preceded by blank lines.
+	blockStart bool
+}
+
+// statement prints a statement on its own line, preceded by all comments which
+// appear before it in the source.
+func (p *printer) statement(stmt ast.Statement) error {
+	pos := startPos(stmt)
+	p.flushComments(pos.Offset)
+	p.beginLine(pos.Line)
+
+	var err error
+
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		if stmt.Exported() {
+			p.out.WriteString("export ")
+		}
+		p.out.WriteString("let ")
+		p.declaration(stmt.Name)
+		p.out.WriteString(" = ")
+		err = p.expression(stmt.Value, parser.LowestPrecedence)
+	case *ast.ImportStatement:
+		p.out.WriteString("import ")
+		if err = p.expression(stmt.Path, parser.LowestPrecedence); err != nil {
+			return err
+		}
+		p.out.WriteString(" as " + stmt.Name.Value)
+	case *ast.ReturnStatement:
+		p.out.WriteString("return ")
+		err = p.expression(stmt.ReturnValue, parser.LowestPrecedence)
+	case *ast.ThrowStatement:
+		p.out.WriteString("throw ")
+		err = p.expression(stmt.Value, parser.LowestPrecedence)
+	case *ast.ExpressionStatement:
+		err = p.expression(stmt.Expression, parser.LowestPrecedence)
+	default:
+		err = fmt.Errorf("printer: unsupported statement type %T", stmt)
+	}
+	if err != nil {
+		return err
+	}
+
+	// If and try expressions end with a block, which is not followed by a semicolon.
+	if exp, ok := stmt.(*ast.ExpressionStatement); !ok || !endsWithBlock(exp.Expression) {
+		p.out.WriteString(";")
+	}
+	p.line = lastLine(stmt)
+
+	return nil
+}
+
+// atomPrecedence is the precedence of expressions which never require parentheses,
+// like literals and identifiers.
+const atomPrecedence = 1 << 16
+
+// expression prints an expression. The expression is wrapped in parentheses
+// if it binds less tight than the given precedence of its context.
+func (p *printer) expression(exp ast.Expression, precedence int) error {
+	own := expressionPrecedence(exp)
+
+	parens := own < precedence
+	if parens {
+		p.out.WriteString("(")
+	}
+
+	var err error
+
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		p.out.WriteString(exp.Value)
+	case *ast.IntegerLiteral:
+		p.out.WriteString(strconv.FormatInt(exp.Value, 10))
+	case *ast.BooleanLiteral:
+		p.out.WriteString(exp.Token.Literal)
+	case *ast.StringLiteral:
+		p.out.WriteString(`"` + exp.Value + `"`)
+	case *ast.PrefixExpression:
+		p.out.WriteString(exp.Operator)
+		err = p.expression(exp.Right, parser.PrefixPrecedence)
+	case *ast.InfixExpression:
+		if err = p.expression(exp.Left, own); err != nil {
+			return err
+		}
+		p.out.WriteString(" ")
+		p.out.WriteString(exp.Operator)
+		p.out.WriteString(" ")
+		// Infix operators are left associative. The right operand therefore
+		// requires parentheses if it has the same precedence: a - (b - c)
+		err = p.expression(exp.Right, own+1)
+	case *ast.IfExpression:
+		p.out.WriteString("if (")
+		if err = p.expression(exp.Condition, parser.LowestPrecedence); err != nil {
+			return err
+		}
+		p.out.WriteString(") ")
+		if err = p.block(exp.Consequence); err != nil {
+			return err
+		}
+		if exp.Alternative != nil {
+			p.out.WriteString(" else ")
+			err = p.block(exp.Alternative)
+		}
+	case *ast.TryExpression:
+		p.out.WriteString("try ")
+		if err = p.block(exp.Block); err != nil {
+			return err
+		}
+		if exp.Catch != nil {
+			p.out.WriteString(" catch (")
+			p.declaration(exp.Parameter)
+			p.out.WriteString(") ")
+			if err = p.block(exp.Catch); err != nil {
+				return err
+			}
+		}
+		if exp.Finally != nil {
+			p.out.WriteString(" finally ")
+			err = p.block(exp.Finally)
+		}
+	case *ast.FunctionLiteral:
+		p.out.WriteString("fn(")
+		for i, param := range exp.Parameters {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.declaration(param)
+		}
+		p.out.WriteString(") ")
+		if exp.ReturnType != nil {
+			p.out.WriteString("-> ")
+			p.typeExpression(exp.ReturnType)
+			p.out.WriteString(" ")
+		}
+		err = p.block(exp.Body)
+	case *ast.CallExpression:
+		// Calls and index expressions are evaluated from left to right. The
+		// precedence of calls is therefore sufficient for both: f(1)[0](2)
+		if err = p.expression(exp.Function, parser.Precedence(token.LParen)); err != nil {
+			return err
+		}
+		p.out.WriteString("(")
+		err = p.expressionList(exp.Arguments)
+		p.out.WriteString(")")
+	case *ast.ArrayLiteral:
+		p.out.WriteString("[")
+		err = p.expressionList(exp.Elements)
+		p.out.WriteString("]")
+	case *ast.IndexExpression:
+		if err = p.expression(exp.Left, parser.Precedence(token.LParen)); err != nil {
+			return err
+		}
+		p.out.WriteString("[")
+		err = p.expression(exp.Index, parser.LowestPrecedence)
+		p.out.WriteString("]")
+	case *ast.MemberExpression:
+		if err = p.expression(exp.Object, parser.Precedence(token.LParen)); err != nil {
+			return err
+		}
+		p.out.WriteString("." + exp.Property.Value)
+	case *ast.HashLiteral:
+		p.out.WriteString("{")
+		for i, pair := range exp.Pairs {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			if err = p.expression(pair.Key, parser.LowestPrecedence); err != nil {
+				return err
+			}
+			p.out.WriteString(": ")
+			if err = p.expression(pair.Value, parser.LowestPrecedence); err != nil {
+				return err
+			}
+		}
+		p.out.WriteString("}")
+	case nil:
+		return fmt.Errorf("printer: missing expression")
+	default:
+		return fmt.Errorf("printer: unsupported expression type %T", exp)
+	}
+	if err != nil {
+		return err
+	}
+
+	if parens {
+		p.out.WriteString(")")
+	}
+
+	return nil
+}
+
+// declaration prints the name declared by a let statement or parameter with
+// its type annotation.
+func (p *printer) declaration(ident *ast.Identifier) {
+	p.out.WriteString(ident.Value)
+	if ident.Type != nil {
+		p.out.WriteString(": ")
+		p.typeExpression(ident.Type)
+	}
+}
+
+func (p *printer) typeExpression(typ ast.TypeExpression) {
+	switch typ := typ.(type) {
+	case *ast.NamedType:
+		p.out.WriteString(typ.Name)
+	case *ast.ArrayType:
+		p.out.WriteString("[")
+		p.typeExpression(typ.Element)
+		p.out.WriteString("]")
+	case *ast.HashType:
+		p.out.WriteString("{")
+		p.typeExpression(typ.Key)
+		p.out.WriteString(": ")
+		p.typeExpression(typ.Value)
+		p.out.WriteString("}")
+	case *ast.FunctionType:
+		p.out.WriteString("fn(")
+		for i, param := range typ.Parameters {
+			if i > 0 {
+				p.out.WriteString(", ")
+			}
+			p.typeExpression(param)
+		}
+		p.out.WriteString(") -> ")
+		p.typeExpression(typ.ReturnType)
+	}
+}
+
+func (p *printer) expressionList(list []ast.Expression) error {
+	for i, exp := range list {
+		if i > 0 {
+			p.out.WriteString(", ")
+		}
+		if err := p.expression(exp, parser.LowestPrecedence); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// block prints a block with its statements indented on separate lines. Empty
+// blocks are printed as "{}".
+func (p *printer) block(block *ast.BlockStatement) error {
+	if block == nil {
+		return fmt.Errorf("printer: missing block")
+	}
+
+	hasComments := len(p.comments) > 0 && p.comments[0].Pos.Offset < block.RBrace.Pos.Offset
+	if len(block.Statements) == 0 && !hasComments {
+		p.out.WriteString("{}")
+		return nil
+	}
+
+	p.out.WriteString("{")
+	p.line = block.Token.Pos.Line
+	p.blockStart = true
+	p.indent++
+
+	for _, stmt := range block.Statements {
+		if err := p.statement(stmt); err != nil {
+			return err
+		}
+	}
+	p.flushComments(block.RBrace.Pos.Offset)
+
+	p.indent--
+	p.blockStart = true
+	p.beginLine(block.RBrace.Pos.Line)
+	p.out.WriteString("}")
+	p.line = block.RBrace.Pos.Line
+
+	return nil
+}
+
+// expressionPrecedence returns the precedence of the operator of the given expression.
+func expressionPrecedence(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.PrefixExpression:
+		return parser.PrefixPrecedence
+	case *ast.IntegerLiteral:
+		// Negative literals, which are created by the optimizer, are
+		// printed as prefix expressions.
+		if exp.Value < 0 {
+			return parser.PrefixPrecedence
+		}
+		return atomPrecedence
+	case *ast.InfixExpression:
+		return parser.Precedence(exp.Token.Type)
+	case *ast.CallExpression:
+		return parser.Precedence(token.LParen)
+	case *ast.IndexExpression:
+		return parser.Precedence(token.LBracket)
+	case *ast.MemberExpression:
+		return parser.Precedence(token.Dot)
+	default:
+		return atomPrecedence
+	}
+}
+
+// flushComments prints all remaining comments located before the given offset.
+// An offset of -1 prints all remaining comments.
+//
+// Comments on the same line as the previously printed node are appended to its line.
+func (p *printer) flushComments(offset int) {
+	for len(p.comments) > 0 {
+		comment := p.comments[0]
+		if offset >= 0 && comment.Pos.Offset >= offset {
+			return
+		}
+		p.comments = p.comments[1:]
+
+		if !p.lineOpen || comment.Pos.Line != p.line {
+			p.beginLine(comment.Pos.Line)
+		} else {
+			p.out.WriteString(" ")
+		}
+
+		p.out.WriteString(comment.Literal)
+		p.line = comment.Pos.Line
+	}
+}
+
+// beginLine starts a new indented output line for a node located at the given
+// source line. A single blank line is kept if the node was separated from the
+// previous one by blank lines.
+func (p *printer) beginLine(line int) {
+	if p.lineOpen {
+		p.out.WriteString("\n")
+		if line > p.line+1 && !p.blockStart {
+			p.out.WriteString("\n")
+		}
+	}
+	p.blockStart = false
+
+	for i := 0; i < p.indent; i++ {
+		p.out.WriteString(Indent)
+	}
+
+	p.lineOpen = true
+}
+
+// endLine terminates the current output line.
+func (p *printer) endLine() {
+	if p.lineOpen {
+		p.out.WriteString("\n")
+		p.lineOpen = false
+	}
+}
+
+func endsWithBlock(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.IfExpression, *ast.TryExpression:
+		return true
+	default:
+		return false
+	}
+}
+
+// startPos returns the source position at which the given statement starts.
+func startPos(stmt ast.Statement) token.Position {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		return stmt.Token.Pos
+	case *ast.LetStatement:
+		return stmt.Pos()
+	case *ast.ImportStatement:
+		return stmt.Token.Pos
+	case *ast.ReturnStatement:
+		return stmt.Token.Pos
+	case *ast.ThrowStatement:
+		return stmt.Token.Pos
+	default:
+		return token.Position{}
+	}
+}
+
+// lastLine returns the last source line of any token within the given node.
+func lastLine(node ast.Node) int {
+	line := 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		if pos := tokenPos(n); pos.Line > line {
+			line = pos.Line
+		}
+		return true
+	})
+	return line
+}
+
+// tokenPos returns the position of the token of the given node. For blocks, this
+// is the closing brace.
+func tokenPos(node ast.Node) token.Position {
+	switch n := node.(type) {
+	case *ast.BlockStatement:
+		return n.RBrace.Pos
+	case ast.Statement:
+		return startPos(n)
+	case *ast.Identifier:
+		return n.Token.Pos
+	case *ast.IntegerLiteral:
+		return n.Token.Pos
+	case *ast.BooleanLiteral:
+		return n.Token.Pos
+	case *ast.StringLiteral:
+		return n.Token.Pos
+	case *ast.PrefixExpression:
+		return n.Token.Pos
+	case *ast.InfixExpression:
+		return n.Token.Pos
+	case *ast.IfExpression:
+		return n.Token.Pos
+	case *ast.TryExpression:
+		return n.Token.Pos
+	case *ast.FunctionLiteral:
+		return n.Token.Pos
+	case *ast.CallExpression:
+		return n.Token.Pos
+	case *ast.ArrayLiteral:
+		return n.Token.Pos
+	case *ast.IndexExpression:
+		return n.Token.Pos
+	case *ast.MemberExpression:
+		return n.Token.Pos
+	case *ast.HashLiteral:
+		return n.Token.Pos
+	case *ast.NamedType:
+		return n.Token.Pos
+	case *ast.ArrayType:
+		return n.Token.Pos
+	case *ast.HashType:
+		return n.Token.Pos
+	case *ast.FunctionType:
+		return n.Token.Pos
+	default:
+		return token.Position{}
+	}
+}
@@ -0,0 +1,207 @@
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFprint(t *testing.T) {
+	t.Run("statements", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"let   x=5", "let x = 5;\n"},
+			{"return x+1;", "return x + 1;\n"},
+			{"x;y", "x;\ny;\n"},
+			{"let a = true; let b = !a;", "let a = true;\nlet b = !a;\n"},
+			{"", ""},
+		}
+
+		for i, test := range tests {
+			t.Run(fmt.Sprintf("tests[%d]", i), func(t *testing.T) {
+				assert.Equal(t, test.expected, format(t, test.input))
+			})
+		}
+	})
+
+	t.Run("minimal parentheses", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{"a + (b * c)", "a + b * c;\n"},
+			{"(a + b) * c", "(a + b) * c;\n"},
+			{"(a - b) - c", "a - b - c;\n"},
+			{"a - (b - c)", "a - (b - c);\n"},
+			{"a / (b * c)", "a / (b * c);\n"},
+			{"((a))", "a;\n"},
+			{"-(5 + 5)", "-(5 + 5);\n"},
+			{"(-a) * b", "-a * b;\n"},
+			{"!(-a)", "!-a;\n"},
+			{"(5 > 4) == (3 < 4)", "5 > 4 == 3 < 4;\n"},
+			{"5 > (4 == 3)", "5 > (4 == 3);\n"},
+			{"!(true == false)", "!(true == false);\n"},
+			{"-a[0]", "-a[0];\n"},
+			{"(-a)[0]", "(-a)[0];\n"},
+			{"(f(1))[0](2)", "f(1)[0](2);\n"},
+			{"(a + b)(c)", "(a + b)(c);\n"},
+			{"add(1, 2) * 3", "add(1, 2) * 3;\n"},
+		}
+
+		for i, test := range tests {
+			t.Run(fmt.Sprintf("tests[%d]", i), func(t *testing.T) {
+				assert.Equal(t, test.expected, format(t, test.input))
+			})
+		}
+	})
+
+	t.Run("literals", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{`"hello  world"`, "\"hello  world\";\n"},
+			{`[1,2,  3]`, "[1, 2, 3];\n"},
+			{`[]`, "[];\n"},
+			{`{"a":1,  true:[2]}`, "{\"a\": 1, true: [2]};\n"},
+			{`{}`, "{};\n"},
+			{`fn(){}`, "fn() {};\n"},
+			{`let x:int=5`, "let x: int = 5;\n"},
+			{`let h:{string:[int]}={}`, "let h: {string: [int]} = {};\n"},
+			{`fn(a:int,f:fn(int)->bool)->[bool]{[f(a)]}`, "fn(a: int, f: fn(int) -> bool) -> [bool] {\n    [f(a)];\n};\n"},
+		}
+
+		for i, test := range tests {
+			t.Run(fmt.Sprintf("tests[%d]", i), func(t *testing.T) {
+				assert.Equal(t, test.expected, format(t, test.input))
+			})
+		}
+	})
+
+	t.Run("blocks", func(t *testing.T) {
+		input := `let max = fn(a,b) {
+
+	// compare
+  if (a > b) { a } else {
+return b; // b is larger
+
+
+  }
+
+};
+if (true) {}
+max(1, 2)`
+
+		expected := `let max = fn(a, b) {
+    // compare
+    if (a > b) {
+        a;
+    } else {
+        return b; // b is larger
+    }
+};
+if (true) {}
+max(1, 2);
+`
+
+		assert.Equal(t, expected, format(t, input))
+	})
+
+	t.Run("try", func(t *testing.T) {
+		input := `let r = try { risky() } catch(e) {
+throw e["message"] } finally { cleanup() };
+try { 1 }  finally {}`
+
+		expected := `let r = try {
+    risky();
+} catch (e) {
+    throw e["message"];
+} finally {
+    cleanup();
+};
+try {
+    1;
+} finally {}
+`
+
+		assert.Equal(t, expected, format(t, input))
+	})
+
+	t.Run("modules", func(t *testing.T) {
+		input := `// math
+import   "lib/math.mk"  as math
+export let  twice = fn(x) { (math.mul)(x, 2) }; (-math.x).y`
+
+		expected := `// math
+import "lib/math.mk" as math;
+export let twice = fn(x) {
+    math.mul(x, 2);
+};
+(-math.x).y;
+`
+
+		assert.Equal(t, expected, format(t, input))
+	})
+
+	t.Run("comments in empty block", func(t *testing.T) {
+		input := "let f = fn() { // nothing\n  // to do\n};"
+
+		expected := "let f = fn() { // nothing\n    // to do\n};\n"
+
+		assert.Equal(t, expected, format(t, input))
+	})
+
+	t.Run("comments and blank lines", func(t *testing.T) {
+		input := `// header
+
+let x = 5;   // five
+let y = 10;
+
+
+// result
+x   + y;
+// end
+`
+
+		expected := `// header
+
+let x = 5; // five
+let y = 10;
+
+// result
+x + y;
+// end
+`
+
+		assert.Equal(t, expected, format(t, input))
+	})
+
+	t.Run("shebang line", func(t *testing.T) {
+		input := "#!/usr/bin/env -S monkey run\nlet x=1;"
+
+		assert.Equal(t, "#!/usr/bin/env -S monkey run\nlet x = 1;\n", format(t, input))
+	})
+
+	t.Run("is idempotent", func(t *testing.T) {
+		input := "// a\nlet x = (1 + 2) * -3; // b\n\n\nreturn !x == false;\nlet f = fn(x) {\n// c\nif (x) { [x] } else { {x: x} }\n\n\nx(1)};"
+
+		once := format(t, input)
+		assert.Equal(t, once, format(t, once))
+	})
+}
+
+func format(t *testing.T, input string) string {
+	par := parser.NewParser(lexer.NewLexer(input))
+	program := par.ParseProgram()
+	require.Empty(t, par.Errors())
+
+	var out bytes.Buffer
+	require.NoError(t, Fprint(&out, program))
+	return out.String()
+}
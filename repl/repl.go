@@ -3,14 +3,16 @@ package repl
 import (
 	"bufio"
 	"fmt"
+	"github.com/fabiante/monkeylang/highlight"
 	"github.com/fabiante/monkeylang/lexer"
-	"github.com/fabiante/monkeylang/token"
 	"io"
 )
 
 const Prompt = ">> "
 
-func Start(in io.Reader, out io.Writer) {
+// Start reads lines from in and prints their tokens to out. If color is
+// true, each line is echoed with syntax highlighting first.
+func Start(in io.Reader, out io.Writer, color bool) {
 	scanner := bufio.NewScanner(in)
 
 	for {
@@ -22,9 +24,11 @@ func Start(in io.Reader, out io.Writer) {
 
 		line := scanner.Text()
 
-		lex := lexer.NewLexer(line)
+		if color {
+			_ = highlight.WriteANSI(out, line+"\n")
+		}
 
-		for t := lex.NextToken(); t.Type != token.EOF; t = lex.NextToken() {
+		for t := range lexer.NewLexer(line).All() {
 			_, _ = fmt.Fprintf(out, "%+v\n", t)
 		}
 	}
@@ -0,0 +1,306 @@
+// Package resolver binds the identifiers of a program to their declarations.
+//
+// Each identifier is annotated with an ast.Binding, which allows to look up
+// its value by index instead of by name. Names are scoped by functions:
+// the parameters and let statements of a function body, including those in
+// nested blocks, belong to the function. Let statements at the top level
+// declare global bindings.
+//
+// Import statements declare their name like let statements. Let statements
+// can only be exported at the top level.
+//
+// A name can be used after its let statement. The value of a let statement
+// cannot refer to the name being declared, unless it is a function literal,
+// which may call itself recursively. Functions declared at the top level can
+// also be used by the functions declared before them, which allows mutual
+// recursion, as they are called only after both have been declared.
+package resolver
+
+import (
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/object"
+	"github.com/fabiante/monkeylang/token"
+)
+
+// Error is a problem found by the resolver.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Resolve sets the Binding of all identifiers in program.
+//
+// It returns errors for undefined names and duplicate parameters, which would
+// fail at runtime or compile time. Declarations which shadow a binding of an
+// enclosing scope or a builtin are valid, but reported as warnings.
+func Resolve(program *ast.Program) (errs []*Error, warnings []*Error) {
+	r := &resolver{}
+	r.program(program)
+	return r.errs, r.warnings
+}
+
+// Declarations resolves the identifiers of program like Resolve and returns
+// the declaring identifier of each identifier which refers to a binding. The
+// declaring identifier is the name of a let statement or a parameter. The
+// identifiers of builtins and undefined names are not contained.
+func Declarations(program *ast.Program) map[*ast.Identifier]*ast.Identifier {
+	r := &resolver{declarations: make(map[*ast.Identifier]*ast.Identifier)}
+	r.program(program)
+	return r.declarations
+}
+
+type resolver struct {
+	scope    *scope
+	errs     []*Error
+	warnings []*Error
+
+	// declarations maps identifiers to their declaring identifiers if it is
+	// not nil.
+	declarations map[*ast.Identifier]*ast.Identifier
+
+	// blocks is the number of blocks enclosing the current statement.
+	blocks int
+}
+
+// scope contains the bindings of a function or of the top level.
+type scope struct {
+	outer    *scope
+	bindings map[string]*declaration
+	numSlots int
+}
+
+type declaration struct {
+	slot int
+	pos  token.Position
+	// ident is the identifier of the latest declaration of the name, which
+	// may be a redeclaration in the same scope.
+	ident *ast.Identifier
+	// pending is true for top-level functions whose let statement has not
+	// been resolved yet. They can only be used within functions. Their slot
+	// is -1 until they are first used.
+	pending bool
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{outer: outer, bindings: make(map[string]*declaration)}
+}
+
+func (r *resolver) program(program *ast.Program) {
+	r.scope = newScope(nil)
+
+	// Builtins are not declared in advance, so that functions keep calling
+	// them until a let statement declares the name.
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || builtinIndex(let.Name.Value) >= 0 {
+			continue
+		}
+		if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+			if _, ok := r.scope.bindings[let.Name.Value]; !ok {
+				r.scope.bindings[let.Name.Value] = &declaration{slot: -1, pos: let.Name.Token.Pos, ident: let.Name, pending: true}
+			}
+		}
+	}
+
+	r.statements(program.Statements)
+}
+
+func (r *resolver) statements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		r.statement(stmt)
+	}
+}
+
+func (r *resolver) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		if stmt.Exported() && r.blocks > 0 {
+			r.errorf(stmt.Export.Pos, "export is only allowed at the top level")
+		}
+
+		// Functions can refer to themselves, as they are called only after
+		// the let statement has been executed.
+		if _, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+			r.declare(stmt.Name)
+			r.expression(stmt.Value)
+		} else {
+			r.expression(stmt.Value)
+			r.declare(stmt.Name)
+		}
+	case *ast.ImportStatement:
+		r.declare(stmt.Name)
+	case *ast.ReturnStatement:
+		r.expression(stmt.ReturnValue)
+	case *ast.ThrowStatement:
+		r.expression(stmt.Value)
+	case *ast.ExpressionStatement:
+		r.expression(stmt.Expression)
+	case *ast.BlockStatement:
+		r.block(stmt)
+	}
+}
+
+func (r *resolver) block(block *ast.BlockStatement) {
+	if block != nil {
+		r.blocks++
+		r.statements(block.Statements)
+		r.blocks--
+	}
+}
+
+func (r *resolver) expression(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		r.use(exp)
+	case *ast.PrefixExpression:
+		r.expression(exp.Right)
+	case *ast.InfixExpression:
+		r.expression(exp.Left)
+		r.expression(exp.Right)
+	case *ast.IfExpression:
+		r.expression(exp.Condition)
+		r.block(exp.Consequence)
+		r.block(exp.Alternative)
+	case *ast.TryExpression:
+		r.block(exp.Block)
+		// Like blocks, catch clauses do not introduce a scope. The parameter
+		// is bound like a let statement at the start of the clause.
+		if exp.Parameter != nil {
+			r.declare(exp.Parameter)
+		}
+		r.block(exp.Catch)
+		r.block(exp.Finally)
+	case *ast.FunctionLiteral:
+		r.scope = newScope(r.scope)
+		for _, param := range exp.Parameters {
+			if previous, ok := r.scope.bindings[param.Value]; ok {
+				r.errorf(param.Token.Pos, "duplicate parameter %s, first declared at %s", param.Value, previous.pos)
+				param.Binding = &ast.Binding{Slot: previous.slot}
+				previous.ident = param
+				continue
+			}
+			r.declare(param)
+		}
+		r.block(exp.Body)
+		r.scope = r.scope.outer
+	case *ast.CallExpression:
+		r.expression(exp.Function)
+		for _, arg := range exp.Arguments {
+			r.expression(arg)
+		}
+	case *ast.ArrayLiteral:
+		for _, element := range exp.Elements {
+			r.expression(element)
+		}
+	case *ast.IndexExpression:
+		r.expression(exp.Left)
+		r.expression(exp.Index)
+	case *ast.MemberExpression:
+		r.expression(exp.Object)
+	case *ast.HashLiteral:
+		for _, pair := range exp.Pairs {
+			r.expression(pair.Key)
+			r.expression(pair.Value)
+		}
+	}
+}
+
+// declare binds the name of ident in the current scope. Declaring a name
+// again in the same scope reuses its slot.
+func (r *resolver) declare(ident *ast.Identifier) {
+	if d, ok := r.scope.bindings[ident.Value]; ok && !d.pending {
+		ident.Binding = &ast.Binding{Slot: d.slot}
+		d.ident = ident
+		return
+	}
+
+	if d, _, ok := r.scope.outer.lookup(ident.Value); ok {
+		r.warnf(ident.Token.Pos, "declaration of %s shadows declaration at %s", ident.Value, d.pos)
+	} else if builtinIndex(ident.Value) >= 0 {
+		r.warnf(ident.Token.Pos, "declaration of %s shadows builtin", ident.Value)
+	}
+
+	d, ok := r.scope.bindings[ident.Value]
+	if !ok {
+		d = &declaration{slot: -1}
+		r.scope.bindings[ident.Value] = d
+	}
+	r.scope.allocate(d)
+	d.pos, d.ident, d.pending = ident.Token.Pos, ident, false
+
+	ident.Binding = &ast.Binding{Slot: d.slot}
+}
+
+// use resolves an identifier which refers to a binding.
+func (r *resolver) use(ident *ast.Identifier) {
+	// Pending functions are undefined at the top level until their let
+	// statement has been executed.
+	d, depth, ok := r.scope.lookup(ident.Value)
+	if ok && !(d.pending && r.scope.outer == nil) {
+		if d.slot < 0 {
+			r.scope.root().allocate(d)
+		}
+		ident.Binding = &ast.Binding{Depth: depth, Slot: d.slot}
+		if r.declarations != nil {
+			r.declarations[ident] = d.ident
+		}
+		return
+	}
+
+	if index := builtinIndex(ident.Value); index >= 0 {
+		ident.Binding = &ast.Binding{Slot: index, Builtin: true}
+		return
+	}
+
+	r.errorf(ident.Token.Pos, "undefined variable %s", ident.Value)
+}
+
+// lookup finds the declaration of name in s or its outer scopes. It returns
+// the number of scopes between s and the scope of the declaration.
+func (s *scope) lookup(name string) (*declaration, int, bool) {
+	depth := 0
+	for scope := s; scope != nil; scope = scope.outer {
+		if d, ok := scope.bindings[name]; ok {
+			return d, depth, true
+		}
+		depth++
+	}
+	return nil, 0, false
+}
+
+// allocate assigns the next slot of s to d unless it already has one.
+func (s *scope) allocate(d *declaration) {
+	if d.slot < 0 {
+		d.slot = s.numSlots
+		s.numSlots++
+	}
+}
+
+func (s *scope) root() *scope {
+	for s.outer != nil {
+		s = s.outer
+	}
+	return s
+}
+
+func builtinIndex(name string) int {
+	for i, def := range object.Builtins {
+		if def.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *resolver) errorf(pos token.Position, format string, a ...any) {
+	r.errs = append(r.errs, &Error{Pos: pos, Msg: fmt.Sprintf(format, a...)})
+}
+
+func (r *resolver) warnf(pos token.Position, format string, a ...any) {
+	r.warnings = append(r.warnings, &Error{Pos: pos, Msg: fmt.Sprintf(format, a...)})
+}
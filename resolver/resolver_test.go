@@ -0,0 +1,188 @@
+package resolver
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	t.Run("bindings", func(t *testing.T) {
+		input := `let a = 1;
+let b = a;
+let f = fn(x, y) {
+	let z = x + b;
+	fn() { z + y + len(f) }
+};
+let a = a + 1;`
+
+		program := testutil.MustParse(t, input)
+		errs, warnings := Resolve(program)
+		require.Empty(t, errs)
+		require.Empty(t, warnings)
+
+		// Identifiers in source order.
+		expected := []string{
+			"a:0:0",         // let a
+			"b:0:1",         // let b
+			"a:0:0",         // = a
+			"f:0:2",         // let f
+			"x:0:0",         // parameter x
+			"y:0:1",         // parameter y
+			"z:0:2",         // let z
+			"x:0:0",         // = x + b
+			"b:1:1",         // = x + b
+			"z:1:2",         // z + y
+			"y:1:1",         // z + y
+			"len:builtin:0", // len
+			"f:2:2",         // len(f)
+			"a:0:0",         // let a
+			"a:0:0",         // = a + 1
+		}
+		assert.Equal(t, expected, bindings(program))
+	})
+
+	t.Run("blocks do not introduce scopes", func(t *testing.T) {
+		program := testutil.MustParse(t, "if (true) { let a = 1; } else { let b = 2; } let c = a + b;")
+		errs, _ := Resolve(program)
+		require.Empty(t, errs)
+
+		assert.Equal(t, []string{"a:0:0", "b:0:1", "c:0:2", "a:0:0", "b:0:1"}, bindings(program))
+	})
+
+	t.Run("functions declared later", func(t *testing.T) {
+		input := `let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+let f = fn() { len("") };
+let len = fn(x) { x };`
+
+		program := testutil.MustParse(t, input)
+		errs, _ := Resolve(program)
+		require.Empty(t, errs)
+
+		expected := []string{
+			"isEven:0:0",    // let isEven
+			"n:0:0",         // parameter n
+			"n:0:0",         // n == 0
+			"isOdd:1:1",     // isOdd(n - 1)
+			"n:0:0",         // n - 1
+			"isOdd:0:1",     // let isOdd
+			"n:0:0",         // parameter n
+			"n:0:0",         // n == 0
+			"isEven:1:0",    // isEven(n - 1)
+			"n:0:0",         // n - 1
+			"f:0:2",         // let f
+			"len:builtin:0", // len("")
+			"len:0:3",       // let len
+			"x:0:0",         // parameter x
+			"x:0:0",         // x
+		}
+		assert.Equal(t, expected, bindings(program))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected []string
+		}{
+			{"x;", []string{"1:1: undefined variable x"}},
+			{"let x = x;", []string{"1:9: undefined variable x"}},
+			{"let f = fn() { g() };\nlet g = fn() { f() };", nil},
+			{"g();\nlet g = fn() { 1 };", []string{"1:1: undefined variable g"}},
+			{"fn(a) { b + c }", []string{"1:9: undefined variable b", "1:13: undefined variable c"}},
+			{"fn(a, b, a) { a }", []string{"1:10: duplicate parameter a, first declared at 1:4"}},
+			{"let f = fn() { f() }; f();", nil},
+			{"let a = 1; let a = a + 1;", nil},
+			{`import "m.mk" as m; m.x; m.y(n.z);`, []string{"1:30: undefined variable n"}},
+			{"export let a = 1; if (a) { export let b = 2; }", []string{"1:28: export is only allowed at the top level"}},
+			{"fn() { export let a = 1; }", []string{"1:8: export is only allowed at the top level"}},
+		}
+
+		for _, test := range tests {
+			t.Run(test.input, func(t *testing.T) {
+				errs, _ := Resolve(testutil.MustParse(t, test.input))
+				assert.Equal(t, test.expected, messages(errs))
+			})
+		}
+	})
+
+	t.Run("warnings", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected []string
+		}{
+			{"let x = 1; fn(x) { x };", []string{"1:15: declaration of x shadows declaration at 1:5"}},
+			{"let x = 1; fn() { let x = 2; x };", []string{"1:23: declaration of x shadows declaration at 1:5"}},
+			{"let len = fn(a) { 0 };", []string{"1:5: declaration of len shadows builtin"}},
+			{"fn(first) { first };", []string{"1:4: declaration of first shadows builtin"}},
+			{"let x = 1; let x = 2; fn(y) { let y = 1; y };", nil},
+		}
+
+		for _, test := range tests {
+			t.Run(test.input, func(t *testing.T) {
+				errs, warnings := Resolve(testutil.MustParse(t, test.input))
+				require.Empty(t, errs)
+				assert.Equal(t, test.expected, messages(warnings))
+			})
+		}
+	})
+}
+
+func TestDeclarations(t *testing.T) {
+	program := testutil.MustParse(t, "let a = 1;\nlet f = fn(b) { a + b + len(c) };\nlet a = a + 1;\na;")
+	declarations := Declarations(program)
+
+	// Uses and declarations as line:col.
+	var result []string
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			if decl, ok := declarations[ident]; ok {
+				result = append(result, ident.Value+"@"+ident.Token.Pos.String()+"->"+decl.Token.Pos.String())
+			}
+		}
+		return true
+	})
+
+	expected := []string{
+		"a@2:17->1:5",
+		"b@2:21->2:12",
+		"a@3:9->1:5",
+		"a@4:1->3:5",
+	}
+	assert.Equal(t, expected, result)
+}
+
+// bindings returns the bindings of all identifiers in the order of ast.Inspect
+// as name:depth:slot.
+func bindings(program *ast.Program) []string {
+	var result []string
+	ast.Inspect(program, func(node ast.Node) bool {
+		ident, ok := node.(*ast.Identifier)
+		if !ok {
+			return true
+		}
+
+		b := ident.Binding
+		switch {
+		case b == nil:
+			result = append(result, ident.Value+":unresolved")
+		case b.Builtin:
+			result = append(result, ident.Value+":builtin:"+strconv.Itoa(b.Slot))
+		default:
+			result = append(result, ident.Value+":"+strconv.Itoa(b.Depth)+":"+strconv.Itoa(b.Slot))
+		}
+		return true
+	})
+	return result
+}
+
+func messages(errs []*Error) []string {
+	var result []string
+	for _, err := range errs {
+		result = append(result, err.Error())
+	}
+	return result
+}
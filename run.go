@@ -0,0 +1,222 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/compiler"
+	"github.com/fabiante/monkeylang/evaluator"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/mkc"
+	"github.com/fabiante/monkeylang/module"
+	"github.com/fabiante/monkeylang/object"
+	"github.com/fabiante/monkeylang/optimizer"
+	"github.com/fabiante/monkeylang/parser"
+	"github.com/fabiante/monkeylang/resolver"
+	"github.com/fabiante/monkeylang/token"
+	"github.com/fabiante/monkeylang/vm"
+	"io"
+	"os"
+	"strings"
+)
+
+// runCmd executes the program in the given file. If the file is "-", the
+// program is read from stdin. The file may contain source code or a program
+// compiled by monkey build.
+//
+// Programs are compiled and executed by the virtual machine. The evaluator can
+// be selected with --engine=eval. With --check, the program is only run if
+// package typecheck finds no type errors.
+func runCmd(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	engine := flags.String("engine", "vm", "execute the program with `engine` vm or eval")
+	optimize := flags.Bool("O", false, "optimize the program before it is executed")
+	check := flags.Bool("check", false, "check the types of the program before it is executed")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 || (*engine != "vm" && *engine != "eval") {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: monkey run [-O] [--check] [--engine=vm|eval] <file | ->")
+		return exitUsage
+	}
+
+	input, err := readSource(flags.Arg(0))
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	name := sourceName(flags.Arg(0))
+
+	if *check && !mkc.IsCompiled([]byte(input)) {
+		// Warnings are printed when the program is parsed again to be run.
+		program, ok := parse(name, input, false, false)
+		if !ok {
+			return exitError
+		}
+		if _, ok := checkTypes(name, program); !ok {
+			return exitError
+		}
+	}
+
+	if *engine == "eval" {
+		if mkc.IsCompiled([]byte(input)) {
+			_, _ = fmt.Fprintf(os.Stderr, "%s: compiled programs can only be run with --engine=vm\n", name)
+			return exitUsage
+		}
+
+		program, ok := parse(name, input, *optimize, true)
+		if !ok {
+			return exitError
+		}
+
+		result := evaluator.Eval(program, object.NewFileEnvironment(name))
+		if err, ok := result.(*object.Error); ok {
+			printRuntimeError(err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	bytecode, ok := loadBytecode(name, input, *optimize)
+	if !ok {
+		return exitError
+	}
+
+	machine := vm.NewVM(bytecode)
+	if err := machine.Run(); err != nil {
+		if err, ok := err.(*object.Error); ok {
+			printRuntimeError(err)
+		} else {
+			printErrors(name, []string{err.Error()})
+		}
+		return exitError
+	}
+
+	return exitOK
+}
+
+// parse parses the source code of the given file, optionally applies the
+// default passes of package optimizer, resolves the identifiers and loads the
+// imported files. Errors and, if warn is set, warnings of the program and the
+// imported files are printed to stderr. Warnings do not fail the parse.
+func parse(name, input string, optimize, warn bool) (*ast.Program, bool) {
+	par := parser.NewParser(lexer.NewLexer(input))
+	program := par.ParseProgram()
+	if errs := par.ErrorList(); len(errs) > 0 {
+		printParseErrors(name, errs)
+		return nil, false
+	}
+
+	if optimize {
+		optimizer.Optimize(program)
+	}
+
+	errs, warnings := resolver.Resolve(program)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			_, _ = fmt.Fprintf(os.Stderr, "%s:%s\n", name, err)
+		}
+		return nil, false
+	}
+
+	loader := module.NewLoader(module.SearchPathFromEnv()...)
+	if optimize {
+		loader.Prepare = func(program *ast.Program) { optimizer.Optimize(program) }
+	}
+	if warn {
+		for _, w := range warnings {
+			printWarning(name, w.Pos, w.Msg)
+		}
+		loader.Warn = func(w *module.Error) { printWarning(w.File, w.Pos, w.Msg) }
+	}
+	if errs := loader.Load(name, program); len(errs) > 0 {
+		for _, err := range errs {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+		}
+		return nil, false
+	}
+
+	return program, true
+}
+
+// compile parses and compiles the source code of the given file. Errors are
+// printed to stderr.
+func compile(name, input string, optimize bool) (*compiler.Bytecode, bool) {
+	program, ok := parse(name, input, optimize, true)
+	if !ok {
+		return nil, false
+	}
+
+	comp := compiler.NewCompiler()
+	comp.SetFile(name)
+	if err := comp.Compile(program); err != nil {
+		printErrors(name, []string{err.Error()})
+		return nil, false
+	}
+	return comp.Bytecode(), true
+}
+
+// loadBytecode returns the bytecode of the given file, which either contains
+// a program compiled by monkey build or source code. Errors are printed to stderr.
+func loadBytecode(name, input string, optimize bool) (*compiler.Bytecode, bool) {
+	if !mkc.IsCompiled([]byte(input)) {
+		return compile(name, input, optimize)
+	}
+
+	bytecode, err := mkc.Read(strings.NewReader(input))
+	if err != nil {
+		printErrors(name, []string{err.Error()})
+		return nil, false
+	}
+	return bytecode, true
+}
+
+// readSource reads the source code of the given file. The name "-" reads from stdin.
+func readSource(name string) (string, error) {
+	var data []byte
+	var err error
+
+	if name == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", name, err)
+	}
+
+	return string(data), nil
+}
+
+// sourceName returns the name of the given source file as used in messages.
+func sourceName(name string) string {
+	if name == "-" {
+		return "<stdin>"
+	}
+	return name
+}
+
+// printParseErrors prints syntax errors prefixed with their positions.
+func printParseErrors(name string, errs []*parser.Error) {
+	for _, err := range errs {
+		_, _ = fmt.Fprintf(os.Stderr, "%s:%s\n", name, err)
+	}
+}
+
+// printRuntimeError prints an error which aborted the program together with
+// the stack of calls it was thrown in.
+func printRuntimeError(err *object.Error) {
+	_, _ = fmt.Fprintln(os.Stderr, err.Trace())
+}
+
+// printWarning prints a problem which does not prevent the program from running.
+func printWarning(name string, pos token.Position, msg string) {
+	_, _ = fmt.Fprintf(os.Stderr, "%s:%s: warning: %s\n", name, pos, msg)
+}
+
+func printErrors(name string, errs []string) {
+	for _, err := range errs {
+		_, _ = fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+	}
+}
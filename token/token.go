@@ -1,5 +1,10 @@
 package token
 
+import (
+	"fmt"
+	"strings"
+)
+
 type TokenType int
 
 const (
@@ -11,6 +16,7 @@ const (
 	Identifier
 
 	Int
+	String
 
 	Assign
 	Plus
@@ -26,11 +32,16 @@ const (
 
 	Comma
 	Semicolon
+	Colon
+	// Arrow separates the parameters of a function from its return type.
+	Arrow
 
 	LParen
 	RParen
 	LBrace
 	RBrace
+	LBracket
+	RBracket
 
 	Func
 	Let
@@ -41,21 +52,142 @@ const (
 	If
 	Else
 	Return
+
+	Throw
+	Try
+	Catch
+	Finally
+
+	Import
+	Export
+	As
+
+	// Dot separates a module from the name of one of its exports.
+	Dot
+
+	// Comment is a line comment starting with "//" or a "#!" line at the very
+	// beginning of the input. The literal contains the whole comment including
+	// the leading characters.
+	Comment
 )
 
+var typeNames = map[TokenType]string{
+	Illegal:    "Illegal",
+	EOF:        "EOF",
+	Identifier: "Identifier",
+	Int:        "Int",
+	String:     "String",
+	Assign:     "Assign",
+	Plus:       "Plus",
+	Minus:      "Minus",
+	Bang:       "Bang",
+	Asterisk:   "Asterisk",
+	Slash:      "Slash",
+	LT:         "LT",
+	GT:         "GT",
+	EQ:         "EQ",
+	NEQ:        "NEQ",
+	Comma:      "Comma",
+	Semicolon:  "Semicolon",
+	Colon:      "Colon",
+	Arrow:      "Arrow",
+	LParen:     "LParen",
+	RParen:     "RParen",
+	LBrace:     "LBrace",
+	RBrace:     "RBrace",
+	LBracket:   "LBracket",
+	RBracket:   "RBracket",
+	Func:       "Func",
+	Let:        "Let",
+	True:       "True",
+	False:      "False",
+	If:         "If",
+	Else:       "Else",
+	Return:     "Return",
+	Throw:      "Throw",
+	Try:        "Try",
+	Catch:      "Catch",
+	Finally:    "Finally",
+	Import:     "Import",
+	Export:     "Export",
+	As:         "As",
+	Dot:        "Dot",
+	Comment:    "Comment",
+}
+
+// String returns the name of the token type, which is the name of its constant.
+func (t TokenType) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TokenType(%d)", int(t))
+}
+
+// LookupType returns the token type with the given name as returned by TokenType.String.
+func LookupType(name string) (TokenType, bool) {
+	for t, n := range typeNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return Illegal, false
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	// Pos is the position of the first character of the token.
+	Pos Position
+}
+
+// End returns the position of the character following the token.
+func (t Token) End() Position {
+	text := t.Literal
+	if t.Type == String {
+		// The literal of a string excludes the quotes.
+		text = `"` + text + `"`
+	}
+
+	end := t.Pos
+	end.Offset += len(text)
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		end.Line += strings.Count(text, "\n")
+		end.Column = len(text) - i
+	} else {
+		end.Column += len(text)
+	}
+	return end
+}
+
+// Position describes a location in the source code.
+type Position struct {
+	// Offset is the byte offset, starting at 0.
+	Offset int
+	// Line is the line number, starting at 1.
+	Line int
+	// Column is the byte offset within the line, starting at 1.
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 var keywords = map[string]TokenType{
-	"let":    Let,
-	"fn":     Func,
-	"true":   True,
-	"false":  False,
-	"if":     If,
-	"else":   Else,
-	"return": Return,
+	"let":     Let,
+	"fn":      Func,
+	"true":    True,
+	"false":   False,
+	"if":      If,
+	"else":    Else,
+	"return":  Return,
+	"throw":   Throw,
+	"try":     Try,
+	"catch":   Catch,
+	"finally": Finally,
+	"import":  Import,
+	"export":  Export,
+	"as":      As,
 }
 
 func LookupIdentifier(literal string) TokenType {
@@ -0,0 +1,27 @@
+package token
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTokenType_String(t *testing.T) {
+	assert.Equal(t, "Let", Let.String())
+	assert.Equal(t, "LParen", LParen.String())
+	assert.Equal(t, "TokenType(-1)", TokenType(-1).String())
+}
+
+func TestLookupType(t *testing.T) {
+	t.Run("names of all types", func(t *testing.T) {
+		for typ, name := range typeNames {
+			actual, ok := LookupType(name)
+			assert.True(t, ok, name)
+			assert.Equal(t, typ, actual)
+		}
+	})
+
+	t.Run("unknown name", func(t *testing.T) {
+		_, ok := LookupType("Foo")
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/fabiante/monkeylang/ast/astjson"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/token"
+	"os"
+	"text/tabwriter"
+)
+
+// tokensCmd prints the tokens of the given file, either as a table with one
+// token per line or as a JSON array. Errors of the lexer are printed to stderr
+// after the tokens.
+func tokensCmd(args []string) int {
+	flags := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	format := flags.String("format", "table", "print the tokens in `format` table or json")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 || *format != "table" && *format != "json" {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: monkey tokens [--format=table|json] <file | ->")
+		return exitUsage
+	}
+
+	input, err := readSource(flags.Arg(0))
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	tokens, errs := lexer.Tokenize(input)
+
+	if *format == "json" {
+		err = printTokensJSON(tokens)
+	} else {
+		err = printTokensTable(tokens)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	for _, err := range errs {
+		_, _ = fmt.Fprintf(os.Stderr, "%s:%s\n", sourceName(flags.Arg(0)), err)
+	}
+	if len(errs) > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+func printTokensTable(tokens []token.Token) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "POS\tTYPE\tLITERAL")
+	for _, t := range tokens {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%q\n", t.Pos, t.Type, t.Literal)
+	}
+	return w.Flush()
+}
+
+func printTokensJSON(tokens []token.Token) error {
+	data, err := astjson.MarshalTokens(tokens)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		return err
+	}
+	out.WriteString("\n")
+
+	_, err = os.Stdout.Write(out.Bytes())
+	return err
+}
@@ -0,0 +1,792 @@
+// Package typecheck infers the static types of a program and reports
+// operations which are certain to fail at runtime.
+//
+// Types are inferred Hindley-Milner style: each expression starts with a type
+// variable, which is unified with the types required by its uses. Functions
+// bound by let statements, directly or by the name of another function, are
+// polymorphic, so that each call may instantiate their parameters with
+// different types:
+//
+//	let id = fn(x) { x };
+//	id(1);      // int
+//	id("one");  // string
+//
+// Monkey is dynamically typed and allows programs which have no static
+// type, like arrays with elements of different types. Instead of reporting
+// these, the checker gives them the type Any, which is compatible with
+// every type. Errors are only reported for the operands of prefix and infix
+// operators, for calls of values which are not functions or with the wrong
+// number or types of arguments, and for index expressions.
+//
+// Type annotations like "let x: int = 5;" declare the type of a name or of
+// the result of a function. Values which do not match their annotation are
+// reported.
+//
+// The checker does not change the program and is not required to run it.
+package typecheck
+
+import (
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/token"
+	"sort"
+)
+
+// Error is a type error found by the checker.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Info contains the inferred types of a program.
+type Info struct {
+	// Types contains the types of all expressions and of the names declared
+	// by let statements and function parameters.
+	Types map[ast.Expression]Type
+}
+
+// TypeOf returns the type of exp, or nil if it has not been checked.
+func (info *Info) TypeOf(exp ast.Expression) Type {
+	return info.Types[exp]
+}
+
+// Check infers the types of program. The returned errors are sorted by
+// position.
+func Check(program *ast.Program) (*Info, []*Error) {
+	c := &checker{types: make(map[ast.Expression]Type)}
+	c.scope = newScope(universe())
+	c.statements(program.Statements)
+
+	info := &Info{Types: make(map[ast.Expression]Type, len(c.types))}
+	for exp, t := range c.types {
+		info.Types[exp] = resolve(t)
+	}
+
+	sort.SliceStable(c.errs, func(i, j int) bool {
+		return c.errs[i].Pos.Offset < c.errs[j].Pos.Offset
+	})
+	return info, c.errs
+}
+
+type checker struct {
+	scope *scope
+	types map[ast.Expression]Type
+	errs  []*Error
+
+	// fn is the innermost function literal being checked, or nil at the top level.
+	fn *function
+	// self is the type of the name of a let statement whose function literal
+	// is about to be checked, see function.self.
+	self *Var
+
+	// level is the nesting depth of let statements. Variables created at a
+	// deeper level than the current one are generalized.
+	level int
+	// nextID numbers the type variables.
+	nextID int
+
+	// trail records the changes to type variables made by unify. It allows
+	// to undo a unification which failed halfway.
+	trail []trailEntry
+}
+
+type trailEntry struct {
+	v       *Var
+	ref     Type
+	addable bool
+	level   int
+}
+
+// function contains the state of a function literal being checked.
+type function struct {
+	// result is the join of the values of all return statements, or nil if
+	// the function has none.
+	result Type
+	// self is the type of the name the function is bound to, or nil if it
+	// is anonymous. It is used to recognize the results of recursive calls.
+	self *Var
+}
+
+// scope contains the bindings of a function or of the top level.
+type scope struct {
+	outer    *scope
+	bindings map[string]*scheme
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{outer: outer, bindings: make(map[string]*scheme)}
+}
+
+func (s *scope) lookup(name string) (*scheme, bool) {
+	for scope := s; scope != nil; scope = scope.outer {
+		if sc, ok := scope.bindings[name]; ok {
+			return sc, true
+		}
+	}
+	return nil, false
+}
+
+// universe returns the scope of the builtin functions.
+func universe() *scope {
+	s := newScope(nil)
+	elem := &Var{id: -1}
+	array := &Array{Elem: elem}
+	generic := func(t Type) *scheme {
+		return &scheme{vars: []*Var{elem}, t: t}
+	}
+
+	// len accepts strings and arrays, puts any number of arguments.
+	s.bindings["len"] = &scheme{t: &Function{Params: []Type{Any}, Result: Int}}
+	s.bindings["puts"] = &scheme{t: Any}
+	s.bindings["first"] = generic(&Function{Params: []Type{array}, Result: elem})
+	s.bindings["last"] = generic(&Function{Params: []Type{array}, Result: elem})
+	s.bindings["rest"] = generic(&Function{Params: []Type{array}, Result: array})
+	s.bindings["push"] = generic(&Function{Params: []Type{array, elem}, Result: array})
+	return s
+}
+
+func (c *checker) statements(statements []ast.Statement) Type {
+	var t Type = Any
+	for _, stmt := range statements {
+		t = c.statement(stmt)
+	}
+	return t
+}
+
+// statement checks stmt and returns the type of its value. Statements which
+// produce no value have the type Any, as their value is null. Return and
+// throw statements do not complete normally and return nil.
+func (c *checker) statement(stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.let(stmt)
+		return Any
+	case *ast.ReturnStatement:
+		t := c.expression(stmt.ReturnValue)
+		if c.fn != nil {
+			c.fn.result = c.join(c.fn.result, t)
+		}
+		return nil
+	case *ast.ThrowStatement:
+		c.expression(stmt.Value)
+		return nil
+	case *ast.ImportStatement:
+		// The exports of modules are not checked.
+		c.declare(stmt.Name, &scheme{t: Any})
+		return Any
+	case *ast.ExpressionStatement:
+		return c.expression(stmt.Expression)
+	case *ast.BlockStatement:
+		return c.block(stmt)
+	default:
+		return Any
+	}
+}
+
+// let binds the name of stmt. Function literals and names are generalized,
+// so that each use of the name can instantiate a polymorphic function with
+// different types.
+func (c *checker) let(stmt *ast.LetStatement) {
+	if ident, ok := stmt.Value.(*ast.Identifier); ok && stmt.Name.Type == nil {
+		c.level++
+		t := c.expression(ident)
+		c.level--
+
+		c.declare(stmt.Name, c.generalize(t))
+		return
+	}
+
+	fn, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t := c.expression(stmt.Value)
+		if declared := c.annotation(stmt.Name.Type); declared != nil {
+			c.expect(stmt.Name, declared, t)
+			t = declared
+		}
+		c.declare(stmt.Name, &scheme{t: t})
+		return
+	}
+
+	// The function may call itself, with the types of its parameters.
+	c.level++
+	self := c.newVar()
+	if declared := c.annotation(stmt.Name.Type); declared != nil {
+		c.unify(self, declared)
+	}
+	c.declare(stmt.Name, &scheme{t: self})
+	c.self = self
+	c.expect(stmt.Name, self, c.expression(fn))
+	c.level--
+
+	c.declare(stmt.Name, c.generalize(self))
+}
+
+// expect reports an error if the value t of the name declared by ident does
+// not match the declared type.
+func (c *checker) expect(ident *ast.Identifier, declared, t Type) {
+	if !c.unify(declared, t) {
+		c.errorf(ident.Token.Pos, "type mismatch: %s declared as %s, got %s", ident.Value, describe(declared), resolve(t))
+	}
+}
+
+// annotation returns the type denoted by a type annotation, or nil if typ is nil.
+func (c *checker) annotation(typ ast.TypeExpression) Type {
+	switch typ := typ.(type) {
+	case nil:
+		return nil
+	case *ast.NamedType:
+		switch typ.Name {
+		case "int":
+			return Int
+		case "bool":
+			return Bool
+		case "string":
+			return String
+		case "any":
+			return Any
+		default:
+			c.errorf(typ.Token.Pos, "unknown type %s", typ.Name)
+			return Any
+		}
+	case *ast.ArrayType:
+		return &Array{Elem: c.annotation(typ.Element)}
+	case *ast.HashType:
+		return &Hash{Key: c.annotation(typ.Key), Value: c.annotation(typ.Value)}
+	case *ast.FunctionType:
+		params := make([]Type, len(typ.Parameters))
+		for i, param := range typ.Parameters {
+			params[i] = c.annotation(param)
+		}
+		return &Function{Params: params, Result: c.annotation(typ.ReturnType)}
+	default:
+		return Any
+	}
+}
+
+func (c *checker) declare(ident *ast.Identifier, sc *scheme) {
+	c.scope.bindings[ident.Value] = sc
+	c.types[ident] = sc.t
+}
+
+// block returns the type of the last statement of block.
+func (c *checker) block(block *ast.BlockStatement) Type {
+	if block == nil {
+		return Any
+	}
+	return c.statements(block.Statements)
+}
+
+func (c *checker) expression(exp ast.Expression) Type {
+	t := c.infer(exp)
+	if t != nil && exp != nil {
+		c.types[exp] = t
+	}
+	return t
+}
+
+func (c *checker) infer(exp ast.Expression) Type {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.BooleanLiteral:
+		return Bool
+	case *ast.StringLiteral:
+		return String
+	case *ast.Identifier:
+		sc, ok := c.scope.lookup(exp.Value)
+		if !ok {
+			// Undefined names are reported by package resolver.
+			return Any
+		}
+		return c.instantiate(sc)
+	case *ast.PrefixExpression:
+		return c.prefix(exp)
+	case *ast.InfixExpression:
+		return c.infix(exp)
+	case *ast.IfExpression:
+		c.expression(exp.Condition)
+		consequence := c.block(exp.Consequence)
+		if exp.Alternative == nil {
+			// The value is null if the condition is not truthy.
+			return c.join(consequence, Any)
+		}
+		return c.join(consequence, c.block(exp.Alternative))
+	case *ast.TryExpression:
+		t := c.block(exp.Block)
+		if exp.Catch != nil {
+			// Errors are objects which are only inspected by indexing.
+			c.declare(exp.Parameter, &scheme{t: Any})
+			t = c.join(t, c.block(exp.Catch))
+		}
+		c.block(exp.Finally)
+		return t
+	case *ast.FunctionLiteral:
+		return c.function(exp)
+	case *ast.CallExpression:
+		return c.call(exp)
+	case *ast.ArrayLiteral:
+		var elem Type
+		for _, element := range exp.Elements {
+			elem = c.join(elem, c.expression(element))
+		}
+		if elem == nil {
+			elem = c.newVar()
+		}
+		return &Array{Elem: elem}
+	case *ast.IndexExpression:
+		return c.index(exp)
+	case *ast.MemberExpression:
+		c.expression(exp.Object)
+		return Any
+	case *ast.HashLiteral:
+		var key, value Type
+		for _, pair := range exp.Pairs {
+			k := c.expression(pair.Key)
+			if !hashable(k) {
+				c.errorf(exp.Token.Pos, "unusable as hash key: %s", resolve(k))
+			}
+			key = c.join(key, k)
+			value = c.join(value, c.expression(pair.Value))
+		}
+		if key == nil {
+			key, value = c.newVar(), c.newVar()
+		}
+		return &Hash{Key: key, Value: value}
+	default:
+		return Any
+	}
+}
+
+func (c *checker) prefix(exp *ast.PrefixExpression) Type {
+	right := c.expression(exp.Right)
+
+	switch exp.Operator {
+	case "!":
+		return Bool
+	case "-":
+		if !c.unify(right, Int) {
+			c.errorf(exp.Token.Pos, "unknown operator: -%s", resolve(right))
+			return Any
+		}
+		return Int
+	default:
+		return Any
+	}
+}
+
+func (c *checker) infix(exp *ast.InfixExpression) Type {
+	left := c.expression(exp.Left)
+	right := c.expression(exp.Right)
+
+	// The arithmetic and comparison operators require operands of the same
+	// type, which is int for all of them except +.
+	var result Type
+	switch exp.Operator {
+	case "==", "!=":
+		// Values of any types can be compared.
+		return Bool
+	case "+":
+		result = left
+	case "-", "*", "/":
+		result = Int
+	case "<", ">":
+		result = Bool
+	default:
+		return Any
+	}
+
+	if !c.unify(left, right) {
+		c.errorf(exp.Token.Pos, "type mismatch: %s %s %s", resolve(left), exp.Operator, resolve(right))
+		return Any
+	}
+
+	var ok bool
+	if exp.Operator == "+" {
+		ok = c.addable(left)
+	} else {
+		ok = c.unify(left, Int)
+	}
+	if !ok {
+		t := resolve(left)
+		c.errorf(exp.Token.Pos, "unknown operator: %s %s %s", t, exp.Operator, t)
+		return Any
+	}
+	return result
+}
+
+func (c *checker) function(exp *ast.FunctionLiteral) Type {
+	outerScope, outerFn := c.scope, c.fn
+	c.scope = newScope(outerScope)
+	c.fn = &function{self: c.self}
+	c.self = nil
+	defer func() {
+		c.scope, c.fn = outerScope, outerFn
+	}()
+
+	params := make([]Type, len(exp.Parameters))
+	for i, param := range exp.Parameters {
+		if params[i] = c.annotation(param.Type); params[i] == nil {
+			params[i] = c.newVar()
+		}
+		c.declare(param, &scheme{t: params[i]})
+	}
+
+	result := c.join(c.fn.result, c.block(exp.Body))
+	if result == nil {
+		// The function returns only values of recursive calls.
+		result = c.newVar()
+	}
+	if declared := c.annotation(exp.ReturnType); declared != nil {
+		if !c.unify(declared, result) {
+			c.errorf(exp.Token.Pos, "type mismatch: result declared as %s, got %s", describe(declared), resolve(result))
+		}
+		result = declared
+	}
+	return &Function{Params: params, Result: result}
+}
+
+func (c *checker) call(exp *ast.CallExpression) Type {
+	callee := c.expression(exp.Function)
+	args := make([]Type, len(exp.Arguments))
+	for i, arg := range exp.Arguments {
+		args[i] = c.expression(arg)
+	}
+
+	switch fn := prune(callee).(type) {
+	case *Function:
+		if len(fn.Params) != len(args) {
+			c.errorf(exp.Token.Pos, "wrong number of arguments: want=%d, got=%d", len(fn.Params), len(args))
+			return fn.Result
+		}
+		for i, param := range fn.Params {
+			if !c.unify(param, args[i]) {
+				// Later arguments are likely to fail for the same reason.
+				c.errorf(exp.Token.Pos, "type mismatch in argument %d: want %s, got %s", i+1, describe(param), resolve(args[i]))
+				break
+			}
+		}
+		return fn.Result
+	case *Var:
+		result := c.newVar()
+		if !c.unify(fn, &Function{Params: args, Result: result}) {
+			c.errorf(exp.Token.Pos, "not a function: %s", resolve(fn))
+			return Any
+		}
+		return result
+	case anyType:
+		return Any
+	default:
+		c.errorf(exp.Token.Pos, "not a function: %s", resolve(fn))
+		return Any
+	}
+}
+
+func (c *checker) index(exp *ast.IndexExpression) Type {
+	left := c.expression(exp.Left)
+	index := c.expression(exp.Index)
+
+	switch left := prune(left).(type) {
+	case *Array:
+		if !c.unify(index, Int) {
+			c.errorf(exp.Token.Pos, "index operator not supported: %s[%s]", resolve(left), resolve(index))
+		}
+		return left.Elem
+	case *Hash:
+		if !hashable(index) {
+			c.errorf(exp.Token.Pos, "unusable as hash key: %s", resolve(index))
+		}
+		// Keys of another type are missing, which is no error.
+		c.unify(index, left.Key)
+		return left.Value
+	case *Var, anyType:
+		// The value may be an array or a hash.
+		return Any
+	default:
+		c.errorf(exp.Token.Pos, "index operator not supported: %s", resolve(left))
+		return Any
+	}
+}
+
+// join returns the type of a value which is either of type a or of type b.
+// If they are not the same type, this is Any. The types are not unified, as
+// a value of one type does not require the other one to have that type, like
+// the parameter x in "if (x) { 1 } else { x }". A nil type is the type of
+// statements which do not complete, and joins with every type.
+//
+// The results of recursive calls are treated like nil, as they are one of
+// the other results of the function.
+func (c *checker) join(a, b Type) Type {
+	if c.recursive(a) {
+		a = nil
+	}
+	if c.recursive(b) {
+		b = nil
+	}
+
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case equal(a, b):
+		return a
+	default:
+		return Any
+	}
+}
+
+// recursive reports whether t is the result of a recursive call of the
+// function being checked, whose type is not known yet.
+func (c *checker) recursive(t Type) bool {
+	if t == nil || c.fn == nil || c.fn.self == nil {
+		return false
+	}
+	fn, ok := prune(c.fn.self).(*Function)
+	if !ok {
+		return false
+	}
+	result, ok := prune(fn.Result).(*Var)
+	return ok && result == prune(t)
+}
+
+// equal reports whether a and b are the same type without unifying them.
+func equal(a, b Type) bool {
+	a, b = prune(a), prune(b)
+	switch a := a.(type) {
+	case *Array:
+		b, ok := b.(*Array)
+		return ok && equal(a.Elem, b.Elem)
+	case *Hash:
+		b, ok := b.(*Hash)
+		return ok && equal(a.Key, b.Key) && equal(a.Value, b.Value)
+	case *Function:
+		b, ok := b.(*Function)
+		if !ok || len(a.Params) != len(b.Params) {
+			return false
+		}
+		for i := range a.Params {
+			if !equal(a.Params[i], b.Params[i]) {
+				return false
+			}
+		}
+		return equal(a.Result, b.Result)
+	default:
+		return a == b
+	}
+}
+
+// describe returns t as it is printed in messages. Variables which must
+// support the + operator are described by the types they may become.
+func describe(t Type) string {
+	if v, ok := prune(t).(*Var); ok && v.addable {
+		return "int or string"
+	}
+	return resolve(t).String()
+}
+
+// hashable reports whether values of type t may be used as hash keys.
+func hashable(t Type) bool {
+	switch prune(t).(type) {
+	case *Array, *Hash, *Function:
+		return false
+	default:
+		return true
+	}
+}
+
+// addable requires t to support the + operator.
+func (c *checker) addable(t Type) bool {
+	switch t := prune(t).(type) {
+	case *Var:
+		c.record(t)
+		t.addable = true
+		return true
+	case Basic:
+		return t == Int || t == String
+	case anyType:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *checker) newVar() *Var {
+	c.nextID++
+	return &Var{id: c.nextID, level: c.level}
+}
+
+// unify makes a and b the same type. If this is not possible, it reverts all
+// changes and returns false.
+func (c *checker) unify(a, b Type) bool {
+	mark := len(c.trail)
+	if c.unifyRec(a, b) {
+		return true
+	}
+
+	for i := len(c.trail) - 1; i >= mark; i-- {
+		e := c.trail[i]
+		e.v.ref, e.v.addable, e.v.level = e.ref, e.addable, e.level
+	}
+	c.trail = c.trail[:mark]
+	return false
+}
+
+func (c *checker) unifyRec(a, b Type) bool {
+	a, b = prune(a), prune(b)
+	if a == b || a == Any || b == Any {
+		return true
+	}
+
+	if v, ok := a.(*Var); ok {
+		return c.bind(v, b)
+	}
+	if v, ok := b.(*Var); ok {
+		return c.bind(v, a)
+	}
+
+	switch a := a.(type) {
+	case *Array:
+		b, ok := b.(*Array)
+		return ok && c.unifyRec(a.Elem, b.Elem)
+	case *Hash:
+		b, ok := b.(*Hash)
+		return ok && c.unifyRec(a.Key, b.Key) && c.unifyRec(a.Value, b.Value)
+	case *Function:
+		b, ok := b.(*Function)
+		if !ok || len(a.Params) != len(b.Params) {
+			return false
+		}
+		for i := range a.Params {
+			if !c.unifyRec(a.Params[i], b.Params[i]) {
+				return false
+			}
+		}
+		return c.unifyRec(a.Result, b.Result)
+	default:
+		return false
+	}
+}
+
+// bind sets the type of the unbound variable v to t.
+func (c *checker) bind(v *Var, t Type) bool {
+	if c.occurs(v, t) {
+		return false
+	}
+	if v.addable && !c.addable(t) {
+		return false
+	}
+	c.record(v)
+	v.ref = t
+	return true
+}
+
+// occurs reports whether v is part of t, which would make t infinite. It
+// also lowers the level of the variables in t to the level of v, as they
+// become reachable wherever v is.
+func (c *checker) occurs(v *Var, t Type) bool {
+	switch t := prune(t).(type) {
+	case *Var:
+		if t == v {
+			return true
+		}
+		if t.level > v.level {
+			c.record(t)
+			t.level = v.level
+		}
+		return false
+	case *Array:
+		return c.occurs(v, t.Elem)
+	case *Hash:
+		return c.occurs(v, t.Key) || c.occurs(v, t.Value)
+	case *Function:
+		for _, p := range t.Params {
+			if c.occurs(v, p) {
+				return true
+			}
+		}
+		return c.occurs(v, t.Result)
+	default:
+		return false
+	}
+}
+
+func (c *checker) record(v *Var) {
+	c.trail = append(c.trail, trailEntry{v: v, ref: v.ref, addable: v.addable, level: v.level})
+}
+
+// generalize returns a scheme which quantifies over the unbound variables in
+// t created within the current let statement.
+func (c *checker) generalize(t Type) *scheme {
+	sc := &scheme{t: t}
+	seen := make(map[*Var]bool)
+
+	var walk func(t Type)
+	walk = func(t Type) {
+		switch t := prune(t).(type) {
+		case *Var:
+			if t.level > c.level && !seen[t] {
+				seen[t] = true
+				sc.vars = append(sc.vars, t)
+			}
+		case *Array:
+			walk(t.Elem)
+		case *Hash:
+			walk(t.Key)
+			walk(t.Value)
+		case *Function:
+			for _, p := range t.Params {
+				walk(p)
+			}
+			walk(t.Result)
+		}
+	}
+	walk(t)
+
+	return sc
+}
+
+// instantiate returns the type of sc with new variables for its quantified ones.
+func (c *checker) instantiate(sc *scheme) Type {
+	if len(sc.vars) == 0 {
+		return sc.t
+	}
+
+	fresh := make(map[*Var]*Var, len(sc.vars))
+	for _, v := range sc.vars {
+		n := c.newVar()
+		n.addable = v.addable
+		fresh[v] = n
+	}
+
+	var subst func(t Type) Type
+	subst = func(t Type) Type {
+		switch t := prune(t).(type) {
+		case *Var:
+			if n, ok := fresh[t]; ok {
+				return n
+			}
+			return t
+		case *Array:
+			return &Array{Elem: subst(t.Elem)}
+		case *Hash:
+			return &Hash{Key: subst(t.Key), Value: subst(t.Value)}
+		case *Function:
+			params := make([]Type, len(t.Params))
+			for i, p := range t.Params {
+				params[i] = subst(p)
+			}
+			return &Function{Params: params, Result: subst(t.Result)}
+		default:
+			return t
+		}
+	}
+	return subst(sc.t)
+}
+
+func (c *checker) errorf(pos token.Position, format string, a ...any) {
+	c.errs = append(c.errs, &Error{Pos: pos, Msg: fmt.Sprintf(format, a...)})
+}
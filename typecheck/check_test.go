@@ -0,0 +1,177 @@
+package typecheck
+
+import (
+	"fmt"
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/internal/corpus"
+	"github.com/fabiante/monkeylang/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	t.Run("types", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected string
+		}{
+			{`let x = 1 + 2;`, "int"},
+			{`let x = "a" + "b";`, "string"},
+			{`let x = 1 < 2;`, "bool"},
+			{`let x = !5;`, "bool"},
+			{`let x = [1, 2];`, "[int]"},
+			{`let x = [1, "two"];`, "[any]"},
+			{`let x = [];`, "[T1]"},
+			{`let x = {"a": [1]};`, "{string: [int]}"},
+			{`let x = if (true) { 1 } else { 2 };`, "int"},
+			{`let x = if (true) { 1 };`, "any"},
+			{`let x = fn(a, b) { a - b };`, "fn(int, int) -> int"},
+			{`let x = fn(a, b) { a + b };`, "fn(T1, T1) -> T1"},
+			{`let x = fn(a) { if (a) { return 1; } 2 };`, "fn(T1) -> int"},
+			{`let x = fn(f, a) { f(a) };`, "fn(fn(T1) -> T2, T1) -> T2"},
+			{`let x = fn(n) { if (n == 0) { return 0; } x(n - 1) };`, "fn(int) -> int"},
+			{`let id = fn(a) { a }; let x = [id(1), id(2)];`, "[int]"},
+			{`let id = fn(a) { a }; let x = id("a") + id("b");`, "string"},
+			{`let x = push(rest([1, 2]), 3);`, "[int]"},
+			{`let x = first(["a"]);`, "string"},
+			{`let x = {1: "a"}[1];`, "string"},
+			{`let x = len("abc");`, "int"},
+			{`let x = try { 1 } catch (e) { 2 };`, "int"},
+			{`let x = try { 1 } catch (e) { e["message"] };`, "any"},
+			{`let x = fn(a) { if (a) { throw "no"; } 1 };`, "fn(T1) -> int"},
+			{`import "m.mk" as m; let x = m.f(1);`, "any"},
+			{`let x = fn(a) { if (a) { 1 } else { a } };`, "fn(T1) -> any"},
+			{`let id = fn(a) { a }; let x = id;`, "fn(T1) -> T1"},
+		}
+
+		for i, test := range tests {
+			t.Run(fmt.Sprintf("tests[%d]", i), func(t *testing.T) {
+				program := testutil.MustParse(t, test.input)
+				info, errs := Check(program)
+				require.Empty(t, errs)
+
+				last := program.Statements[len(program.Statements)-1].(*ast.LetStatement)
+				assert.Equal(t, test.expected, normalize(info.TypeOf(last.Name).String()))
+			})
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected []string
+		}{
+			{`1 + true`, []string{"1:3: type mismatch: int + bool"}},
+			{`"a" - "b"`, []string{"1:5: unknown operator: string - string"}},
+			{`true + false`, []string{"1:6: unknown operator: bool + bool"}},
+			{`[1] < [2]`, []string{"1:5: unknown operator: [int] < [int]"}},
+			{`-true`, []string{"1:1: unknown operator: -bool"}},
+			{`fn(a) { a + 1 }("x")`, []string{"1:16: type mismatch in argument 1: want int, got string"}},
+			{`let f = fn(a, b) { a }; f(1);`, []string{"1:26: wrong number of arguments: want=2, got=1"}},
+			{`len("a", "b")`, []string{"1:4: wrong number of arguments: want=1, got=2"}},
+			{`let x = 5; x(1);`, []string{"1:13: not a function: int"}},
+			{`5[0]`, []string{"1:2: index operator not supported: int"}},
+			{`[1]["a"]`, []string{"1:4: index operator not supported: [int][string]"}},
+			{`{[1]: 2}`, []string{"1:1: unusable as hash key: [int]"}},
+			{`let f = fn(a) { a * 2 }; f(true) + f("x");`, []string{
+				"1:27: type mismatch in argument 1: want int, got bool",
+				"1:37: type mismatch in argument 1: want int, got string",
+			}},
+			{`let add = fn(a, b) { a + b }; add(1, 2); add("a", "b"); add(true, false);`, []string{
+				"1:60: type mismatch in argument 1: want int or string, got bool",
+			}},
+		}
+
+		for i, test := range tests {
+			t.Run(fmt.Sprintf("tests[%d]", i), func(t *testing.T) {
+				_, errs := Check(testutil.MustParse(t, test.input))
+
+				messages := make([]string, 0, len(errs))
+				for _, err := range errs {
+					messages = append(messages, normalize(err.Error()))
+				}
+				assert.Equal(t, test.expected, messages)
+			})
+		}
+	})
+
+	t.Run("valid programs", func(t *testing.T) {
+		// Values of different types in the branches of an expression do not
+		// constrain each other, and aliases of functions stay polymorphic.
+		inputs := []string{
+			`let f = fn(x) { if (x) { 1 } else { x } }; f(true)`,
+			`let g = fn(x) { [x, 1] }; g("a")`,
+			`let h = fn(x) { {"a": x, "b": 1} }; h(true)`,
+			`let m = fn(x) { x }; let n = m; n(1); n("z")`,
+		}
+
+		for _, input := range inputs {
+			t.Run(input, func(t *testing.T) {
+				_, errs := Check(testutil.MustParse(t, input))
+				assert.Empty(t, errs)
+			})
+		}
+	})
+
+	t.Run("annotations", func(t *testing.T) {
+		tests := []struct {
+			input    string
+			expected []string
+		}{
+			{`let x: int = 5;`, nil},
+			{`let x: [any] = [1, "a"];`, nil},
+			{`let f: fn(int) -> int = fn(a) { a };`, nil},
+			{`let f = fn(a: string, b) -> string { a + b }; f("a", "b");`, nil},
+			{`let x: int = "five";`, []string{"1:5: type mismatch: x declared as int, got string"}},
+			{`let x: float = 5;`, []string{"1:8: unknown type float"}},
+			{`let f: fn(int) -> bool = fn(a) { a };`, []string{"1:5: type mismatch: f declared as fn(int) -> bool, got fn(T1) -> T1"}},
+			{`let f = fn(a: int) -> bool { a };`, []string{"1:9: type mismatch: result declared as bool, got int"}},
+			{`let f = fn(a: bool) { -a };`, []string{"1:23: unknown operator: -bool"}},
+			{`let f = fn(a: int) { a }; f("x");`, []string{"1:28: type mismatch in argument 1: want int, got string"}},
+			{`let x: string = 5; x + 1;`, []string{
+				"1:5: type mismatch: x declared as string, got int",
+				"1:22: type mismatch: string + int",
+			}},
+		}
+
+		for i, test := range tests {
+			t.Run(fmt.Sprintf("tests[%d]", i), func(t *testing.T) {
+				_, errs := Check(testutil.MustParse(t, test.input))
+
+				var messages []string
+				for _, err := range errs {
+					messages = append(messages, normalize(err.Error()))
+				}
+				assert.Equal(t, test.expected, messages)
+			})
+		}
+	})
+
+	t.Run("corpus", func(t *testing.T) {
+		// Programs which run without errors must not be reported.
+		for _, program := range corpus.Programs {
+			if program.Error != "" {
+				continue
+			}
+			t.Run(program.Name, func(t *testing.T) {
+				_, errs := Check(testutil.MustParse(t, program.Input))
+				assert.Empty(t, errs)
+			})
+		}
+	})
+}
+
+var typeVar = regexp.MustCompile(`T\d+`)
+
+// normalize numbers the type variables in s in the order of their appearance.
+func normalize(s string) string {
+	names := make(map[string]string)
+	return typeVar.ReplaceAllStringFunc(s, func(v string) string {
+		if _, ok := names[v]; !ok {
+			names[v] = fmt.Sprintf("T%d", len(names)+1)
+		}
+		return names[v]
+	})
+}
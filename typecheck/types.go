@@ -0,0 +1,126 @@
+package typecheck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the static type of an expression.
+type Type interface {
+	String() string
+	typeNode()
+}
+
+// Basic is the type of integers, booleans and strings.
+type Basic string
+
+const (
+	Int    Basic = "int"
+	Bool   Basic = "bool"
+	String Basic = "string"
+)
+
+func (b Basic) String() string { return string(b) }
+
+// Array is the type of arrays whose elements have the type Elem.
+type Array struct {
+	Elem Type
+}
+
+func (a *Array) String() string { return "[" + a.Elem.String() + "]" }
+
+// Hash is the type of hashes with keys of type Key and values of type Value.
+type Hash struct {
+	Key   Type
+	Value Type
+}
+
+func (h *Hash) String() string { return "{" + h.Key.String() + ": " + h.Value.String() + "}" }
+
+// Function is the type of functions.
+type Function struct {
+	Params []Type
+	Result Type
+}
+
+func (f *Function) String() string {
+	params := make([]string, 0, len(f.Params))
+	for _, p := range f.Params {
+		params = append(params, p.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + ") -> " + f.Result.String()
+}
+
+// Any is the type of values whose type cannot be determined statically, like
+// the elements of an array with elements of different types or the value of
+// an if expression without alternative, which may be null. Values of type
+// Any can be used as values of any type.
+var Any Type = anyType{}
+
+type anyType struct{}
+
+func (anyType) String() string { return "any" }
+
+// Var is a type which has not been inferred yet. Once it is, Var refers to
+// the inferred type.
+type Var struct {
+	id int
+	// ref is the type this variable has been unified with.
+	ref Type
+	// addable is true if the type must support the + operator, which means
+	// it must be int or string.
+	addable bool
+	// level is the number of let statements enclosing the creation of the
+	// variable. It decides which variables are generalized.
+	level int
+}
+
+func (v *Var) String() string {
+	if v.ref != nil {
+		return v.ref.String()
+	}
+	return fmt.Sprintf("T%d", v.id)
+}
+
+func (Basic) typeNode()     {}
+func (*Array) typeNode()    {}
+func (*Hash) typeNode()     {}
+func (*Function) typeNode() {}
+func (anyType) typeNode()   {}
+func (*Var) typeNode()      {}
+
+// prune returns the type a chain of unified variables refers to.
+func prune(t Type) Type {
+	for {
+		v, ok := t.(*Var)
+		if !ok || v.ref == nil {
+			return t
+		}
+		t = v.ref
+	}
+}
+
+// resolve replaces all unified variables within t by their types.
+func resolve(t Type) Type {
+	switch t := prune(t).(type) {
+	case *Array:
+		return &Array{Elem: resolve(t.Elem)}
+	case *Hash:
+		return &Hash{Key: resolve(t.Key), Value: resolve(t.Value)}
+	case *Function:
+		params := make([]Type, len(t.Params))
+		for i, p := range t.Params {
+			params[i] = resolve(p)
+		}
+		return &Function{Params: params, Result: resolve(t.Result)}
+	default:
+		return t
+	}
+}
+
+// scheme is a type which is polymorphic in vars. Each use of a binding with
+// this type instantiates the variables with new ones.
+type scheme struct {
+	vars []*Var
+	t    Type
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/lint"
+	"github.com/fabiante/monkeylang/parser"
+	"os"
+)
+
+// vetCmd reports suspicious constructs in the given files with the rules of
+// package lint. Without files, stdin is checked.
+//
+// The rules can be selected with a JSON config file as described by
+// lint.Config. Diagnostics are printed to stdout as text or, with
+// --format=sarif, as SARIF log. The exit code is exitError if any
+// diagnostics were reported.
+func vetCmd(args []string) int {
+	flags := flag.NewFlagSet("vet", flag.ContinueOnError)
+	configFile := flags.String("config", "", "read the enabled rules from the JSON `file`")
+	format := flags.String("format", "text", "print diagnostics in `format` text or sarif")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *format != "text" && *format != "sarif" {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: monkey vet [--config file] [--format=text|sarif] [files...]")
+		return exitUsage
+	}
+
+	rules := lint.DefaultRules()
+	if *configFile != "" {
+		var ok bool
+		if rules, ok = readLintConfig(*configFile); !ok {
+			return exitUsage
+		}
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	code := exitOK
+	results := make([]lint.File, 0, len(files))
+	for _, file := range files {
+		result, ok := vetFile(file, rules)
+		if !ok || len(result.Diagnostics) > 0 {
+			code = exitError
+		}
+		results = append(results, result)
+	}
+
+	if *format == "sarif" {
+		if err := lint.WriteSARIF(os.Stdout, rules, results); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		return code
+	}
+
+	for _, result := range results {
+		for _, d := range result.Diagnostics {
+			_, _ = fmt.Printf("%s:%s\n", result.Name, d)
+		}
+	}
+	return code
+}
+
+func readLintConfig(name string) ([]lint.Rule, bool) {
+	f, err := os.Open(name)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return nil, false
+	}
+	defer f.Close()
+
+	config, err := lint.ReadConfig(f)
+	if err == nil {
+		var rules []lint.Rule
+		if rules, err = config.EnabledRules(); err == nil {
+			return rules, true
+		}
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+	return nil, false
+}
+
+// vetFile lints a single file and reports whether it could be parsed.
+func vetFile(name string, rules []lint.Rule) (lint.File, bool) {
+	result := lint.File{Name: sourceName(name)}
+
+	input, err := readSource(name)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return result, false
+	}
+
+	par := parser.NewParser(lexer.NewLexer(input))
+	program := par.ParseProgram()
+	if errs := par.ErrorList(); len(errs) > 0 {
+		printParseErrors(result.Name, errs)
+		return result, false
+	}
+
+	// Without rules, Lint would apply the default rules.
+	if len(rules) > 0 {
+		result.Diagnostics = lint.Lint(program, rules...)
+	}
+	return result, true
+}
@@ -0,0 +1,30 @@
+package vm
+
+import (
+	"github.com/fabiante/monkeylang/code"
+	"github.com/fabiante/monkeylang/object"
+)
+
+// Frame is the execution state of a function call.
+type Frame struct {
+	cl *object.Closure
+	ip int
+	// basePointer is the stack pointer before the call. The locals of the
+	// function are stored on the stack starting at this index.
+	basePointer int
+	// tailCalls is the number of frames which have been replaced by this
+	// one, see object.StackFrame.
+	tailCalls int
+}
+
+func NewFrame(cl *object.Closure, basePointer int) *Frame {
+	return &Frame{
+		cl:          cl,
+		ip:          -1,
+		basePointer: basePointer,
+	}
+}
+
+func (f *Frame) Instructions() code.Instructions {
+	return f.cl.Fn.Instructions
+}
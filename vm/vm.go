@@ -0,0 +1,736 @@
+// Package vm executes bytecode produced by the compiler on a stack machine.
+package vm
+
+import (
+	"github.com/fabiante/monkeylang/code"
+	"github.com/fabiante/monkeylang/compiler"
+	"github.com/fabiante/monkeylang/object"
+	"github.com/fabiante/monkeylang/token"
+)
+
+const (
+	// StackSize leaves room for the locals and temporaries of MaxFrames
+	// frames, so that deep recursion is limited by the number of frames
+	// like in the evaluator.
+	StackSize   = 64 * MaxFrames
+	GlobalsSize = 65536
+	MaxFrames   = object.MaxFrames
+)
+
+// Values without identity are shared by all programs.
+var (
+	True  = &object.Boolean{Value: true}
+	False = &object.Boolean{Value: false}
+	Null  = &object.Null{}
+)
+
+type VM struct {
+	constants []object.Object
+
+	stack []object.Object
+	// sp points to the next free slot. The top of the stack is stack[sp-1].
+	sp int
+
+	globals []object.Object
+
+	frames      []*Frame
+	framesIndex int
+
+	// handlers contains the error handlers installed by OpTry, starting
+	// with the outermost one.
+	handlers []handler
+
+	// modules contains the imported modules by the function which executes
+	// their top level.
+	modules map[*object.CompiledFunction]*object.Module
+}
+
+// handler is an error handler installed by OpTry.
+type handler struct {
+	// target is the offset of the handler in the instructions of the frame.
+	target int
+	// framesIndex and sp are restored when an error is handled.
+	framesIndex int
+	sp          int
+}
+
+func NewVM(bytecode *compiler.Bytecode) *VM {
+	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions, Lines: bytecode.Lines, File: bytecode.File}
+	mainClosure := &object.Closure{Fn: mainFn}
+	mainFrame := NewFrame(mainClosure, 0)
+
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = mainFrame
+
+	return &VM{
+		constants: bytecode.Constants,
+
+		stack: make([]object.Object, StackSize),
+		sp:    0,
+
+		globals: make([]object.Object, GlobalsSize),
+
+		frames:      frames,
+		framesIndex: 1,
+
+		modules: make(map[*object.CompiledFunction]*object.Module),
+	}
+}
+
+// NewVMWithGlobalsStore creates a VM which continues with the globals of a
+// previous run. This is used by the REPL together with compiler.NewCompilerWithState.
+func NewVMWithGlobalsStore(bytecode *compiler.Bytecode, globals []object.Object) *VM {
+	vm := NewVM(bytecode)
+	vm.globals = globals
+	return vm
+}
+
+// LastPoppedStackElem returns the element which was popped last. After Run,
+// this is the value of the last expression statement of the program or the
+// value of a top-level return statement.
+func (vm *VM) LastPoppedStackElem() object.Object {
+	return vm.stack[vm.sp]
+}
+
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+func (vm *VM) pushFrame(f *Frame) error {
+	if vm.framesIndex >= MaxFrames {
+		return object.NewError(object.InternalError, "stack overflow")
+	}
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+	return nil
+}
+
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+// Run executes the program until its instructions are exhausted, a top-level
+// return statement is executed or an error is thrown which is not caught.
+// Such errors are returned as *object.Error.
+func (vm *VM) Run() error {
+	for {
+		err := vm.run()
+		if err == nil {
+			return nil
+		}
+		if err = vm.raise(err); err != nil {
+			return err
+		}
+	}
+}
+
+// run executes instructions until the program ends or an error is thrown.
+func (vm *VM) run() error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			if err := vm.push(vm.constants[constIndex]); err != nil {
+				return err
+			}
+		case code.OpPop:
+			vm.pop()
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv,
+			code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpLessThan:
+			if err := vm.executeBinaryOperation(op); err != nil {
+				return err
+			}
+		case code.OpTrue:
+			if err := vm.push(True); err != nil {
+				return err
+			}
+		case code.OpFalse:
+			if err := vm.push(False); err != nil {
+				return err
+			}
+		case code.OpNull:
+			if err := vm.push(Null); err != nil {
+				return err
+			}
+		case code.OpBang:
+			operand := vm.pop()
+			if err := vm.push(nativeBoolToBooleanObject(!isTruthy(operand))); err != nil {
+				return err
+			}
+		case code.OpMinus:
+			if err := vm.executeMinusOperator(); err != nil {
+				return err
+			}
+		case code.OpJump:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			// The loop increments ip before reading the next instruction.
+			vm.currentFrame().ip = pos - 1
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			condition := vm.pop()
+			if !isTruthy(condition) {
+				vm.currentFrame().ip = pos - 1
+			}
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			vm.globals[globalIndex] = vm.pop()
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			if err := vm.push(vm.globals[globalIndex]); err != nil {
+				return err
+			}
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			if err := vm.push(vm.stack[frame.basePointer+int(localIndex)]); err != nil {
+				return err
+			}
+		case code.OpGetBuiltin:
+			builtinIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			if err := vm.push(object.Builtins[builtinIndex].Builtin); err != nil {
+				return err
+			}
+		case code.OpGetFree:
+			freeIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			if err := vm.push(vm.currentFrame().cl.Free[freeIndex]); err != nil {
+				return err
+			}
+		case code.OpCurrentClosure:
+			if err := vm.push(vm.currentFrame().cl); err != nil {
+				return err
+			}
+		case code.OpArray:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			array := vm.buildArray(vm.sp-numElements, vm.sp)
+			vm.sp = vm.sp - numElements
+
+			if err := vm.push(array); err != nil {
+				return err
+			}
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			hash, err := vm.buildHash(vm.sp-numElements, vm.sp)
+			if err != nil {
+				return err
+			}
+			vm.sp = vm.sp - numElements
+
+			if err := vm.push(hash); err != nil {
+				return err
+			}
+		case code.OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+
+			if err := vm.executeIndexExpression(left, index); err != nil {
+				return err
+			}
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			if err := vm.executeCall(int(numArgs)); err != nil {
+				return err
+			}
+		case code.OpTailCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			if err := vm.executeTailCall(int(numArgs)); err != nil {
+				return err
+			}
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+
+			// A return statement at the top level ends the program. The
+			// value stays available as last popped element.
+			if vm.framesIndex == 1 {
+				return nil
+			}
+
+			frame := vm.popFrame()
+			// The closure itself is located below the base pointer.
+			vm.sp = frame.basePointer - 1
+
+			if err := vm.push(returnValue); err != nil {
+				return err
+			}
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+
+			if err := vm.push(Null); err != nil {
+				return err
+			}
+		case code.OpClosure:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			numFree := code.ReadUint8(ins[ip+3:])
+			vm.currentFrame().ip += 3
+
+			if err := vm.pushClosure(int(constIndex), int(numFree)); err != nil {
+				return err
+			}
+		case code.OpTry:
+			target := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			vm.handlers = append(vm.handlers, handler{target: target, framesIndex: vm.framesIndex, sp: vm.sp})
+		case code.OpEndTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case code.OpThrow:
+			return thrownError(vm.pop())
+		case code.OpImport:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			if err := vm.executeImport(int(constIndex)); err != nil {
+				return err
+			}
+		case code.OpModule:
+			numExports := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			fn := vm.currentFrame().cl.Fn
+			module := &object.Module{File: fn.File, Exports: make(map[string]object.Object, numExports)}
+			for i := vm.sp - 2*numExports; i < vm.sp; i += 2 {
+				module.Exports[vm.stack[i].(*object.String).Value] = vm.stack[i+1]
+			}
+			vm.sp = vm.sp - 2*numExports
+			vm.modules[fn] = module
+
+			if err := vm.push(module); err != nil {
+				return err
+			}
+		case code.OpMember:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			name := vm.constants[constIndex].(*object.String).Value
+			if err := vm.executeMemberExpression(vm.pop(), name); err != nil {
+				return err
+			}
+		default:
+			def, err := code.Lookup(byte(op))
+			if err != nil {
+				return err
+			}
+			return object.NewError(object.InternalError, "unsupported opcode %s", def.Name)
+		}
+	}
+
+	return nil
+}
+
+func (vm *VM) push(o object.Object) error {
+	if vm.sp >= StackSize {
+		return object.NewError(object.InternalError, "stack overflow")
+	}
+
+	vm.stack[vm.sp] = o
+	vm.sp++
+
+	return nil
+}
+
+func (vm *VM) pop() object.Object {
+	o := vm.stack[vm.sp-1]
+	vm.sp--
+	return o
+}
+
+// executeBinaryOperation applies an infix operator to the two topmost
+// elements of the stack. The semantics match those of the evaluator.
+func (vm *VM) executeBinaryOperation(op code.Opcode) error {
+	right := vm.pop()
+	left := vm.pop()
+
+	leftType := left.Type()
+	rightType := right.Type()
+
+	switch {
+	case leftType == object.IntegerObj && rightType == object.IntegerObj:
+		return vm.executeBinaryIntegerOperation(op, left, right)
+	case leftType == object.StringObj && rightType == object.StringObj:
+		return vm.executeBinaryStringOperation(op, left, right)
+	// All other values are compared by identity. Booleans and null are
+	// singletons, so this compares them by value.
+	case op == code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(left == right))
+	case op == code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(left != right))
+	case leftType != rightType:
+		return object.NewError(object.TypeError, "type mismatch: %s %s %s", leftType, operators[op], rightType)
+	default:
+		return object.NewError(object.TypeError, "unknown operator: %s %s %s", leftType, operators[op], rightType)
+	}
+}
+
+// operators maps the opcodes of binary operations to their operators for error messages.
+var operators = map[code.Opcode]string{
+	code.OpAdd:         "+",
+	code.OpSub:         "-",
+	code.OpMul:         "*",
+	code.OpDiv:         "/",
+	code.OpEqual:       "==",
+	code.OpNotEqual:    "!=",
+	code.OpGreaterThan: ">",
+	code.OpLessThan:    "<",
+}
+
+func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.Object) error {
+	leftValue := left.(*object.Integer).Value
+	rightValue := right.(*object.Integer).Value
+
+	switch op {
+	case code.OpAdd:
+		return vm.push(&object.Integer{Value: leftValue + rightValue})
+	case code.OpSub:
+		return vm.push(&object.Integer{Value: leftValue - rightValue})
+	case code.OpMul:
+		return vm.push(&object.Integer{Value: leftValue * rightValue})
+	case code.OpDiv:
+		if rightValue == 0 {
+			return object.NewError(object.ArithmeticError, "division by zero")
+		}
+		return vm.push(&object.Integer{Value: leftValue / rightValue})
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	case code.OpGreaterThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+	case code.OpLessThan:
+		return vm.push(nativeBoolToBooleanObject(leftValue < rightValue))
+	default:
+		return object.NewError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operators[op], right.Type())
+	}
+}
+
+func (vm *VM) executeBinaryStringOperation(op code.Opcode, left, right object.Object) error {
+	leftValue := left.(*object.String).Value
+	rightValue := right.(*object.String).Value
+
+	switch op {
+	case code.OpAdd:
+		return vm.push(&object.String{Value: leftValue + rightValue})
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+	default:
+		return object.NewError(object.TypeError, "unknown operator: %s %s %s", left.Type(), operators[op], right.Type())
+	}
+}
+
+func (vm *VM) executeMinusOperator() error {
+	operand := vm.pop()
+
+	integer, ok := operand.(*object.Integer)
+	if !ok {
+		return object.NewError(object.TypeError, "unknown operator: -%s", operand.Type())
+	}
+
+	return vm.push(&object.Integer{Value: -integer.Value})
+}
+
+func (vm *VM) buildArray(startIndex, endIndex int) object.Object {
+	elements := make([]object.Object, endIndex-startIndex)
+	copy(elements, vm.stack[startIndex:endIndex])
+	return &object.Array{Elements: elements}
+}
+
+func (vm *VM) buildHash(startIndex, endIndex int) (object.Object, error) {
+	pairs := make(map[object.HashKey]object.HashPair, (endIndex-startIndex)/2)
+
+	for i := startIndex; i < endIndex; i += 2 {
+		key := vm.stack[i]
+		value := vm.stack[i+1]
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, object.NewError(object.TypeError, "unusable as hash key: %s", key.Type())
+		}
+
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return &object.Hash{Pairs: pairs}, nil
+}
+
+func (vm *VM) executeIndexExpression(left, index object.Object) error {
+	switch {
+	case left.Type() == object.ArrayObj && index.Type() == object.IntegerObj:
+		elements := left.(*object.Array).Elements
+		i := index.(*object.Integer).Value
+		if i < 0 || i >= int64(len(elements)) {
+			return vm.push(Null)
+		}
+		return vm.push(elements[i])
+	case left.Type() == object.ErrorObj && index.Type() == object.StringObj:
+		if val := left.(*object.Error).Property(index.(*object.String).Value); val != nil {
+			return vm.push(val)
+		}
+		return vm.push(Null)
+	case left.Type() == object.HashObj:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return object.NewError(object.TypeError, "unusable as hash key: %s", index.Type())
+		}
+		pair, ok := left.(*object.Hash).Pairs[key.HashKey()]
+		if !ok {
+			return vm.push(Null)
+		}
+		return vm.push(pair.Value)
+	default:
+		return object.NewError(object.TypeError, "index operator not supported: %s", left.Type())
+	}
+}
+
+// executeImport pushes the module whose top level is executed by the function
+// with the given constant index. If the module has not been imported before,
+// the function is called instead and pushes the module when it returns.
+func (vm *VM) executeImport(constIndex int) error {
+	fn, ok := vm.constants[constIndex].(*object.CompiledFunction)
+	if !ok {
+		return object.NewError(object.InternalError, "not a module: %+v", vm.constants[constIndex])
+	}
+
+	if module, ok := vm.modules[fn]; ok {
+		return vm.push(module)
+	}
+
+	cl := &object.Closure{Fn: fn}
+	if err := vm.push(cl); err != nil {
+		return err
+	}
+	return vm.callClosure(cl, 0)
+}
+
+func (vm *VM) executeMemberExpression(obj object.Object, name string) error {
+	module, ok := obj.(*object.Module)
+	if !ok {
+		return object.NewError(object.TypeError, "member access not supported: %s", obj.Type())
+	}
+
+	value, ok := module.Exports[name]
+	if !ok {
+		return object.NewError(object.ReferenceError, "undefined export %s of module %s", name, module.File)
+	}
+	return vm.push(value)
+}
+
+// executeCall calls the callee located below numArgs arguments on the stack.
+func (vm *VM) executeCall(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+
+	switch callee := callee.(type) {
+	case *object.Closure:
+		return vm.callClosure(callee, numArgs)
+	case *object.Builtin:
+		return vm.callBuiltin(callee, numArgs)
+	default:
+		return object.NewError(object.TypeError, "not a function: %s", callee.Type())
+	}
+}
+
+// executeTailCall calls the callee located below numArgs arguments on the
+// stack and returns its result from the current function. Closures are
+// executed in the frame of the current function, so tail calls do not grow
+// the stack.
+func (vm *VM) executeTailCall(numArgs int) error {
+	callee := vm.stack[vm.sp-1-numArgs]
+
+	cl, ok := callee.(*object.Closure)
+	if !ok || vm.framesIndex == 1 {
+		return vm.executeCall(numArgs)
+	}
+	if err := checkArguments(cl, numArgs); err != nil {
+		return err
+	}
+
+	// The callee and its arguments replace the current closure and its locals.
+	frame := vm.currentFrame()
+	copy(vm.stack[frame.basePointer-1:], vm.stack[vm.sp-1-numArgs:vm.sp])
+
+	frame.cl = cl
+	frame.ip = -1
+	frame.tailCalls++
+
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+	if vm.sp >= StackSize {
+		return object.NewError(object.InternalError, "stack overflow")
+	}
+
+	return nil
+}
+
+func (vm *VM) callClosure(cl *object.Closure, numArgs int) error {
+	if err := checkArguments(cl, numArgs); err != nil {
+		return err
+	}
+
+	// The arguments become the first locals of the function. The stack is
+	// checked before the frame is pushed, so that an overflow is thrown at
+	// the call.
+	frame := NewFrame(cl, vm.sp-numArgs)
+	if frame.basePointer+cl.Fn.NumLocals >= StackSize {
+		return object.NewError(object.InternalError, "stack overflow")
+	}
+	if err := vm.pushFrame(frame); err != nil {
+		return err
+	}
+
+	vm.sp = frame.basePointer + cl.Fn.NumLocals
+	return nil
+}
+
+func checkArguments(cl *object.Closure, numArgs int) error {
+	if numArgs != cl.Fn.NumParameters {
+		return object.NewError(object.TypeError, "wrong number of arguments: want=%d, got=%d", cl.Fn.NumParameters, numArgs)
+	}
+	return nil
+}
+
+func (vm *VM) callBuiltin(builtin *object.Builtin, numArgs int) error {
+	args := vm.stack[vm.sp-numArgs : vm.sp]
+
+	result := builtin.Fn(args...)
+	vm.sp = vm.sp - numArgs - 1
+
+	switch result := result.(type) {
+	case nil:
+		return vm.push(Null)
+	case *object.Error:
+		// Errors which have been thrown before are ordinary values, for
+		// example if a caught error is passed to first.
+		if result.Stack == nil {
+			return result
+		}
+		return vm.push(result)
+	default:
+		return vm.push(result)
+	}
+}
+
+func (vm *VM) pushClosure(constIndex, numFree int) error {
+	constant := vm.constants[constIndex]
+	function, ok := constant.(*object.CompiledFunction)
+	if !ok {
+		return object.NewError(object.InternalError, "not a function: %+v", constant)
+	}
+
+	free := make([]object.Object, numFree)
+	copy(free, vm.stack[vm.sp-numFree:vm.sp])
+	vm.sp = vm.sp - numFree
+
+	closure := &object.Closure{Fn: function, Free: free}
+	return vm.push(closure)
+}
+
+// raise throws err at the current instruction. If a handler is installed, the
+// frames and the stack are unwound to it and execution continues at the
+// handler with the error on the stack. Otherwise, the error is returned.
+func (vm *VM) raise(err error) error {
+	e, ok := err.(*object.Error)
+	if !ok {
+		e = &object.Error{Kind: object.InternalError, Message: err.Error()}
+	}
+	if e.Stack == nil {
+		vm.locate(e)
+	}
+
+	if len(vm.handlers) == 0 {
+		return e
+	}
+
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+
+	vm.framesIndex = h.framesIndex
+	vm.sp = h.sp
+	// The loop increments ip before reading the next instruction.
+	vm.currentFrame().ip = h.target - 1
+
+	return vm.push(e)
+}
+
+// locate sets the position and the stack of err from the instructions which
+// are executed by the active frames.
+func (vm *VM) locate(err *object.Error) {
+	for i := vm.framesIndex - 1; i >= 0; i-- {
+		frame := vm.frames[i]
+		line, column := frame.cl.Fn.Lines.Position(frame.ip)
+
+		// The first frame is the top level of the program.
+		function := ""
+		if i > 0 {
+			function = object.FunctionName(frame.cl.Fn.Name)
+		}
+
+		err.Stack = append(err.Stack, object.StackFrame{Function: function, File: frame.cl.Fn.File, Pos: token.Position{Line: line, Column: column}, TailCalls: frame.tailCalls})
+	}
+	err.Pos = err.Stack[0].Pos
+}
+
+// thrownError returns the error thrown by OpThrow for obj. Errors which have
+// been thrown before are thrown again unchanged. All other values are thrown
+// as error whose message is their representation.
+func thrownError(obj object.Object) error {
+	if err, ok := obj.(*object.Error); ok && err.Stack != nil {
+		return err
+	}
+	return &object.Error{Kind: object.ThrownError, Message: obj.Inspect()}
+}
+
+func nativeBoolToBooleanObject(value bool) *object.Boolean {
+	if value {
+		return True
+	}
+	return False
+}
+
+// isTruthy reports whether obj counts as true in conditions. Only false and
+// null are not truthy.
+func isTruthy(obj object.Object) bool {
+	switch obj {
+	case Null, False:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,168 @@
+package vm
+
+import (
+	"github.com/fabiante/monkeylang/ast"
+	"github.com/fabiante/monkeylang/compiler"
+	"github.com/fabiante/monkeylang/internal/corpus"
+	"github.com/fabiante/monkeylang/internal/testutil"
+	"github.com/fabiante/monkeylang/lexer"
+	"github.com/fabiante/monkeylang/object"
+	"github.com/fabiante/monkeylang/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestVM_Run(t *testing.T) {
+	t.Run("corpus", func(t *testing.T) {
+		for _, program := range corpus.Programs {
+			t.Run(program.Name, func(t *testing.T) {
+				vm := newTestVM(t, program.Input)
+				err := vm.Run()
+
+				if program.Error != "" {
+					assert.EqualError(t, err, program.Error)
+				} else {
+					require.NoError(t, err)
+					require.NotNil(t, vm.LastPoppedStackElem())
+					assert.Equal(t, program.Expected, vm.LastPoppedStackElem().Inspect())
+				}
+			})
+		}
+	})
+
+	t.Run("stack is empty after expression statements", func(t *testing.T) {
+		vm := newTestVM(t, "1; let f = fn(a) { a }; f(2); [3, 4];")
+		require.NoError(t, vm.Run())
+
+		assert.Equal(t, 0, vm.sp)
+	})
+
+	t.Run("unbounded recursion overflows the stack", func(t *testing.T) {
+		vm := newTestVM(t, "let f = fn(x) { 1 + f(x + 1) }; f(0);")
+
+		assert.EqualError(t, vm.Run(), "stack overflow")
+	})
+
+	t.Run("stack frames name the file", func(t *testing.T) {
+		c := compiler.NewCompiler()
+		c.SetFile("math.mk")
+		require.NoError(t, c.Compile(testutil.MustParse(t, "let add = fn(a, b) { a + b };\nadd(true, false);")))
+
+		err := NewVM(c.Bytecode()).Run()
+		require.IsType(t, &object.Error{}, err)
+		assert.Equal(t, "TypeError: unknown operator: BOOLEAN + BOOLEAN\n    at add (math.mk:1:24)\n    at math.mk:2:4", err.(*object.Error).Trace())
+	})
+
+	t.Run("stack frames replaced by tail calls", func(t *testing.T) {
+		c := compiler.NewCompiler()
+		c.SetFile("math.mk")
+		require.NoError(t, c.Compile(testutil.MustParse(t, "let add = fn(a, b) { a + b };\nlet g = fn() { add(true, 1) };\ng();")))
+
+		err := NewVM(c.Bytecode()).Run()
+		require.IsType(t, &object.Error{}, err)
+		assert.Equal(t, "TypeError: type mismatch: BOOLEAN + INTEGER\n    at add (math.mk:1:24)\n    ... 1 frame replaced by a tail call\n    at math.mk:3:2", err.(*object.Error).Trace())
+	})
+
+	t.Run("repeated stack frames", func(t *testing.T) {
+		c := compiler.NewCompiler()
+		c.SetFile("so.mk")
+		require.NoError(t, c.Compile(testutil.MustParse(t, "let f = fn(n) { f(n + 1) + 1 };\nf(0);")))
+
+		err := NewVM(c.Bytecode()).Run()
+		require.IsType(t, &object.Error{}, err)
+		assert.Equal(t, "InternalError: stack overflow\n    at f (so.mk:1:18)\n    ... repeated 1022 more times\n    at so.mk:2:2", err.(*object.Error).Trace())
+	})
+
+	t.Run("modules", func(t *testing.T) {
+		program := testutil.MustParse(t, `import "math.mk" as math; import "math.mk" as again; [math.add(1, 2), again.twice(4)]`)
+		link(t, program, map[string]string{
+			"math.mk": "export let add = fn(a, b) { a + b };\nlet one = 1;\nexport let twice = fn(x) { add(x, x) * one };",
+		})
+		c := compiler.NewCompiler()
+		require.NoError(t, c.Compile(program))
+
+		vm := NewVM(c.Bytecode())
+		require.NoError(t, vm.Run())
+		assert.Equal(t, "[3, 8]", vm.LastPoppedStackElem().Inspect())
+		assert.Equal(t, 0, vm.sp)
+	})
+
+	t.Run("unexported binding", func(t *testing.T) {
+		program := testutil.MustParse(t, `import "math.mk" as math; math.one`)
+		link(t, program, map[string]string{"math.mk": "let one = 1;"})
+		c := compiler.NewCompiler()
+		require.NoError(t, c.Compile(program))
+
+		err := NewVM(c.Bytecode()).Run()
+		require.IsType(t, &object.Error{}, err)
+		assert.Equal(t, "ReferenceError: undefined export one of module math.mk\n    at 1:31", err.(*object.Error).Trace())
+	})
+
+	t.Run("stack frames name the module", func(t *testing.T) {
+		program := testutil.MustParse(t, `import "lib.mk" as lib;`)
+		link(t, program, map[string]string{"lib.mk": `import "err.mk" as err;`, "err.mk": "let x = 0;\n1 / x;"})
+		c := compiler.NewCompiler()
+		c.SetFile("main.mk")
+		require.NoError(t, c.Compile(program))
+
+		err := NewVM(c.Bytecode()).Run()
+		require.IsType(t, &object.Error{}, err)
+		assert.Equal(t, "ArithmeticError: division by zero\n    at <module> (err.mk:2:3)\n    at <module> (lib.mk:1:1)\n    at main.mk:1:1", err.(*object.Error).Trace())
+	})
+
+	t.Run("globals store is kept between runs", func(t *testing.T) {
+		globals := make([]object.Object, GlobalsSize)
+		symbolTable := compiler.NewCompiler().SymbolTable()
+		var constants []object.Object
+
+		for _, input := range []string{"let a = 1;", "let b = a + 1;", "a + b"} {
+			c := compiler.NewCompilerWithState(symbolTable, constants)
+			require.NoError(t, c.Compile(testutil.MustParse(t, input)))
+			bytecode := c.Bytecode()
+			constants = bytecode.Constants
+
+			require.NoError(t, NewVMWithGlobalsStore(bytecode, globals).Run())
+		}
+
+		assert.Equal(t, int64(1), globals[0].(*object.Integer).Value)
+		assert.Equal(t, int64(2), globals[1].(*object.Integer).Value)
+	})
+}
+
+func BenchmarkFibonacci(b *testing.B) {
+	par := parser.NewParser(lexer.NewLexer(corpus.Fibonacci))
+	program := par.ParseProgram()
+	require.Empty(b, par.Errors())
+
+	c := compiler.NewCompiler()
+	require.NoError(b, c.Compile(program))
+	bytecode := c.Bytecode()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		vm := NewVM(bytecode)
+		require.NoError(b, vm.Run())
+		require.Equal(b, "75025", vm.LastPoppedStackElem().Inspect())
+	}
+}
+
+func newTestVM(t *testing.T, input string) *VM {
+	c := compiler.NewCompiler()
+	require.NoError(t, c.Compile(testutil.MustParse(t, input)))
+
+	return NewVM(c.Bytecode())
+}
+
+// link sets the imported programs of program, and of the programs imported by
+// it, to the parsed sources with the imported file names.
+func link(t *testing.T, program *ast.Program, sources map[string]string) {
+	ast.Inspect(program, func(node ast.Node) bool {
+		if stmt, ok := node.(*ast.ImportStatement); ok {
+			stmt.File = stmt.Path.Value
+			stmt.Program = testutil.MustParse(t, sources[stmt.Path.Value])
+			link(t, stmt.Program, sources)
+		}
+		return true
+	})
+}